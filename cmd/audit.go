@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/server"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
+)
+
+// Flags
+var (
+	auditDest   string
+	auditJSON   bool
+	auditLevel  string
+	auditListen string
+)
+
+// auditCmd represents the "audit" command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit the local repository against the imported advisories",
+	Long: `audit matches every version stored in the local repository against the advisories
+imported with "audit import", and fails when a version is affected by an advisory at or
+above --audit-level, e.g.:
+        npm-pkg audit import ./advisory-database/advisories/github-reviewed --dest=./mirror
+        npm-pkg audit --dest=./mirror --audit-level=high`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyAuditConfig(cmd); err != nil {
+			return err
+		}
+		if err := entities.ValidateSeverity(auditLevel); err != nil {
+			return err
+		}
+		fs := filesystem.NewOsFileSystem()
+		db, err := repositories.NewAdvisoryRepository(auditDest, fs).LoadAdvisories()
+		if err != nil {
+			return err
+		}
+		if len(db.Advisories()) == 0 {
+			return fmt.Errorf("no advisories in %s: import a database with \"npm-pkg audit import\"", auditDest)
+		}
+		repo := repositories.NewLocalNpmRepository(auditDest, fs, "")
+		vulnerabilities, err := services.AuditLocalRepository(repo, db)
+		if err != nil {
+			return err
+		}
+
+		if auditJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(vulnerabilities); err != nil {
+				return err
+			}
+		} else {
+			printVulnerabilities(vulnerabilities)
+		}
+
+		failing := 0
+		for _, vulnerability := range vulnerabilities {
+			if entities.SeverityLevel(vulnerability.Severity()) >= entities.SeverityLevel(auditLevel) {
+				failing++
+			}
+		}
+		if failing > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d vulnerable versions at or above %s severity", failing, auditLevel)
+		}
+		return nil
+	},
+}
+
+// auditImportCmd represents the "audit import" command
+var auditImportCmd = &cobra.Command{
+	Use:   "import <path>",
+	Short: "Import an OSV advisory database into the local repository",
+	Long: `import reads the npm advisories of an OSV database and merges them into the advisories
+of the local repository. The path is a JSON file, a zip archive such as the npm dump of
+osv.dev, or a directory such as a clone of the GitHub Advisory Database. Withdrawn
+advisories are removed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyAuditConfig(cmd); err != nil {
+			return err
+		}
+		repo := repositories.NewAdvisoryRepository(auditDest, filesystem.NewOsFileSystem())
+		imported, err := repo.ReadOSV(args[0])
+		if err != nil {
+			return err
+		}
+		db, err := repo.LoadAdvisories()
+		if err != nil {
+			return err
+		}
+		db.Merge(imported.Advisories, imported.Withdrawn)
+		if err := repo.SaveAdvisories(db); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d npm advisories (%d withdrawn): %d advisories in %s\n",
+			len(imported.Advisories), len(imported.Withdrawn), len(db.Advisories()), auditDest)
+		return nil
+	},
+}
+
+// auditServeCmd represents the "audit serve" command
+var auditServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the bulk advisory endpoint used by npm audit",
+	Long: `serve answers ` + server.BulkAdvisoriesPath + ` from the imported advisories, so that
+npm audit works against the offline registry. Route this path of the registry to the
+listening address in its reverse proxy, e.g.:
+        npm-pkg audit serve --dest=./mirror --listen=127.0.0.1:8081`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyAuditConfig(cmd); err != nil {
+			return err
+		}
+		db, err := repositories.NewAdvisoryRepository(auditDest, filesystem.NewOsFileSystem()).LoadAdvisories()
+		if err != nil {
+			return err
+		}
+		var handler http.Handler = server.BulkAdvisoriesHandler(db)
+		registry := newMetricsRegistry()
+		if registry != nil {
+			handler = server.NewMetrics(registry).Instrument(server.BulkAdvisoriesPath, handler)
+		}
+		stopMetrics, err := startMetricsListener(registry, logger.NewLogger(zapcore.InfoLevel, true))
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+		mux := http.NewServeMux()
+		mux.Handle(server.BulkAdvisoriesPath, handler)
+		fmt.Printf("Serving %d advisories on http://%s%s\n", len(db.Advisories()), auditListen, server.BulkAdvisoriesPath)
+		return http.ListenAndServe(auditListen, mux)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditImportCmd, auditServeCmd)
+
+	defaults := config.Default()
+	auditCmd.PersistentFlags().StringVarP(&auditDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false,
+		"Print the vulnerable versions as JSON")
+	auditCmd.Flags().StringVar(&auditLevel, "audit-level", "low",
+		"Lowest severity failing the audit: info, low, moderate, high or critical")
+	auditServeCmd.Flags().StringVar(&auditListen, "listen", "127.0.0.1:8081",
+		"Address the endpoint listens on")
+	addMetricsFlag(auditServeCmd)
+}
+
+// applyAuditConfig applies the configuration file to the flags of the audit commands.
+func applyAuditConfig(cmd *cobra.Command) error {
+	loaded, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fromConfig(cmd.Flags(), "dest", &auditDest, loaded.Dest)
+	return nil
+}
+
+func printVulnerabilities(vulnerabilities []entities.Vulnerability) {
+	if len(vulnerabilities) == 0 {
+		fmt.Println("No vulnerable versions found")
+		return
+	}
+	for _, vulnerability := range vulnerabilities {
+		fmt.Printf("%s@%s (%s)\n", vulnerability.Package, vulnerability.Version, vulnerability.Severity())
+		for _, advisory := range vulnerability.Advisories {
+			ids := append([]string{advisory.ID}, advisory.Aliases...)
+			fmt.Printf("  - %s [%s] %s\n    %s\n", strings.Join(ids, ", "), advisory.Severity, advisory.Summary, advisory.URL)
+		}
+	}
+	fmt.Printf("%d vulnerable versions\n", len(vulnerabilities))
+}
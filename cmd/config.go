@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// configFile is the configuration file, npm-pkg.yaml by default.
+	configFile string
+	// configProfile is the profile of the configuration file to apply.
+	configProfile string
+)
+
+// configCmd represents the "config" command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	Long: `The configuration is read from npm-pkg.yaml in the working directory, or from the
+file given with --config or NPM_PKG_CONFIG. A profile of the file is applied with
+--profile or NPM_PKG_PROFILE, then the NPM_PKG_* environment variables, e.g.
+NPM_PKG_DOWNLOAD_WORKERS or NPM_PKG_REGISTRY_URL, override its values.`,
+}
+
+// configShowCmd represents the "config show" command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(loaded.Config)
+		if err != nil {
+			return fmt.Errorf("failed to encode the configuration: %w", err)
+		}
+
+		file := loaded.File
+		if file == "" {
+			file = "none"
+		}
+		fmt.Printf("# Configuration file: %s\n", file)
+		if loaded.Profile != "" {
+			fmt.Printf("# Profile: %s\n", loaded.Profile)
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+}
+
+// loadConfig loads the configuration selected by the --config and --profile flags.
+func loadConfig() (config.Loaded, error) {
+	return config.Load(config.ResolvePath(configFile), configProfile)
+}
+
+// fromConfig sets a flag variable to its configured value, unless the flag was given.
+func fromConfig[T any](flags *pflag.FlagSet, name string, target *T, value T) {
+	if !flags.Changed(name) {
+		*target = value
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	diffDest string
+	diffJSON bool
+)
+
+// diffCmd represents the "diff" command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-snapshot> [new-snapshot]",
+	Short: "Compare the local repository, or a snapshot, with a previous snapshot",
+	Long: `diff lists the packages and versions added and removed since a snapshot written by the
+snapshot command, the license changes, the new install scripts and the maintainer changes. The
+current content of the local repository is compared, or a second snapshot when given, e.g.:
+        npm-pkg diff before.json --dest=./mirror
+        npm-pkg diff before.json after.json --json`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		fromConfig(cmd.Flags(), "dest", &diffDest, loaded.Dest)
+
+		old, err := readSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		var current entities.Snapshot
+		if len(args) == 2 {
+			current, err = readSnapshot(args[1])
+		} else {
+			repo := repositories.NewLocalNpmRepository(diffDest, filesystem.NewOsFileSystem(), "")
+			current, err = services.SnapshotRepository(repo, time.Now().UTC().Truncate(time.Second))
+		}
+		if err != nil {
+			return err
+		}
+		diff := current.Diff(old)
+
+		if diffJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(diff)
+		}
+		printSnapshotDiff(diff)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	defaults := config.Default()
+	diffCmd.Flags().StringVarP(&diffDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository, compared when no second snapshot is given")
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false,
+		"Print the differences as JSON")
+}
+
+// printSnapshotDiff prints the differences, the changes to review first.
+func printSnapshotDiff(diff entities.SnapshotDiff) {
+	if diff.IsEmpty() {
+		fmt.Println("No difference")
+		return
+	}
+	fmt.Printf("%d packages added, %d removed; %d versions added, %d removed\n",
+		len(diff.AddedPackages), len(diff.RemovedPackages), len(diff.AddedVersions), len(diff.RemovedVersions))
+	if len(diff.InstallScripts) > 0 {
+		fmt.Printf("  - New install scripts: %d\n", len(diff.InstallScripts))
+		for _, change := range diff.InstallScripts {
+			fmt.Printf("    * %s@%s: %s\n", change.Package, change.Version, strings.Join(change.Scripts, ", "))
+		}
+	}
+	if len(diff.LicenseChanges) > 0 {
+		fmt.Printf("  - License changes: %d\n", len(diff.LicenseChanges))
+		for _, change := range diff.LicenseChanges {
+			fmt.Printf("    * %s %s -> %s: %s -> %s\n", change.Package, change.Previous, change.Version,
+				orNoAssertion(change.From), orNoAssertion(change.To))
+		}
+	}
+	if len(diff.MaintainersChanges) > 0 {
+		fmt.Printf("  - Maintainer changes: %d\n", len(diff.MaintainersChanges))
+		for _, change := range diff.MaintainersChanges {
+			var changes []string
+			for _, name := range change.Added {
+				changes = append(changes, "+"+name)
+			}
+			for _, name := range change.Removed {
+				changes = append(changes, "-"+name)
+			}
+			fmt.Printf("    * %s %s -> %s: %s\n", change.Package, change.Previous, change.Version, strings.Join(changes, ", "))
+		}
+	}
+	printPackageVersions("Added versions", diff.AddedVersions)
+	printPackageVersions("Removed versions", diff.RemovedVersions)
+}
+
+// printPackageVersions prints versions grouped by package.
+func printPackageVersions(title string, versions []entities.PackageVersion) {
+	if len(versions) == 0 {
+		return
+	}
+	fmt.Printf("  - %s: %d\n", title, len(versions))
+	for i := 0; i < len(versions); {
+		j := i
+		var list []string
+		for ; j < len(versions) && versions[j].Package == versions[i].Package; j++ {
+			list = append(list, versions[j].Version)
+		}
+		fmt.Printf("    * %s: %s\n", versions[i].Package, strings.Join(list, ", "))
+		i = j
+	}
+}
+
+// orNoAssertion returns NOASSERTION for a missing license.
+func orNoAssertion(license string) string {
+	if license == "" {
+		return entities.NoAssertion
+	}
+	return license
+}
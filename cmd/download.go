@@ -5,13 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
 	"github.com/npmoffline/internal/pkg/filesystem"
-	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/npmoffline/internal/pkg/logger"
 	"github.com/npmoffline/internal/repositories"
 	"github.com/npmoffline/internal/services"
@@ -30,7 +31,15 @@ var (
 	downloadWorkers int
 
 	updateLocalRepository bool
+	abbreviatedMetadata   bool
+	followOptionalDeps    bool
+	followOptionalPeers   bool
+	targetPlatforms       []string
+	reportFile            string
+	deprecatedPolicy      string
+	registryURL           string
 	verbose               bool
+	downloadDryRun        bool
 )
 
 // downloadCmd represents the "download" subcommand
@@ -51,7 +60,14 @@ You can combine these flags and arguments in a single command.`,
 	// RunE is used instead of Run so that we can return an error if needed.
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		// 1) Aggregate all packages from CLI args, file, or package.json
+		// 0) Load the configuration: the flags which are not given take its values
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		applyDownloadConfig(cmd, loaded.Config)
+
+		// 1) Aggregate all packages from CLI args, configuration, file, or package.json
 		var pkgList []string
 
 		// a) Direct CLI arguments
@@ -59,7 +75,12 @@ You can combine these flags and arguments in a single command.`,
 			pkgList = append(pkgList, args...)
 		}
 
-		// b) If a file with packages is specified
+		// b) Packages of the configuration
+		for _, entry := range loaded.Packages {
+			pkgList = append(pkgList, entry.String())
+		}
+
+		// c) If a file with packages is specified
 		if packageListFile != "" {
 			filePkgs, err := parsePackageListFile(packageListFile)
 			if err != nil {
@@ -68,7 +89,7 @@ You can combine these flags and arguments in a single command.`,
 			pkgList = append(pkgList, filePkgs...)
 		}
 
-		// c) If a package.json file is specified
+		// d) If a package.json file is specified
 		if packageJSONFile != "" {
 			pkgJSONPkgs, err := parsePackageJSON(packageJSONFile)
 			if err != nil {
@@ -82,6 +103,23 @@ You can combine these flags and arguments in a single command.`,
 			return fmt.Errorf("no packages were specified to download")
 		}
 
+		platforms, err := entities.ParsePlatforms(targetPlatforms)
+		if err != nil {
+			return err
+		}
+		deprecated, err := services.ParseDeprecatedPolicy(deprecatedPolicy)
+		if err != nil {
+			return err
+		}
+		selection, err := newSelectionPolicies(cmd, loaded.Selection)
+		if err != nil {
+			return err
+		}
+		policy, err := loadPackagePolicy(policyFile)
+		if err != nil {
+			return err
+		}
+
 		// 2) Create a context with a timeout (adjust time as you see fit)
 		ctx, cancel := context.WithTimeout(context.Background(), time.Hour*24)
 		defer cancel()
@@ -93,19 +131,56 @@ You can combine these flags and arguments in a single command.`,
 		if verbose {
 			logLevel = zapcore.DebugLevel
 		}
-		log := logger.NewLogger(logLevel, true)
-		httpCli := httpclient.NewHttpClient(http.DefaultClient)
+		// On an interactive terminal, the progress is redrawn in place below the log lines,
+		// otherwise it is logged every second.
+		var progress services.ProgressReporter
+		var logOutput io.Writer = os.Stdout
+		if isTerminal(os.Stdout) {
+			terminal := newTerminalProgress(os.Stdout)
+			progress, logOutput = terminal, terminal
+		}
+		log := logger.NewLoggerWithOutput(logLevel, true, logOutput)
+		// The metrics are recorded and served only when the listener is enabled.
+		registry := newMetricsRegistry()
+		stopMetrics, err := startMetricsListener(registry, log)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+		var runMetrics *services.Metrics
+		if registry != nil {
+			runMetrics = services.NewMetrics(registry)
+		}
+		httpCli, err := newHttpClient(registry)
+		if err != nil {
+			return fmt.Errorf("failed to configure the HTTP client: %w", err)
+		}
 		fs := filesystem.NewOsFileSystem()
-		npmRepo := repositories.NewNpmRepository("https://registry.npmjs.org", httpCli, log)
+		npmRepo := repositories.NewNpmRepository(registryURL, httpCli, log)
 		fileRepo := repositories.NewLocalNpmRepository(downloadDest, fs, downloadStateFile)
+		// The packages removed with a tombstone are never downloaded again.
+		if policy.Tombstones, err = fileRepo.LoadTombstones(); err != nil {
+			return err
+		}
 
-		serv := services.NewNpmDownloadService(npmRepo, fileRepo, log)
+		serv := services.NewNpmDownloadService(npmRepo, fileRepo, log, services.MetadataOptions{
+			AbbreviatedMetadata:            abbreviatedMetadata,
+			FollowOptionalDependencies:     followOptionalDeps,
+			FollowOptionalPeerDependencies: followOptionalPeers,
+			Platforms:                      platforms,
+			DeprecatedPolicy:               deprecated,
+			Selection:                      selection,
+			Policy:                         policy,
+			DryRun:                         downloadDryRun,
+			Metrics:                        runMetrics,
+		})
 
 		// Pass the options for parallel workers and update local repository
 		options := services.DownloadPackagesOptions{
 			MetadataWorkers:       metadataWorkers,
 			DownloadWorkers:       downloadWorkers,
 			UpdateLocalRepository: updateLocalRepository,
+			Progress:              progress,
 		}
 
 		serv.DownloadPackages(ctx, pkgList, options)
@@ -127,6 +202,17 @@ You can combine these flags and arguments in a single command.`,
 			fmt.Printf("    * %s\n", p)
 		}
 
+		report := serv.Report()
+		printRunReport(report)
+		if downloadDryRun {
+			printDownloadEstimate(report.DownloadEstimate())
+		}
+		if reportFile != "" {
+			if err := writeRunReport(report, reportFile); err != nil {
+				return fmt.Errorf("failed to write the run report: %w", err)
+			}
+		}
+
 		fmt.Println("All done. Have a great day!")
 		return nil
 	},
@@ -136,8 +222,11 @@ func init() {
 	// Attach downloadCmd to the root command
 	rootCmd.AddCommand(downloadCmd)
 
+	// The flags default to the values of the default configuration
+	defaults := config.Default()
+
 	// Define flags for the downloadCmd
-	downloadCmd.Flags().StringVarP(&downloadDest, "dest", "d", ".",
+	downloadCmd.Flags().StringVarP(&downloadDest, "dest", "d", defaults.Dest,
 		"Destination folder for downloaded packages")
 	downloadCmd.Flags().StringVarP(&packageListFile, "file", "f", "",
 		"Path to a file containing a list of packages (one package per line)")
@@ -146,19 +235,144 @@ func init() {
 	downloadCmd.Flags().StringVarP(
 		&downloadStateFile,
 		"state-file", "s",
-		"./download_state",
+		defaults.StateFile,
 		"Path to the file storing the state of already-downloaded packages",
 	)
+	downloadCmd.Flags().StringVar(&registryURL, "registry", defaults.Registry.URL,
+		"URL of the registry the packages are downloaded from")
 
 	// Define flags for configuring the parallelism
-	downloadCmd.Flags().IntVar(&metadataWorkers, "metadata-workers", 5,
+	downloadCmd.Flags().IntVar(&metadataWorkers, "metadata-workers", defaults.MetadataWorkers,
 		"Number of parallel workers for fetching metadata")
-	downloadCmd.Flags().IntVar(&downloadWorkers, "download-workers", 100,
+	downloadCmd.Flags().IntVar(&downloadWorkers, "download-workers", defaults.DownloadWorkers,
 		"Number of parallel workers for downloading tarballs")
 
 	// Define flag for updating local repository
-	downloadCmd.Flags().BoolVar(&updateLocalRepository, "update-local-repository", true,
+	downloadCmd.Flags().BoolVar(&updateLocalRepository, "update-local-repository", defaults.UpdateLocalRepository,
 		"Check for package updates present in the local repository via the state file (default true)")
+
+	// Define flag for the metadata format
+	downloadCmd.Flags().BoolVar(&abbreviatedMetadata, "abbreviated-metadata", defaults.AbbreviatedMetadata,
+		"Resolve the dependencies from the abbreviated metadata, and download the full packuments only to store them")
+
+	// Define flags for the optional dependencies
+	downloadCmd.Flags().BoolVar(&followOptionalDeps, "optional-dependencies", defaults.OptionalDependencies,
+		"Also download the optionalDependencies, such as the platform binaries of native modules")
+	downloadCmd.Flags().BoolVar(&followOptionalPeers, "optional-peer-dependencies", defaults.OptionalPeerDependencies,
+		"Also download the peer dependencies marked as optional in peerDependenciesMeta")
+
+	downloadCmd.Flags().StringSliceVar(&targetPlatforms, "platform", defaults.Platforms,
+		"Target platforms as os-cpu[-libc], e.g. linux-x64-glibc,linux-arm64; versions which cannot be installed on any of them are skipped")
+
+	downloadCmd.Flags().StringVar(&deprecatedPolicy, "deprecated", defaults.Deprecated,
+		"Policy for the deprecated versions: include, exclude or if-required (only the versions a dependent needs)")
+	downloadCmd.Flags().StringVar(&reportFile, "report", defaults.Report,
+		"Path of a JSON file where the run report is written")
+	downloadCmd.Flags().BoolVarP(&verbose, "verbose", "v", defaults.Verbose,
+		"Enable debug logs")
+	downloadCmd.Flags().BoolVar(&downloadDryRun, "dry-run", false,
+		"Resolve the versions to download and estimate their size without writing tarballs, package.json files or the state")
+
+	// Define flags for the version selection
+	addSelectionFlags(downloadCmd)
+
+	// Define flags for the package policy
+	addPolicyFlags(downloadCmd, defaults.Policy)
+
+	// Define flags for the outbound networking
+	addNetworkFlags(downloadCmd)
+
+	// Define the flag of the metrics listener
+	addMetricsFlag(downloadCmd)
+}
+
+// applyDownloadConfig sets the flags which were not given to their configured value.
+func applyDownloadConfig(cmd *cobra.Command, cfg config.Config) {
+	flags := cmd.Flags()
+	fromConfig(flags, "dest", &downloadDest, cfg.Dest)
+	fromConfig(flags, "state-file", &downloadStateFile, cfg.StateFile)
+	fromConfig(flags, "registry", &registryURL, cfg.Registry.URL)
+	fromConfig(flags, "metadata-workers", &metadataWorkers, cfg.MetadataWorkers)
+	fromConfig(flags, "download-workers", &downloadWorkers, cfg.DownloadWorkers)
+	fromConfig(flags, "update-local-repository", &updateLocalRepository, cfg.UpdateLocalRepository)
+	fromConfig(flags, "abbreviated-metadata", &abbreviatedMetadata, cfg.AbbreviatedMetadata)
+	fromConfig(flags, "optional-dependencies", &followOptionalDeps, cfg.OptionalDependencies)
+	fromConfig(flags, "optional-peer-dependencies", &followOptionalPeers, cfg.OptionalPeerDependencies)
+	fromConfig(flags, "platform", &targetPlatforms, cfg.Platforms)
+	fromConfig(flags, "deprecated", &deprecatedPolicy, cfg.Deprecated)
+	fromConfig(flags, "report", &reportFile, cfg.Report)
+	fromConfig(flags, "verbose", &verbose, cfg.Verbose)
+	fromConfig(flags, "policy", &policyFile, cfg.Policy)
+	applyNetworkConfig(cmd, cfg.Registry)
+}
+
+// printRunReport prints the remote tarballs, the deprecated versions, the unpublished packages,
+// the dependencies which could not be mirrored and the versions blocked by the policy.
+func printRunReport(report *entities.RunReport) {
+	if tarballs := report.RemoteTarballs(); len(tarballs) > 0 {
+		fmt.Printf("  - Remote tarballs: %d\n", len(tarballs))
+		for _, tarball := range tarballs {
+			fmt.Printf("    * %s (%s)\n", tarball.URL, tarball.Integrity)
+		}
+	}
+	if deprecated := report.Deprecated(); len(deprecated) > 0 {
+		fmt.Printf("  - Deprecated versions: %d\n", len(deprecated))
+		for _, version := range deprecated {
+			status := "skipped"
+			if version.Downloaded {
+				status = "downloaded"
+			}
+			fmt.Printf("    * %s@%s (%s): %s\n", version.Package, version.Version, status, version.Message)
+		}
+	}
+	if unpublished := report.Unpublished(); len(unpublished) > 0 {
+		fmt.Printf("  - Unpublished packages: %d\n", len(unpublished))
+		for _, pkg := range unpublished {
+			fmt.Printf("    * %s (unpublished on %s)\n", pkg.Name, pkg.Time.Format(time.RFC3339))
+		}
+	}
+	if unmirrorable := report.Unmirrorable(); len(unmirrorable) > 0 {
+		fmt.Printf("  - Unmirrorable dependencies: %d\n", len(unmirrorable))
+		for _, dep := range unmirrorable {
+			fmt.Printf("    * %s -> %s: %s (%s)\n", dep.Dependent, dep.Name, dep.Spec, dep.Type)
+		}
+	}
+	var blocked []entities.PolicyDecision
+	for _, decision := range report.PolicyDecisions() {
+		if !decision.Allowed() {
+			blocked = append(blocked, decision)
+		}
+	}
+	if len(blocked) > 0 {
+		fmt.Printf("  - Blocked by the policy: %d\n", len(blocked))
+		for _, decision := range blocked {
+			name := decision.Package
+			if decision.Version != "" {
+				name += "@" + decision.Version
+			}
+			fmt.Printf("    * %s: %s\n", name, decision.Reason)
+		}
+	}
+}
+
+// printDownloadEstimate prints the number and size of the tarballs a dry run would download.
+func printDownloadEstimate(estimate entities.DownloadEstimate) {
+	fmt.Println("Dry run, nothing was written:")
+	fmt.Printf("  - Would download: %d versions of %d packages\n", estimate.Versions, estimate.Packages)
+	fmt.Printf("  - Tarballs size: %s\n", formatSize(estimate.Size))
+	fmt.Printf("  - Unpacked size: %s\n", formatSize(estimate.UnpackedSize))
+	if estimate.Unknown > 0 {
+		fmt.Printf("  - Tarballs of unknown size: %d\n", estimate.Unknown)
+	}
+}
+
+// writeRunReport writes the run report as JSON.
+func writeRunReport(report *entities.RunReport, filePath string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
 }
 
 // parsePackageListFile reads a file line by line and returns a slice of package names.
@@ -183,13 +397,15 @@ func parsePackageListFile(filePath string) ([]string, error) {
 // PackageJSON represents the relevant fields we care about in a package.json
 // You can add fields for devDependencies, peerDependencies, etc. as needed.
 type PackageJSON struct {
-	Dependencies     map[string]string `json:"dependencies,omitempty"`
-	DevDependencies  map[string]string `json:"devDependencies,omitempty"`
-	PeerDependencies map[string]string `json:"peerDependencies,omitempty"`
+	Dependencies         map[string]string `json:"dependencies,omitempty"`
+	DevDependencies      map[string]string `json:"devDependencies,omitempty"`
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
 }
 
 // parsePackageJSON reads a package.json file and collects package names from
-// dependencies, devDependencies, and peerDependencies.
+// dependencies, devDependencies, peerDependencies and optionalDependencies.
+// Optional dependencies are skipped when --optional-dependencies=false.
 func parsePackageJSON(filePath string) ([]string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -211,6 +427,11 @@ func parsePackageJSON(filePath string) ([]string, error) {
 	for name := range pkg.PeerDependencies {
 		pkgs = append(pkgs, name)
 	}
+	if followOptionalDeps {
+		for name := range pkg.OptionalDependencies {
+			pkgs = append(pkgs, name)
+		}
+	}
 
 	return pkgs, nil
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	graphDest   string
+	graphFormat string
+	graphOutput string
+)
+
+// graphCmd represents the "graph" command
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Export the dependency graph of the local repository",
+	Long: `graph exports the dependencies discovered by the download runs: as JSON, with an edge per
+dependent version, or as a Graphviz DOT graph with a node per package, e.g.:
+        npm-pkg graph --dest=./mirror --format=dot | dot -Tsvg > graph.svg`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if graphFormat != "dot" && graphFormat != "json" {
+			return fmt.Errorf("invalid format %q: expected dot or json", graphFormat)
+		}
+		graph, err := loadDependencyGraph(cmd, &graphDest)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if graphOutput != "" {
+			file, err := os.Create(graphOutput)
+			if err != nil {
+				return fmt.Errorf("failed to create the graph: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		if graphFormat == "dot" {
+			_, err = io.WriteString(w, graph.DOT())
+		} else {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(graph)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write the graph: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	defaults := config.Default()
+	graphCmd.Flags().StringVarP(&graphDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot",
+		"Format of the graph: dot or json")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "",
+		"File where the graph is written (defaults to the standard output)")
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	infoDest      string
+	infoStateFile string
+	infoJSON      bool
+)
+
+// infoCmd represents the "info" command
+var infoCmd = &cobra.Command{
+	Use:   "info <package>",
+	Short: "Show a package of the local repository",
+	Long: `info shows the versions of a package stored in the local repository with their size,
+download time and integrity, verified against the stored package.json, the versions of the
+package.json without a tarball, the dist-tags and whether the download state tracks it, e.g.:
+        npm-pkg info react --dest=./mirror`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		flags := cmd.Flags()
+		fromConfig(flags, "dest", &infoDest, loaded.Dest)
+		fromConfig(flags, "state-file", &infoStateFile, loaded.StateFile)
+
+		repo := repositories.NewLocalNpmRepository(infoDest, filesystem.NewOsFileSystem(), infoStateFile)
+		info, err := services.InspectLocalPackage(repo, args[0])
+		if err != nil {
+			return err
+		}
+
+		if infoJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(info)
+		}
+		cmd.SilenceUsage = true
+		return printPackageInfo(info)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	defaults := config.Default()
+	infoCmd.Flags().StringVarP(&infoDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	infoCmd.Flags().StringVarP(&infoStateFile, "state-file", "s", defaults.StateFile,
+		"Path to the state file of the downloads")
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false,
+		"Print the package as JSON")
+}
+
+func printPackageInfo(info entities.PackageInfo) error {
+	fmt.Printf("%s: %d versions stored, %s\n", info.Name, len(info.Versions), formatSize(info.Size()))
+	if info.Tracked {
+		fmt.Printf("Tracked by the download state, last sync %s\n", info.LastSync.Format(time.RFC3339))
+	} else {
+		fmt.Println("Not tracked by the download state")
+	}
+	fmt.Printf("Dist-tags: %s\n", formatDistTags(info.DistTags))
+	if len(info.RegistryDistTags) > 0 {
+		fmt.Printf("Registry dist-tags: %s\n", formatDistTags(info.RegistryDistTags))
+	}
+
+	fmt.Println()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tSIZE\tDOWNLOADED\tINTEGRITY")
+	for _, version := range info.Versions {
+		status := string(version.Status)
+		if version.Deprecated != "" {
+			status += " (deprecated)"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", version.Version, formatSize(version.Size), version.DownloadedAt.Format(time.RFC3339), status)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if len(info.Missing) > 0 {
+		fmt.Printf("\n%d versions of the package.json are not stored: %s\n", len(info.Missing), strings.Join(info.Missing, ", "))
+	}
+	if corrupted := info.Corrupted(); len(corrupted) > 0 {
+		return fmt.Errorf("%d tarballs do not match their integrity: %s", len(corrupted), strings.Join(corrupted, ", "))
+	}
+	return nil
+}
+
+// formatDistTags writes the dist-tags as "latest=1.0.0, next=2.0.0-rc.1".
+func formatDistTags(tags entities.DistTags) string {
+	if len(tags) == 0 {
+		return "none"
+	}
+	var pairs []string
+	for tag, version := range tags {
+		pairs = append(pairs, tag+"="+version)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	licensesDest   string
+	licensesFormat string
+	licensesOutput string
+)
+
+// licensesCmd represents the "licenses" command
+var licensesCmd = &cobra.Command{
+	Use:   "licenses",
+	Short: "Report the licenses of the packages of the local repository",
+	Long: `licenses lists the license of every version stored in the local repository, read from
+the stored package.json and normalised to an SPDX expression. Missing licenses and licenses
+which are not made of known SPDX identifiers are flagged as unknown, and licenses violating
+the package policy given with --policy as denied, e.g.:
+        npm-pkg licenses --dest=./mirror --format=html --output=licenses.html`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		flags := cmd.Flags()
+		fromConfig(flags, "dest", &licensesDest, loaded.Dest)
+		fromConfig(flags, "policy", &policyFile, loaded.Policy)
+
+		write, ok := licenseWriters[licensesFormat]
+		if !ok {
+			return fmt.Errorf("invalid format %q: expected csv, json or html", licensesFormat)
+		}
+		policy, err := loadPackagePolicy(policyFile)
+		if err != nil {
+			return err
+		}
+
+		repo := repositories.NewLocalNpmRepository(licensesDest, filesystem.NewOsFileSystem(), "")
+		report, err := services.BuildLicenseReport(repo, policy)
+		if err != nil {
+			return err
+		}
+
+		if licensesOutput == "" {
+			return write(os.Stdout, report)
+		}
+		file, err := os.Create(licensesOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create the report: %w", err)
+		}
+		defer file.Close()
+		if err := write(file, report); err != nil {
+			return fmt.Errorf("failed to write the report: %w", err)
+		}
+		fmt.Printf("%d versions, %d flagged: report written to %s\n", len(report.Entries), len(report.Flagged()), licensesOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(licensesCmd)
+
+	defaults := config.Default()
+	licensesCmd.Flags().StringVarP(&licensesDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	licensesCmd.Flags().StringVar(&licensesFormat, "format", "csv",
+		"Format of the report: csv, json or html")
+	licensesCmd.Flags().StringVarP(&licensesOutput, "output", "o", "",
+		"File where the report is written (defaults to the standard output)")
+	addPolicyFlags(licensesCmd, defaults.Policy)
+}
+
+// licenseWriters write the license report in each format.
+var licenseWriters = map[string]func(io.Writer, entities.LicenseReport) error{
+	"csv":  writeLicensesCSV,
+	"json": writeLicensesJSON,
+	"html": writeLicensesHTML,
+}
+
+func writeLicensesCSV(w io.Writer, report entities.LicenseReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"package", "version", "license", "status", "reason"}); err != nil {
+		return err
+	}
+	for _, entry := range report.Entries {
+		if err := writer.Write([]string{entry.Package, entry.Version, entry.License, string(entry.Status), entry.Reason}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeLicensesJSON(w io.Writer, report entities.LicenseReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Summary []entities.LicenseCount `json:"summary"`
+		Entries []entities.LicenseEntry `json:"entries"`
+	}{
+		Summary: report.Summary(),
+		Entries: report.Entries,
+	})
+}
+
+// licensesTemplate is the HTML report: the summary by license, the flagged versions and all the versions.
+var licensesTemplate = template.Must(template.New("licenses").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>License report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.unknown { background: #fff3cd; }
+.denied { background: #f8d7da; }
+</style>
+</head>
+<body>
+<h1>License report</h1>
+<p>{{len .Entries}} versions, {{len .Flagged}} flagged.</p>
+<h2>Summary</h2>
+<table>
+<tr><th>License</th><th>Status</th><th>Versions</th></tr>
+{{range .Summary}}<tr class="{{.Status}}"><td>{{.License}}</td><td>{{.Status}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{if .Flagged}}<h2>Flagged versions</h2>
+<table>
+<tr><th>Package</th><th>Version</th><th>License</th><th>Status</th><th>Reason</th></tr>
+{{range .Flagged}}<tr class="{{.Status}}"><td>{{.Package}}</td><td>{{.Version}}</td><td>{{.License}}</td><td>{{.Status}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+{{end}}<h2>All versions</h2>
+<table>
+<tr><th>Package</th><th>Version</th><th>License</th><th>Status</th></tr>
+{{range .Entries}}<tr class="{{.Status}}"><td>{{.Package}}</td><td>{{.Version}}</td><td>{{.License}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func writeLicensesHTML(w io.Writer, report entities.LicenseReport) error {
+	return licensesTemplate.Execute(w, report)
+}
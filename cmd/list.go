@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	listDest   string
+	listJSON   bool
+	listFilter string
+)
+
+// listCmd represents the "list" command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the packages of the local repository",
+	Long: `list prints the packages of the local repository with their number of stored versions
+and the disk usage of their directory, e.g.:
+        npm-pkg list --dest=./mirror --filter='@babel/*'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		fromConfig(cmd.Flags(), "dest", &listDest, loaded.Dest)
+
+		repo := repositories.NewLocalNpmRepository(listDest, filesystem.NewOsFileSystem(), "")
+		packages, err := services.ListLocalPackages(repo, listFilter)
+		if err != nil {
+			return err
+		}
+
+		if listJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(packages)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "PACKAGE\tVERSIONS\tSIZE")
+		versions, size := 0, int64(0)
+		for _, pkg := range packages {
+			fmt.Fprintf(writer, "%s\t%d\t%s\n", pkg.Name, pkg.Versions, formatSize(pkg.Size))
+			versions += pkg.Versions
+			size += pkg.Size
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("%d packages, %d versions, %s\n", len(packages), versions, formatSize(size))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	defaults := config.Default()
+	listCmd.Flags().StringVarP(&listDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	listCmd.Flags().BoolVar(&listJSON, "json", false,
+		"Print the packages as JSON")
+	listCmd.Flags().StringVar(&listFilter, "filter", "",
+		"Only list the packages whose name matches this glob, such as \"@babel/*\"")
+}
+
+// formatSize writes a size in bytes with a binary unit, such as "1.5 MiB".
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/npmoffline/internal/pkg/metrics"
+	"github.com/npmoffline/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// metricsListen is the address of the metrics listener, disabled when empty.
+var metricsListen string
+
+// addMetricsFlag registers the flag enabling the metrics listener on the given command.
+func addMetricsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&metricsListen, "metrics-listen", "",
+		"Address of a listener exposing Prometheus metrics on "+server.MetricsPath+", e.g. 127.0.0.1:9464 (disabled when empty)")
+}
+
+// newMetricsRegistry returns the registry of the metrics when the listener is enabled, nil otherwise.
+func newMetricsRegistry() *metrics.Registry {
+	if metricsListen == "" {
+		return nil
+	}
+	return metrics.NewRegistry()
+}
+
+// metricsReadHeaderTimeout bounds the time a scraper may take to send the headers of its request.
+const metricsReadHeaderTimeout = 10 * time.Second
+
+// metricsShutdownTimeout bounds the time left to the scrapes in flight when the command ends.
+const metricsShutdownTimeout = 5 * time.Second
+
+// startMetricsListener serves the metrics of the registry in the background and returns the
+// function stopping the listener, to be called when the command ends. The address is bound
+// before returning so that a busy port is reported, the later serve errors are logged.
+func startMetricsListener(registry *metrics.Registry, log logger.Logger) (func(), error) {
+	if registry == nil {
+		return func() {}, nil
+	}
+	listener, err := net.Listen("tcp", metricsListen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(server.MetricsPath, registry.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: metricsReadHeaderTimeout}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("The metrics listener stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Serving metrics on http://%s%s\n", listener.Addr(), server.MetricsPath)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Warn("Failed to stop the metrics listener: %v", err)
+		}
+	}, nil
+}
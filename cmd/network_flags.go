@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/pkg/httpclient"
+	"github.com/npmoffline/internal/pkg/metrics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// networkOptions holds the outbound networking flags, initialised from the environment.
+	networkOptions = httpclient.DefaultOptions()
+	// rateLimitOptions holds the registry rate limiting flags.
+	rateLimitOptions = httpclient.DefaultRateLimitOptions()
+)
+
+// addNetworkFlags registers the proxy, TLS and timeout flags on the given command.
+func addNetworkFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.StringVar(&networkOptions.HTTPSProxy, "https-proxy", networkOptions.HTTPSProxy,
+		"Proxy URL used for https requests (defaults to HTTPS_PROXY)")
+	flags.StringVar(&networkOptions.HTTPProxy, "http-proxy", networkOptions.HTTPProxy,
+		"Proxy URL used for http requests (defaults to HTTP_PROXY)")
+	flags.StringVar(&networkOptions.NoProxy, "no-proxy", networkOptions.NoProxy,
+		"Comma-separated list of hosts, domains or CIDR blocks that bypass the proxy (defaults to NO_PROXY)")
+
+	flags.StringSliceVar(&networkOptions.CAFiles, "ca-file", nil,
+		"PEM bundle of extra certificate authorities to trust (can be repeated)")
+	flags.StringVar(&networkOptions.ClientCertFile, "client-cert", "",
+		"PEM client certificate used for mutual TLS")
+	flags.StringVar(&networkOptions.ClientKeyFile, "client-key", "",
+		"PEM private key of the client certificate")
+
+	flags.DurationVar(&networkOptions.ConnectTimeout, "connect-timeout", networkOptions.ConnectTimeout,
+		"Timeout for establishing TCP connections")
+	flags.DurationVar(&networkOptions.TLSHandshakeTimeout, "tls-timeout", networkOptions.TLSHandshakeTimeout,
+		"Timeout for the TLS handshake")
+	flags.DurationVar(&networkOptions.ResponseHeaderTimeout, "response-header-timeout", networkOptions.ResponseHeaderTimeout,
+		"Timeout waiting for the response headers")
+	flags.DurationVar(&networkOptions.IdleConnTimeout, "idle-conn-timeout", networkOptions.IdleConnTimeout,
+		"Time an idle connection is kept open")
+	flags.IntVar(&networkOptions.MaxConnsPerHost, "max-conns-per-host", networkOptions.MaxConnsPerHost,
+		"Maximum number of connections per host (0 means no limit)")
+
+	flags.Float64Var(&rateLimitOptions.RequestsPerSecond, "requests-per-second", rateLimitOptions.RequestsPerSecond,
+		"Maximum request rate per registry host, lowered automatically when throttled (0 means no limit)")
+	flags.IntVar(&rateLimitOptions.MaxConcurrentPerHost, "max-concurrent-per-host", rateLimitOptions.MaxConcurrentPerHost,
+		"Maximum number of in-flight requests per registry host, which caps the workers fetching from the registry at once (0 means no limit)")
+}
+
+// applyNetworkConfig sets the networking flags which were not given to their configured value.
+func applyNetworkConfig(cmd *cobra.Command, registry config.Registry) {
+	flags := cmd.Flags()
+	network, rateLimit := registry.NetworkOptions(), registry.RateLimitOptions()
+
+	fromConfig(flags, "https-proxy", &networkOptions.HTTPSProxy, network.HTTPSProxy)
+	fromConfig(flags, "http-proxy", &networkOptions.HTTPProxy, network.HTTPProxy)
+	fromConfig(flags, "no-proxy", &networkOptions.NoProxy, network.NoProxy)
+	fromConfig(flags, "ca-file", &networkOptions.CAFiles, network.CAFiles)
+	fromConfig(flags, "client-cert", &networkOptions.ClientCertFile, network.ClientCertFile)
+	fromConfig(flags, "client-key", &networkOptions.ClientKeyFile, network.ClientKeyFile)
+	fromConfig(flags, "connect-timeout", &networkOptions.ConnectTimeout, network.ConnectTimeout)
+	fromConfig(flags, "tls-timeout", &networkOptions.TLSHandshakeTimeout, network.TLSHandshakeTimeout)
+	fromConfig(flags, "response-header-timeout", &networkOptions.ResponseHeaderTimeout, network.ResponseHeaderTimeout)
+	fromConfig(flags, "idle-conn-timeout", &networkOptions.IdleConnTimeout, network.IdleConnTimeout)
+	fromConfig(flags, "max-conns-per-host", &networkOptions.MaxConnsPerHost, network.MaxConnsPerHost)
+	fromConfig(flags, "requests-per-second", &rateLimitOptions.RequestsPerSecond, rateLimit.RequestsPerSecond)
+	fromConfig(flags, "max-concurrent-per-host", &rateLimitOptions.MaxConcurrentPerHost, rateLimit.MaxConcurrentPerHost)
+}
+
+// newHttpClient builds the HTTP client from the networking and rate limiting flags. The requests
+// are recorded in the metrics registry when it is not nil.
+func newHttpClient(registry *metrics.Registry) (httpclient.Client, error) {
+	// A requested rate below the default floor of the adaptive rate becomes the floor.
+	if rate := rateLimitOptions.RequestsPerSecond; rate > 0 && rate < rateLimitOptions.MinRequestsPerSecond {
+		rateLimitOptions.MinRequestsPerSecond = rate
+	}
+	if err := rateLimitOptions.Validate(); err != nil {
+		return nil, err
+	}
+	// Keep an idle connection for each request allowed in flight, so that the workers reuse them.
+	if networkOptions.MaxIdleConnsPerHost == 0 {
+		networkOptions.MaxIdleConnsPerHost = rateLimitOptions.MaxConcurrentPerHost
+	}
+	client, err := httpclient.NewHttpClientWithOptions(networkOptions)
+	if err != nil {
+		return nil, err
+	}
+	if registry != nil {
+		client = httpclient.NewInstrumentedClient(client, registry)
+	}
+	return httpclient.NewRateLimitedClient(client, rateLimitOptions), nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the YAML file of the package policy.
+var policyFile string
+
+// addPolicyFlags registers the package policy flags on the given command.
+func addPolicyFlags(cmd *cobra.Command, defaultFile string) {
+	cmd.Flags().StringVar(&policyFile, "policy", defaultFile,
+		"YAML file with the rules allowing or blocking packages by name, version, license, maintainer or install scripts")
+}
+
+// loadPackagePolicy reads and compiles the package policy. Without a file, every version is allowed.
+func loadPackagePolicy(path string) (entities.PackagePolicy, error) {
+	var policy entities.PackagePolicy
+	if path == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read the policy: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return policy, fmt.Errorf("failed to parse the policy %s: %w", path, err)
+	}
+	if err := policy.Compile(); err != nil {
+		return policy, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/services"
+)
+
+// terminalProgress redraws the progress of a download in place on an interactive terminal.
+// The log output goes through it, so that the log lines are printed above the progress.
+type terminalProgress struct {
+	mutex sync.Mutex
+	out   io.Writer
+	// drawn is the number of lines of the progress currently on the terminal.
+	drawn int
+	lines []string
+}
+
+func newTerminalProgress(out io.Writer) *terminalProgress {
+	return &terminalProgress{out: out}
+}
+
+// Write prints log output above the progress.
+func (p *terminalProgress) Write(data []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.clear()
+	n, err := p.out.Write(data)
+	p.draw()
+	return n, err
+}
+
+// Update redraws the progress.
+func (p *terminalProgress) Update(snapshot entities.ProgressSnapshot) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.clear()
+	p.lines = progressLines(snapshot)
+	p.draw()
+}
+
+// Finish redraws the progress a last time and leaves it on the terminal.
+func (p *terminalProgress) Finish(snapshot entities.ProgressSnapshot) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.clear()
+	p.lines = progressLines(snapshot)
+	p.draw()
+	p.lines = nil
+	p.drawn = 0
+}
+
+// clear moves the cursor up to the first line of the progress and erases the progress.
+func (p *terminalProgress) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA\033[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+func (p *terminalProgress) draw() {
+	for _, line := range p.lines {
+		fmt.Fprintln(p.out, line)
+	}
+	p.drawn = len(p.lines)
+}
+
+// progressLines formats the progress of each stage and of the whole run.
+func progressLines(snapshot entities.ProgressSnapshot) []string {
+	metadata, download := snapshot.Metadata, snapshot.Download
+	return []string{
+		fmt.Sprintf("Metadata  %6d/%-6d active %-3d retries %-4d failures %-4d ETA %s",
+			metadata.Done, metadata.Queued, metadata.Active, metadata.Retries, metadata.Failures, services.FormatETA(metadata.ETA(snapshot.Elapsed))),
+		fmt.Sprintf("Tarballs  %6d/%-6d active %-3d retries %-4d failures %-4d ETA %s",
+			download.Done, download.Queued, download.Active, download.Retries, download.Failures, services.FormatETA(download.ETA(snapshot.Elapsed))),
+		fmt.Sprintf("Received  %s at %s/s, elapsed %s, ETA %s",
+			formatSize(snapshot.Bytes), formatSize(snapshot.BytesPerSecond()), snapshot.Elapsed.Round(time.Second), services.FormatETA(snapshot.ETA())),
+	}
+}
+
+// isTerminal reports whether the file is an interactive terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
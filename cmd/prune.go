@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	pruneDest             string
+	pruneStateFile        string
+	pruneKeepLast         int
+	pruneLockfiles        []string
+	prunePrereleaseMaxAge string
+	pruneDryRun           bool
+	pruneJSON             bool
+)
+
+// pruneCmd represents the "prune" command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old versions from the local repository",
+	Long: `prune removes from the local repository the versions selected by a retention policy:
+the versions beyond the N highest of each package, and the pre-releases older than a given age.
+The versions installed by the given lockfiles are always kept. The tarballs are deleted, the
+stored package.json, the download state and the dependency graph are updated, e.g.:
+        npm-pkg prune --dest=./mirror --keep-last=5 --lockfile=./app/package-lock.json --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		flags := cmd.Flags()
+		fromConfig(flags, "dest", &pruneDest, loaded.Dest)
+		fromConfig(flags, "state-file", &pruneStateFile, loaded.StateFile)
+
+		policy, err := newRetentionPolicy()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+		repo := repositories.NewLocalNpmRepository(pruneDest, filesystem.NewOsFileSystem(), pruneStateFile)
+		report, err := services.PruneRepository(repo, policy, time.Now(), pruneDryRun)
+		if err != nil {
+			return err
+		}
+
+		if pruneJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+		printPruneReport(report)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	defaults := config.Default()
+	pruneCmd.Flags().StringVarP(&pruneDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	pruneCmd.Flags().StringVarP(&pruneStateFile, "state-file", "s", defaults.StateFile,
+		"File of the download state, from which the removed packages are dropped")
+	pruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0,
+		"Keep the N highest versions of each package")
+	pruneCmd.Flags().StringArrayVar(&pruneLockfiles, "lockfile", nil,
+		"package-lock.json or npm-shrinkwrap.json whose versions are always kept, can be repeated")
+	pruneCmd.Flags().StringVar(&prunePrereleaseMaxAge, "prerelease-older-than", "",
+		"Remove the pre-releases released before this age, such as 90d or 720h")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false,
+		"Print what would be removed and the bytes freed, without removing anything")
+	pruneCmd.Flags().BoolVar(&pruneJSON, "json", false,
+		"Print the report as JSON")
+}
+
+// newRetentionPolicy builds the retention policy from the flags.
+func newRetentionPolicy() (entities.RetentionPolicy, error) {
+	policy := entities.RetentionPolicy{KeepLast: pruneKeepLast, Locked: entities.LockedVersions{}}
+	if pruneKeepLast < 0 {
+		return policy, fmt.Errorf("invalid --keep-last %d: expected a positive number", pruneKeepLast)
+	}
+	if prunePrereleaseMaxAge != "" {
+		age, err := parseAge(prunePrereleaseMaxAge)
+		if err != nil {
+			return policy, fmt.Errorf("invalid --prerelease-older-than %q: expected a duration such as 90d or 720h", prunePrereleaseMaxAge)
+		}
+		policy.PrereleaseMaxAge = age
+	}
+	if policy.IsEmpty() {
+		return policy, fmt.Errorf("no retention policy: set --keep-last or --prerelease-older-than")
+	}
+	for _, lockfile := range pruneLockfiles {
+		data, err := os.ReadFile(lockfile)
+		if err != nil {
+			return policy, fmt.Errorf("failed to read the lockfile: %w", err)
+		}
+		if err := policy.Locked.ParsePackageLock(data); err != nil {
+			return policy, fmt.Errorf("failed to parse the lockfile %s: %w", lockfile, err)
+		}
+	}
+	return policy, nil
+}
+
+// parseAge parses a Go duration, or a number of days such as "90d".
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return age, nil
+}
+
+// printPruneReport prints the removed versions of each package and the bytes freed.
+func printPruneReport(report entities.PruneReport) {
+	verb, freed := "Removed", "freed"
+	if report.DryRun {
+		verb, freed = "Would remove", "would free"
+	}
+	for _, pkg := range report.Packages {
+		suffix := ""
+		if pkg.Deleted {
+			suffix = " (no version left, package removed)"
+		}
+		fmt.Printf("%s %s %s, %s%s\n", verb, pkg.Name, strings.Join(pkg.Removed, ", "), formatSize(pkg.Freed), suffix)
+	}
+	fmt.Printf("%d versions of %d packages, %s %s\n", report.Removed(), len(report.Packages), freed, formatSize(report.Freed()))
+}
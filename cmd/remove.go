@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	removeDest      string
+	removeStateFile string
+	removeTombstone bool
+	removeReason    string
+	removeDryRun    bool
+	removeJSON      bool
+)
+
+// removeCmd represents the "remove" command
+var removeCmd = &cobra.Command{
+	Use:   "remove <package>[@range]...",
+	Short: "Remove packages from the local repository",
+	Long: `remove deletes packages, or the versions of a range, from the local repository: the
+tarballs are deleted, the stored package.json is updated, and the removed packages are dropped
+from the download state and the dependency graph. With --tombstone, the next runs never download
+them again, even as a dependency, e.g.:
+        npm-pkg remove event-stream@3.3.6 left-pad --dest=./mirror --tombstone --reason="compromised"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		flags := cmd.Flags()
+		fromConfig(flags, "dest", &removeDest, loaded.Dest)
+		fromConfig(flags, "state-file", &removeStateFile, loaded.StateFile)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		var targets []entities.Tombstone
+		for _, arg := range args {
+			pkg := entities.NewRetrievePackage(arg)
+			target, err := entities.NewTombstone(pkg.Name, pkg.Spec, removeReason, now)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target)
+		}
+
+		cmd.SilenceUsage = true
+		repo := repositories.NewLocalNpmRepository(removeDest, filesystem.NewOsFileSystem(), removeStateFile)
+		report, err := services.RemovePackages(repo, targets, services.RemoveOptions{
+			Tombstone: removeTombstone,
+			DryRun:    removeDryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if removeJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+		printPruneReport(report)
+		if removeTombstone && !report.DryRun {
+			for _, target := range targets {
+				fmt.Printf("Tombstone recorded for %s\n", target)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	defaults := config.Default()
+	removeCmd.Flags().StringVarP(&removeDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	removeCmd.Flags().StringVarP(&removeStateFile, "state-file", "s", defaults.StateFile,
+		"File of the download state, from which the removed packages are dropped")
+	removeCmd.Flags().BoolVar(&removeTombstone, "tombstone", false,
+		"Record a tombstone so that the next runs never download the removed packages or versions again")
+	removeCmd.Flags().StringVar(&removeReason, "reason", "",
+		"Reason of the removal, recorded in the tombstone and shown when a run blocks the package")
+	removeCmd.Flags().BoolVar(&removeDryRun, "dry-run", false,
+		"Print what would be removed and the bytes freed, without removing anything")
+	removeCmd.Flags().BoolVar(&removeJSON, "json", false,
+		"Print the report as JSON")
+}
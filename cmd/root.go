@@ -26,6 +26,8 @@ func Execute() {
 
 // init is where you can define persistent flags or global config for rootCmd
 func init() {
-	// Example of persistent flag on the root command:
-	// rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "",
+		"Configuration file (defaults to NPM_PKG_CONFIG, else npm-pkg.yaml if it exists)")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "",
+		"Profile of the configuration file to apply (defaults to NPM_PKG_PROFILE)")
 }
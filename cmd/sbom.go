@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	sbomDest   string
+	sbomFormat string
+	sbomOutput string
+)
+
+// sbomCmd represents the "sbom" command
+var sbomCmd = &cobra.Command{
+	Use:   "sbom",
+	Short: "Generate a software bill of materials of the local repository",
+	Long: `sbom lists every version stored in the local repository as a CycloneDX or SPDX JSON
+document: each component has its purl, the hashes of its integrity, its license and the
+stored versions resolving its dependencies, e.g.:
+        npm-pkg sbom --dest=./mirror --format=spdx-json --output=mirror.spdx.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		fromConfig(cmd.Flags(), "dest", &sbomDest, loaded.Dest)
+
+		write, ok := services.SBOMWriters[sbomFormat]
+		if !ok {
+			return fmt.Errorf("invalid format %q: expected cyclonedx-json or spdx-json", sbomFormat)
+		}
+		repo := repositories.NewLocalNpmRepository(sbomDest, filesystem.NewOsFileSystem(), "")
+		sbom, err := services.BuildSBOM(repo)
+		if err != nil {
+			return err
+		}
+
+		if sbomOutput == "" {
+			return write(os.Stdout, sbom, services.NewSBOMDocument(time.Now()))
+		}
+		file, err := os.Create(sbomOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create the SBOM: %w", err)
+		}
+		defer file.Close()
+		if err := write(file, sbom, services.NewSBOMDocument(time.Now())); err != nil {
+			return fmt.Errorf("failed to write the SBOM: %w", err)
+		}
+		fmt.Printf("%d components: SBOM written to %s\n", len(sbom.Components), sbomOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sbomCmd)
+
+	defaults := config.Default()
+	sbomCmd.Flags().StringVarP(&sbomDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	sbomCmd.Flags().StringVar(&sbomFormat, "format", "cyclonedx-json",
+		"Format of the SBOM: cyclonedx-json or spdx-json")
+	sbomCmd.Flags().StringVarP(&sbomOutput, "output", "o", "",
+		"File where the SBOM is written (defaults to the standard output)")
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// selectionPolicy holds the default version selection flags.
+	selectionPolicy entities.SelectionPolicy
+	// releasedAfter is the --released-after date, as YYYY-MM-DD.
+	releasedAfter string
+	// selectionConfigFile is the YAML file holding the default and per package selection policies.
+	selectionConfigFile string
+)
+
+// addSelectionFlags registers the version selection flags on the given command.
+func addSelectionFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.IntVar(&selectionPolicy.Latest, "latest", 0,
+		"Only download the N highest versions of each package")
+	flags.BoolVar(&selectionPolicy.LatestPerMajor, "latest-per-major", false,
+		"Only download the highest version of each major version")
+	flags.StringVar(&releasedAfter, "released-after", "",
+		"Ignore the versions released before this date (YYYY-MM-DD)")
+	flags.BoolVar(&selectionPolicy.DistTagsOnly, "dist-tags-only", false,
+		"Only download the versions pointed to by a dist-tag, such as latest or next")
+	flags.BoolVar(&selectionPolicy.RequiredOnly, "required-only", false,
+		"Only download the versions npm would install for the ranges required by the dependents")
+	flags.StringVar(&selectionConfigFile, "selection-config", "",
+		"YAML file with the default selection policy and the policies of some packages; the selection flags override its default policy")
+}
+
+// newSelectionPolicies builds the selection policies from the configuration, the selection
+// configuration file, which replaces the configured policies, and the flags. Only the flags given
+// on the command line override the default policy.
+func newSelectionPolicies(cmd *cobra.Command, policies entities.SelectionPolicies) (entities.SelectionPolicies, error) {
+	if selectionConfigFile != "" {
+		policies = entities.SelectionPolicies{}
+		data, err := os.ReadFile(selectionConfigFile)
+		if err != nil {
+			return policies, fmt.Errorf("failed to read the selection config: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&policies); err != nil && !errors.Is(err, io.EOF) {
+			return policies, fmt.Errorf("failed to parse the selection config %s: %w", selectionConfigFile, err)
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("latest") {
+		policies.Default.Latest = selectionPolicy.Latest
+	}
+	if flags.Changed("latest-per-major") {
+		policies.Default.LatestPerMajor = selectionPolicy.LatestPerMajor
+	}
+	if flags.Changed("released-after") {
+		date, err := time.Parse(time.DateOnly, releasedAfter)
+		if err != nil {
+			return policies, fmt.Errorf("invalid --released-after date %q: expected YYYY-MM-DD", releasedAfter)
+		}
+		policies.Default.ReleasedAfter = date
+	}
+	if flags.Changed("dist-tags-only") {
+		policies.Default.DistTagsOnly = selectionPolicy.DistTagsOnly
+	}
+	if flags.Changed("required-only") {
+		policies.Default.RequiredOnly = selectionPolicy.RequiredOnly
+	}
+
+	if err := policies.Validate(); err != nil {
+		return policies, fmt.Errorf("invalid selection policy: %w", err)
+	}
+	return policies, nil
+}
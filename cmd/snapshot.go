@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/npmoffline/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	snapshotDest   string
+	snapshotOutput string
+)
+
+// snapshotCmd represents the "snapshot" command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Write a manifest of the content of the local repository",
+	Long: `snapshot writes the versions stored in the local repository with their license, install
+scripts, maintainers and integrity as JSON, to be compared later with the diff command, e.g.:
+        npm-pkg snapshot --dest=./mirror --output=before.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		fromConfig(cmd.Flags(), "dest", &snapshotDest, loaded.Dest)
+
+		repo := repositories.NewLocalNpmRepository(snapshotDest, filesystem.NewOsFileSystem(), "")
+		snapshot, err := services.SnapshotRepository(repo, time.Now().UTC().Truncate(time.Second))
+		if err != nil {
+			return err
+		}
+
+		if snapshotOutput == "" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(snapshot)
+		}
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode the snapshot: %w", err)
+		}
+		if err := os.WriteFile(snapshotOutput, data, 0644); err != nil {
+			return fmt.Errorf("failed to write the snapshot: %w", err)
+		}
+		fmt.Printf("%d packages: snapshot written to %s\n", len(snapshot.Packages), snapshotOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	defaults := config.Default()
+	snapshotCmd.Flags().StringVarP(&snapshotDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	snapshotCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "",
+		"File where the snapshot is written (defaults to the standard output)")
+}
+
+// readSnapshot reads a snapshot written by the snapshot command.
+func readSnapshot(path string) (entities.Snapshot, error) {
+	var snapshot entities.Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read the snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/npmoffline/internal/config"
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/spf13/cobra"
+)
+
+// Flags
+var (
+	whyDest      string
+	whyMaxChains int
+)
+
+// whyCmd represents the "why" command
+var whyCmd = &cobra.Command{
+	Use:   "why <package>",
+	Short: "Explain why a package is in the local repository",
+	Long: `why prints the shortest dependency chains leading from the packages given as input of
+the download runs to a package, with the range each dependent required, e.g.:
+        npm-pkg why iconv-lite --dest=./mirror
+        express@4.18.2 > body-parser@1.20.1 (1.20.1) > iconv-lite (0.4.24)`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		graph, err := loadDependencyGraph(cmd, &whyDest)
+		if err != nil {
+			return err
+		}
+		name := entities.NewRetrievePackage(args[0]).Name
+		chains := graph.Why(name, whyMaxChains)
+		if len(chains) == 0 {
+			return fmt.Errorf("%s was not discovered by any download run into %s", name, whyDest)
+		}
+		for _, chain := range chains {
+			if len(chain) == 1 {
+				fmt.Printf("%s (requested directly)\n", name)
+				continue
+			}
+			fmt.Println(chain.String())
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+
+	defaults := config.Default()
+	whyCmd.Flags().StringVarP(&whyDest, "dest", "d", defaults.Dest,
+		"Folder of the local repository")
+	whyCmd.Flags().IntVar(&whyMaxChains, "max-chains", 20,
+		"Maximum number of chains printed (0 for all)")
+}
+
+// loadDependencyGraph loads the dependency graph of the local repository given by the dest flag.
+func loadDependencyGraph(cmd *cobra.Command, dest *string) (entities.DependencyGraph, error) {
+	loaded, err := loadConfig()
+	if err != nil {
+		return entities.DependencyGraph{}, err
+	}
+	fromConfig(cmd.Flags(), "dest", dest, loaded.Dest)
+	repo := repositories.NewLocalNpmRepository(*dest, filesystem.NewOsFileSystem(), "")
+	return repo.LoadDependencyGraph()
+}
@@ -0,0 +1,350 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultFileName is the configuration file loaded from the working directory when no file is given.
+	DefaultFileName = "npm-pkg.yaml"
+	// DefaultRegistry is the registry the packages are downloaded from.
+	DefaultRegistry = "https://registry.npmjs.org"
+	// ConfigEnv and ProfileEnv select the configuration file and the profile.
+	ConfigEnv  = "NPM_PKG_CONFIG"
+	ProfileEnv = "NPM_PKG_PROFILE"
+)
+
+// Config is the configuration of the download command. Its values come, by increasing priority,
+// from the defaults, the configuration file, the selected profile of the file, the NPM_PKG_*
+// environment variables and the command line flags.
+type Config struct {
+	Dest                     string                     `yaml:"dest"`
+	StateFile                string                     `yaml:"stateFile"`
+	MetadataWorkers          int                        `yaml:"metadataWorkers"`
+	DownloadWorkers          int                        `yaml:"downloadWorkers"`
+	UpdateLocalRepository    bool                       `yaml:"updateLocalRepository"`
+	AbbreviatedMetadata      bool                       `yaml:"abbreviatedMetadata"`
+	OptionalDependencies     bool                       `yaml:"optionalDependencies"`
+	OptionalPeerDependencies bool                       `yaml:"optionalPeerDependencies"`
+	Platforms                []string                   `yaml:"platforms"`
+	Deprecated               string                     `yaml:"deprecated"`
+	Report                   string                     `yaml:"report"`
+	Verbose                  bool                       `yaml:"verbose"`
+	Policy                   string                     `yaml:"policy"`
+	Packages                 []PackageEntry             `yaml:"packages"`
+	Registry                 Registry                   `yaml:"registry"`
+	Selection                entities.SelectionPolicies `yaml:"selection"`
+}
+
+// Registry holds the registry URL and the outbound networking settings.
+type Registry struct {
+	URL                   string   `yaml:"url"`
+	HTTPSProxy            string   `yaml:"httpsProxy"`
+	HTTPProxy             string   `yaml:"httpProxy"`
+	NoProxy               string   `yaml:"noProxy"`
+	CAFiles               []string `yaml:"caFiles"`
+	ClientCert            string   `yaml:"clientCert"`
+	ClientKey             string   `yaml:"clientKey"`
+	ConnectTimeout        Duration `yaml:"connectTimeout"`
+	TLSTimeout            Duration `yaml:"tlsTimeout"`
+	ResponseHeaderTimeout Duration `yaml:"responseHeaderTimeout"`
+	IdleConnTimeout       Duration `yaml:"idleConnTimeout"`
+	MaxConnsPerHost       int      `yaml:"maxConnsPerHost"`
+	RequestsPerSecond     float64  `yaml:"requestsPerSecond"`
+	MaxConcurrentPerHost  int      `yaml:"maxConcurrentPerHost"`
+}
+
+// NetworkOptions returns the options of the HTTP transport.
+func (r Registry) NetworkOptions() httpclient.Options {
+	return httpclient.Options{
+		HTTPSProxy:            r.HTTPSProxy,
+		HTTPProxy:             r.HTTPProxy,
+		NoProxy:               r.NoProxy,
+		CAFiles:               r.CAFiles,
+		ClientCertFile:        r.ClientCert,
+		ClientKeyFile:         r.ClientKey,
+		ConnectTimeout:        time.Duration(r.ConnectTimeout),
+		TLSHandshakeTimeout:   time.Duration(r.TLSTimeout),
+		ResponseHeaderTimeout: time.Duration(r.ResponseHeaderTimeout),
+		IdleConnTimeout:       time.Duration(r.IdleConnTimeout),
+		MaxConnsPerHost:       r.MaxConnsPerHost,
+	}
+}
+
+// RateLimitOptions returns the options of the registry rate limiter.
+func (r Registry) RateLimitOptions() httpclient.RateLimitOptions {
+	options := httpclient.DefaultRateLimitOptions()
+	options.RequestsPerSecond = r.RequestsPerSecond
+	options.MaxConcurrentPerHost = r.MaxConcurrentPerHost
+	return options
+}
+
+// Duration is a time.Duration written as "30s" or "1m30s".
+type Duration time.Duration
+
+// UnmarshalText parses a duration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// UnmarshalYAML parses a duration.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+// MarshalYAML writes the duration as a string.
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+// PackageEntry is a package to download, written as a string, "name[@spec][|pre-release regex]",
+// or as a mapping with the name and the pre-release regex.
+type PackageEntry struct {
+	Name       string `yaml:"name"`
+	PreRelease string `yaml:"preRelease,omitempty"`
+}
+
+// String returns the entry as given on the command line.
+func (p PackageEntry) String() string {
+	if p.PreRelease == "" {
+		return p.Name
+	}
+	return p.Name + "|" + p.PreRelease
+}
+
+// UnmarshalText parses the string form of an entry.
+func (p *PackageEntry) UnmarshalText(text []byte) error {
+	p.Name, p.PreRelease, _ = strings.Cut(strings.TrimSpace(string(text)), "|")
+	return nil
+}
+
+// UnmarshalYAML parses an entry written as a string or as a mapping.
+func (p *PackageEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return p.UnmarshalText([]byte(node.Value))
+	}
+	type plain PackageEntry
+	return node.Decode((*plain)(p))
+}
+
+// MarshalYAML writes the entry as a string.
+func (p PackageEntry) MarshalYAML() (any, error) {
+	return p.String(), nil
+}
+
+// Default returns the default configuration, which matches the defaults of the command line flags.
+// The proxies are read from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+func Default() Config {
+	network := httpclient.DefaultOptions()
+	rateLimit := httpclient.DefaultRateLimitOptions()
+	return Config{
+		Dest:                     ".",
+		StateFile:                "./download_state",
+		MetadataWorkers:          5,
+		DownloadWorkers:          100,
+		UpdateLocalRepository:    true,
+		OptionalDependencies:     true,
+		OptionalPeerDependencies: true,
+		Deprecated:               "include",
+		Registry: Registry{
+			URL:                   DefaultRegistry,
+			HTTPSProxy:            network.HTTPSProxy,
+			HTTPProxy:             network.HTTPProxy,
+			NoProxy:               network.NoProxy,
+			ConnectTimeout:        Duration(network.ConnectTimeout),
+			TLSTimeout:            Duration(network.TLSHandshakeTimeout),
+			ResponseHeaderTimeout: Duration(network.ResponseHeaderTimeout),
+			IdleConnTimeout:       Duration(network.IdleConnTimeout),
+			MaxConnsPerHost:       network.MaxConnsPerHost,
+			RequestsPerSecond:     rateLimit.RequestsPerSecond,
+			MaxConcurrentPerHost:  rateLimit.MaxConcurrentPerHost,
+		},
+	}
+}
+
+// file is the layout of the configuration file: the default values, and profiles overriding them.
+type file struct {
+	Config `yaml:",inline"`
+	// Profiles are decoded from the nodes of the file once the profile is known.
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+}
+
+// Loaded is a configuration with the origin of its values.
+type Loaded struct {
+	Config
+	// File is the configuration file read, empty if none.
+	File string
+	// Profile is the profile applied, empty if none.
+	Profile string
+	// sources tell where the values were set: file lines and environment variables.
+	sources *sources
+}
+
+// ResolvePath returns the configuration file to load: the given path, else the NPM_PKG_CONFIG
+// environment variable, else npm-pkg.yaml if it exists in the working directory, else none.
+func ResolvePath(path string) string {
+	if path != "" {
+		return path
+	}
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path
+	}
+	if _, err := os.Stat(DefaultFileName); err == nil {
+		return DefaultFileName
+	}
+	return ""
+}
+
+// Load reads the configuration file, applies the profile and the environment variables, and
+// validates the result. Without a file, the defaults and the environment are used. An empty
+// profile selects the NPM_PKG_PROFILE environment variable, if set.
+func Load(path string, profile string) (Loaded, error) {
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	loaded := Loaded{Config: Default(), File: path, Profile: profile, sources: newSources(path)}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to read the configuration: %w", err)
+		}
+		if err := loaded.decode(data); err != nil {
+			return loaded, err
+		}
+	} else if profile != "" {
+		return loaded, fmt.Errorf("profile %q requires a configuration file", profile)
+	}
+
+	if err := applyEnv(&loaded.Config, loaded.sources); err != nil {
+		return loaded, err
+	}
+	if errs := loaded.Validate(); len(errs) > 0 {
+		return loaded, errs
+	}
+	return loaded, nil
+}
+
+// decode applies the file, then its profile, to the configuration. Unknown keys are rejected.
+func (l *Loaded) decode(data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("%s: %v", l.File, err)
+	}
+	if len(root.Content) == 0 {
+		return nil // Empty file.
+	}
+	l.sources.base = root.Content[0]
+
+	parsed := file{Config: l.Config}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil {
+		return fmt.Errorf("%s: %v", l.File, cleanYAMLError(err))
+	}
+	l.Config = parsed.Config
+
+	if l.Profile == "" {
+		return nil
+	}
+	node := findNode(l.sources.base, []string{"profiles", l.Profile}, true)
+	if node == nil {
+		return fmt.Errorf("%s: unknown profile %q", l.File, l.Profile)
+	}
+	l.sources.profile = node
+	if err := checkKnownKeys(node, reflect.TypeOf(Config{}), nil); err != nil {
+		return fmt.Errorf("%s: profile %s: %v", l.File, l.Profile, err)
+	}
+	if err := node.Decode(&l.Config); err != nil {
+		return fmt.Errorf("%s: profile %s: %v", l.File, l.Profile, cleanYAMLError(err))
+	}
+	return nil
+}
+
+// cleanYAMLError removes the "yaml: " prefix of the decoding errors.
+func cleanYAMLError(err error) string {
+	return strings.ReplaceAll(err.Error(), "yaml: ", "")
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkKnownKeys rejects the keys of a mapping which are not fields of the structure, like the
+// KnownFields option of the decoder, which does not apply to the nodes decoded on their own.
+func checkKnownKeys(node *yaml.Node, valueType reflect.Type, key []string) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if reflect.PointerTo(valueType).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+	switch valueType.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode || valueType == timeType {
+			return nil
+		}
+		fields := structFields(valueType)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := fields[name]
+			if !ok {
+				return fmt.Errorf("line %d: field %s not found in %s", node.Content[i].Line, name, displayKey(key, "configuration"))
+			}
+			if err := checkKnownKeys(node.Content[i+1], field.Type, append(key, name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := checkKnownKeys(node.Content[i+1], valueType.Elem(), append(key, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			if err := checkKnownKeys(item, valueType.Elem(), append(key, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// structFields returns the fields of a structure by YAML key, including the inlined ones.
+func structFields(valueType reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if strings.Contains(options, "inline") {
+			for inlineName, inlineField := range structFields(field.Type) {
+				fields[inlineName] = inlineField
+			}
+			continue
+		}
+		if name != "" && name != "-" {
+			fields[name] = field
+		}
+	}
+	return fields
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `dest: ./mirror
+downloadWorkers: 20
+platforms: [linux-x64-glibc]
+packages:
+  - express
+  - react@next
+  - name: typescript
+    preRelease: ^beta
+registry:
+  url: https://npm.example.com
+  connectTimeout: 5s
+selection:
+  default:
+    latest: 3
+profiles:
+  ci:
+    downloadWorkers: 4
+    registry:
+      url: https://mirror.example.com
+  broken:
+    metadataWorkers: 0
+    registry:
+      clientKey: key.pem
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, testConfig)
+
+	t.Run("File values override the defaults", func(t *testing.T) {
+		loaded, err := Load(path, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "./mirror", loaded.Dest)
+		assert.Equal(t, 20, loaded.DownloadWorkers)
+		assert.Equal(t, 5, loaded.MetadataWorkers)
+		assert.Equal(t, []string{"linux-x64-glibc"}, loaded.Platforms)
+		assert.Equal(t, []PackageEntry{{Name: "express"}, {Name: "react@next"}, {Name: "typescript", PreRelease: "^beta"}}, loaded.Packages)
+		assert.Equal(t, "typescript|^beta", loaded.Packages[2].String())
+		assert.Equal(t, "https://npm.example.com", loaded.Registry.URL)
+		assert.Equal(t, 5*time.Second, loaded.Registry.NetworkOptions().ConnectTimeout)
+		assert.Equal(t, 3, loaded.Selection.Default.Latest)
+	})
+
+	t.Run("Profile values override the file values", func(t *testing.T) {
+		loaded, err := Load(path, "ci")
+
+		require.NoError(t, err)
+		assert.Equal(t, "./mirror", loaded.Dest)
+		assert.Equal(t, 4, loaded.DownloadWorkers)
+		assert.Equal(t, "https://mirror.example.com", loaded.Registry.URL)
+		assert.Equal(t, Duration(5*time.Second), loaded.Registry.ConnectTimeout)
+	})
+
+	t.Run("Environment variables override the profile", func(t *testing.T) {
+		t.Setenv(ProfileEnv, "ci")
+		t.Setenv("NPM_PKG_DOWNLOAD_WORKERS", "8")
+		t.Setenv("NPM_PKG_REGISTRY_URL", "https://env.example.com")
+		t.Setenv("NPM_PKG_PACKAGES", "lodash, vue|^rc")
+		t.Setenv("NPM_PKG_REGISTRY_IDLE_CONN_TIMEOUT", "1m")
+
+		loaded, err := Load(path, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "ci", loaded.Profile)
+		assert.Equal(t, 8, loaded.DownloadWorkers)
+		assert.Equal(t, "https://env.example.com", loaded.Registry.URL)
+		assert.Equal(t, []PackageEntry{{Name: "lodash"}, {Name: "vue", PreRelease: "^rc"}}, loaded.Packages)
+		assert.Equal(t, Duration(time.Minute), loaded.Registry.IdleConnTimeout)
+	})
+
+	t.Run("Invalid environment variable", func(t *testing.T) {
+		t.Setenv("NPM_PKG_METADATA_WORKERS", "many")
+
+		_, err := Load(path, "")
+
+		assert.EqualError(t, err, `NPM_PKG_METADATA_WORKERS: invalid integer "many"`)
+	})
+
+	t.Run("Validation errors give the location of the values", func(t *testing.T) {
+		t.Setenv("NPM_PKG_DEPRECATED", "never")
+
+		_, err := Load(path, "broken")
+
+		var errs ValidationErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 3)
+		assert.Equal(t, `NPM_PKG_DEPRECATED: deprecated: invalid policy "never": expected include, exclude or if-required`, errs[0].Error())
+		assert.Equal(t, path+":21: metadataWorkers: must be at least 1, got 0", errs[1].Error())
+		assert.Equal(t, path+":23: registry.clientCert: clientCert and clientKey must be set together", errs[2].Error())
+	})
+
+	t.Run("Unknown keys are rejected", func(t *testing.T) {
+		_, err := Load(writeConfig(t, "dest: .\ndownloadWorker: 3\n"), "")
+		assert.ErrorContains(t, err, "line 2: field downloadWorker not found")
+
+		_, err = Load(writeConfig(t, "profiles:\n  ci:\n    registry:\n      uri: x\n"), "ci")
+		assert.ErrorContains(t, err, "profile ci: line 4: field uri not found in registry")
+	})
+
+	t.Run("Unknown profile", func(t *testing.T) {
+		_, err := Load(path, "staging")
+		assert.ErrorContains(t, err, `unknown profile "staging"`)
+	})
+
+	t.Run("Defaults without a file", func(t *testing.T) {
+		loaded, err := Load("", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, Default(), loaded.Config)
+
+		_, err = Load("", "ci")
+		assert.Error(t, err)
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "NPM_PKG_DOWNLOAD_WORKERS", EnvName("downloadWorkers"))
+	assert.Equal(t, "NPM_PKG_REGISTRY_URL", EnvName("registry.url"))
+	assert.Equal(t, "NPM_PKG_SELECTION_DEFAULT_LATEST_PER_MAJOR", EnvName("selection.default.latestPerMajor"))
+}
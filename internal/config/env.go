@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration.
+const envPrefix = "NPM_PKG_"
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// EnvName returns the environment variable of a configuration key, e.g. NPM_PKG_REGISTRY_URL
+// for "registry.url" or NPM_PKG_DOWNLOAD_WORKERS for "downloadWorkers".
+func EnvName(key string) string {
+	var name strings.Builder
+	name.WriteString(envPrefix)
+	for i, part := range strings.Split(key, ".") {
+		if i > 0 {
+			name.WriteByte('_')
+		}
+		for j, r := range part {
+			if unicode.IsUpper(r) && j > 0 {
+				name.WriteByte('_')
+			}
+			name.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return name.String()
+}
+
+// applyEnv overrides the values of the structure with the environment variables named after
+// their keys. Lists are comma-separated. Maps, such as the policies of the packages, cannot be
+// set from the environment.
+func applyEnv(target any, sources *sources) error {
+	return applyEnvValue(reflect.ValueOf(target).Elem(), "", sources)
+}
+
+func applyEnvValue(value reflect.Value, key string, sources *sources) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldKey := name
+		if key != "" {
+			fieldKey = key + "." + name
+		}
+		fieldValue := value.Field(i)
+
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			if err := applyEnvValue(fieldValue, fieldKey, sources); err != nil {
+				return err
+			}
+			continue
+		}
+		envName := EnvName(fieldKey)
+		raw, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		if err := setFromString(fieldValue, raw); err != nil {
+			return fmt.Errorf("%s: %v", envName, err)
+		}
+		sources.env[fieldKey] = envName
+	}
+	return nil
+}
+
+// setFromString parses a value written in an environment variable.
+func setFromString(value reflect.Value, raw string) error {
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) && value.Type() != timeType {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Struct:
+		date, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return fmt.Errorf("invalid date %q: expected YYYY-MM-DD", raw)
+		}
+		value.Set(reflect.ValueOf(date))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(value.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setFromString(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/npmoffline/internal/entities"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is an invalid value of the configuration.
+type FieldError struct {
+	// Key is the path of the value, e.g. ["registry", "url"] or ["packages", "2"].
+	Key []string
+	// Location is where the value was set: "npm-pkg.yaml:12", an environment variable,
+	// or empty for a default value.
+	Location string
+	Message  string
+}
+
+func (e FieldError) Error() string {
+	message := fmt.Sprintf("%s: %s", displayKey(e.Key, ""), e.Message)
+	if e.Location == "" {
+		return message
+	}
+	return e.Location + ": " + message
+}
+
+// ValidationErrors are all the invalid values of a configuration.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+// displayKey joins the parts of a key with dots, indexes being written in brackets.
+func displayKey(key []string, root string) string {
+	if len(key) == 0 {
+		return root
+	}
+	var display strings.Builder
+	for i, part := range key {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			display.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			display.WriteByte('.')
+		}
+		display.WriteString(part)
+	}
+	return display.String()
+}
+
+// sources tell where the values of a loaded configuration were set.
+type sources struct {
+	file    string
+	base    *yaml.Node
+	profile *yaml.Node
+	// env holds the environment variables by dotted key.
+	env map[string]string
+}
+
+func newSources(file string) *sources {
+	return &sources{file: file, env: map[string]string{}}
+}
+
+// locate returns where the value of a key was set. When the key is missing from the file, such as
+// the clientCert of a configuration only setting the clientKey, the closest parent key is used.
+func (s *sources) locate(key []string) string {
+	if s == nil {
+		return ""
+	}
+	if envName, ok := s.env[strings.Join(key, ".")]; ok {
+		return envName
+	}
+	for _, exact := range []bool{true, false} {
+		for _, root := range []*yaml.Node{s.profile, s.base} {
+			if node := findNode(root, key, exact); node != nil {
+				return fmt.Sprintf("%s:%d", s.file, node.Line)
+			}
+		}
+	}
+	return ""
+}
+
+// findNode returns the node of a key. Unless exact is set, the node of its deepest existing
+// parent key is returned when the key is missing.
+func findNode(node *yaml.Node, key []string, exact bool) *yaml.Node {
+	if node == nil || len(key) == 0 {
+		return node
+	}
+	var child *yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key[0] {
+				child = node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if index, err := strconv.Atoi(key[0]); err == nil && index < len(node.Content) {
+			child = node.Content[index]
+		}
+	}
+	if child == nil {
+		return nil
+	}
+	if found := findNode(child, key[1:], exact); found != nil || exact {
+		return found
+	}
+	return child
+}
+
+// validDeprecatedPolicies are the values of the deprecated setting.
+var validDeprecatedPolicies = []string{"", "include", "exclude", "if-required"}
+
+// Validate checks every value of the configuration and returns all the invalid ones.
+func (l Loaded) Validate() ValidationErrors {
+	var errs ValidationErrors
+	fail := func(message string, key ...string) {
+		errs = append(errs, FieldError{Key: key, Location: l.sources.locate(key), Message: message})
+	}
+
+	c := l.Config
+	if c.Dest == "" {
+		fail("must not be empty", "dest")
+	}
+	if c.StateFile == "" {
+		fail("must not be empty", "stateFile")
+	}
+	if c.MetadataWorkers < 1 {
+		fail(fmt.Sprintf("must be at least 1, got %d", c.MetadataWorkers), "metadataWorkers")
+	}
+	if c.DownloadWorkers < 1 {
+		fail(fmt.Sprintf("must be at least 1, got %d", c.DownloadWorkers), "downloadWorkers")
+	}
+	for i, platform := range c.Platforms {
+		if _, err := entities.ParsePlatform(platform); err != nil {
+			fail(err.Error(), "platforms", strconv.Itoa(i))
+		}
+	}
+	if !slices.Contains(validDeprecatedPolicies, c.Deprecated) {
+		fail(fmt.Sprintf("invalid policy %q: expected include, exclude or if-required", c.Deprecated), "deprecated")
+	}
+
+	for i, entry := range c.Packages {
+		key := []string{"packages", strconv.Itoa(i)}
+		if _, err := entities.NewPackageName(entities.NewRetrievePackage(entry.Name).Name); err != nil {
+			fail(err.Error(), key...)
+		}
+		if _, err := regexp.Compile(entry.PreRelease); err != nil {
+			fail(fmt.Sprintf("invalid pre-release regex %q: %v", entry.PreRelease, err), key...)
+		}
+	}
+
+	r := c.Registry
+	if registry, err := url.Parse(r.URL); err != nil || (registry.Scheme != "http" && registry.Scheme != "https") || registry.Host == "" {
+		fail(fmt.Sprintf("invalid registry URL %q: expected an http or https URL", r.URL), "registry", "url")
+	}
+	for name, proxy := range map[string]string{"httpsProxy": r.HTTPSProxy, "httpProxy": r.HTTPProxy} {
+		if proxy == "" {
+			continue
+		}
+		if proxyURL, err := url.Parse(proxy); err != nil || proxyURL.Host == "" {
+			fail(fmt.Sprintf("invalid proxy URL %q", proxy), "registry", name)
+		}
+	}
+	if (r.ClientCert == "") != (r.ClientKey == "") {
+		fail("clientCert and clientKey must be set together", "registry", "clientCert")
+	}
+	for name, timeout := range map[string]Duration{
+		"connectTimeout":        r.ConnectTimeout,
+		"tlsTimeout":            r.TLSTimeout,
+		"responseHeaderTimeout": r.ResponseHeaderTimeout,
+		"idleConnTimeout":       r.IdleConnTimeout,
+	} {
+		if timeout < 0 {
+			fail("must not be negative", "registry", name)
+		}
+	}
+	if r.MaxConnsPerHost < 0 {
+		fail("must not be negative (0 means no limit)", "registry", "maxConnsPerHost")
+	}
+	if r.RequestsPerSecond < 0 {
+		fail("must not be negative (0 means no limit)", "registry", "requestsPerSecond")
+	}
+	if r.MaxConcurrentPerHost < 0 {
+		fail("must not be negative (0 means no limit)", "registry", "maxConcurrentPerHost")
+	}
+
+	if err := c.Selection.Default.Validate(); err != nil {
+		fail(err.Error(), "selection", "default")
+	}
+	for name, policy := range c.Selection.Packages {
+		if err := (entities.SelectionPolicies{Packages: map[string]entities.SelectionPolicy{name: policy}}).Validate(); err != nil {
+			fail(err.Error(), "selection", "packages", name)
+		}
+	}
+
+	// Maps are iterated in random order.
+	sort.SliceStable(errs, func(i, j int) bool { return strings.Join(errs[i].Key, ".") < strings.Join(errs[j].Key, ".") })
+	return errs
+}
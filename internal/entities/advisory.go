@@ -0,0 +1,145 @@
+package entities
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Severities are the severities of the advisories, from the lowest.
+var Severities = []string{"info", "low", "moderate", "high", "critical"}
+
+// Advisory is a vulnerability of the versions of a package, imported from an advisory database.
+type Advisory struct {
+	// ID is the identifier of the advisory, such as GHSA-jf85-cpcp-j695.
+	ID string `json:"id"`
+	// Aliases are the other identifiers of the vulnerability, such as CVE-2019-10744.
+	Aliases  []string `json:"aliases,omitempty"`
+	Package  string   `json:"package"`
+	Summary  string   `json:"summary"`
+	Severity string   `json:"severity"`
+	URL      string   `json:"url,omitempty"`
+	CWEs     []string `json:"cwes,omitempty"`
+	// Vulnerable is the range of the affected versions, such as ">=4.0.0 <4.17.12".
+	Vulnerable string `json:"vulnerable"`
+	// Versions are affected versions listed without a range.
+	Versions []string  `json:"versions,omitempty"`
+	Modified time.Time `json:"modified"`
+
+	// vulnerable is the parsed Vulnerable range, set by the database.
+	vulnerable *SemVerRange
+}
+
+// Affects returns true if the version is affected by the advisory.
+func (a Advisory) Affects(version SemVer) bool {
+	if slices.Contains(a.Versions, version.String()) {
+		return true
+	}
+	if a.vulnerable == nil {
+		a.compile()
+	}
+	return a.vulnerable != nil && a.vulnerable.Matches(version)
+}
+
+// compile parses the vulnerable range. An invalid range matches no version.
+func (a *Advisory) compile() {
+	if a.Vulnerable == "" {
+		return
+	}
+	if vulnerable, err := ParseSemVerRange(a.Vulnerable); err == nil {
+		a.vulnerable = &vulnerable
+	}
+}
+
+// SeverityLevel returns the rank of a severity in Severities, or -1 if it is unknown.
+func SeverityLevel(severity string) int {
+	return slices.Index(Severities, severity)
+}
+
+// ValidateSeverity checks that a severity is one of Severities.
+func ValidateSeverity(severity string) error {
+	if SeverityLevel(severity) < 0 {
+		return fmt.Errorf("invalid severity %q: expected info, low, moderate, high or critical", severity)
+	}
+	return nil
+}
+
+// AdvisoryDatabase holds the imported advisories, indexed by package.
+type AdvisoryDatabase struct {
+	packages map[string][]Advisory
+}
+
+// NewAdvisoryDatabase creates a database from a list of advisories.
+func NewAdvisoryDatabase(advisories []Advisory) *AdvisoryDatabase {
+	db := &AdvisoryDatabase{packages: map[string][]Advisory{}}
+	db.Merge(advisories, nil)
+	return db
+}
+
+// Merge adds the advisories, replacing the advisories with the same identifier and package, and
+// removes the withdrawn advisories, given by identifier.
+func (db *AdvisoryDatabase) Merge(advisories []Advisory, withdrawn []string) {
+	for name, existing := range db.packages {
+		db.packages[name] = slices.DeleteFunc(existing, func(a Advisory) bool { return slices.Contains(withdrawn, a.ID) })
+	}
+	for _, advisory := range advisories {
+		advisory.compile()
+		existing := db.packages[advisory.Package]
+		index := slices.IndexFunc(existing, func(a Advisory) bool { return a.ID == advisory.ID })
+		if index >= 0 {
+			existing[index] = advisory
+		} else {
+			db.packages[advisory.Package] = append(existing, advisory)
+		}
+	}
+}
+
+// ForPackage returns the advisories of a package.
+func (db *AdvisoryDatabase) ForPackage(name string) []Advisory {
+	return db.packages[name]
+}
+
+// Affecting returns the advisories affecting a version.
+func (db *AdvisoryDatabase) Affecting(name string, version SemVer) []Advisory {
+	var affecting []Advisory
+	for _, advisory := range db.packages[name] {
+		if advisory.Affects(version) {
+			affecting = append(affecting, advisory)
+		}
+	}
+	return affecting
+}
+
+// Advisories returns all the advisories, sorted by package and identifier.
+func (db *AdvisoryDatabase) Advisories() []Advisory {
+	var advisories []Advisory
+	for _, existing := range db.packages {
+		advisories = append(advisories, existing...)
+	}
+	sort.Slice(advisories, func(i, j int) bool {
+		if advisories[i].Package != advisories[j].Package {
+			return advisories[i].Package < advisories[j].Package
+		}
+		return advisories[i].ID < advisories[j].ID
+	})
+	return advisories
+}
+
+// Vulnerability is a stored version affected by advisories.
+type Vulnerability struct {
+	Package    string     `json:"package"`
+	Version    string     `json:"version"`
+	Advisories []Advisory `json:"advisories"`
+}
+
+// Severity returns the highest severity of the advisories.
+func (v Vulnerability) Severity() string {
+	severity := ""
+	for _, advisory := range v.Advisories {
+		if SeverityLevel(advisory.Severity) > SeverityLevel(severity) {
+			severity = advisory.Severity
+		}
+	}
+	return severity
+}
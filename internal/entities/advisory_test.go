@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryDatabase(t *testing.T) {
+	version := func(value string) SemVer {
+		v, err := NewSemVer(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	t.Run("Advisories affecting a version", func(t *testing.T) {
+		db := NewAdvisoryDatabase([]Advisory{
+			{ID: "A", Package: "lodash", Severity: "high", Vulnerable: ">=4.0.0 <4.17.12"},
+			{ID: "B", Package: "lodash", Severity: "low", Vulnerable: "<4.17.21"},
+			{ID: "C", Package: "lodash", Severity: "critical", Versions: []string{"3.10.1"}},
+			{ID: "D", Package: "lodash", Severity: "critical", Vulnerable: "^abc"},
+		})
+
+		assert.Len(t, db.Affecting("lodash", version("4.17.11")), 2)
+		assert.Len(t, db.Affecting("lodash", version("4.17.20")), 1)
+		assert.Len(t, db.Affecting("lodash", version("4.17.21")), 0)
+		assert.Len(t, db.Affecting("lodash", version("3.10.1")), 2)
+		assert.Empty(t, db.Affecting("underscore", version("1.0.0")))
+	})
+
+	t.Run("Merge replaces and withdraws advisories", func(t *testing.T) {
+		db := NewAdvisoryDatabase([]Advisory{
+			{ID: "A", Package: "pkg", Vulnerable: "<1.0.0"},
+			{ID: "B", Package: "pkg", Vulnerable: "<2.0.0"},
+		})
+
+		db.Merge([]Advisory{{ID: "A", Package: "pkg", Vulnerable: "<3.0.0"}, {ID: "C", Package: "other"}}, []string{"B"})
+
+		advisories := db.Advisories()
+		assert.Equal(t, []string{"C", "A"}, []string{advisories[0].ID, advisories[1].ID})
+		assert.Len(t, db.Affecting("pkg", version("2.5.0")), 1)
+	})
+
+	t.Run("Highest severity of a vulnerability", func(t *testing.T) {
+		vulnerability := Vulnerability{Advisories: []Advisory{{Severity: "low"}, {Severity: "critical"}, {Severity: "moderate"}}}
+
+		assert.Equal(t, "critical", vulnerability.Severity())
+	})
+}
+
+func TestValidateSeverity(t *testing.T) {
+	assert.NoError(t, ValidateSeverity("high"))
+	assert.EqualError(t, ValidateSeverity("severe"), `invalid severity "severe": expected info, low, moderate, high or critical`)
+}
@@ -0,0 +1,13 @@
+package entities
+
+// CacheValidators holds the HTTP validators returned by the registry with a packument.
+// They are sent back on the next sync to let the registry answer 304 Not Modified.
+type CacheValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// IsEmpty returns true if no validator is available.
+func (c CacheValidators) IsEmpty() bool {
+	return c.ETag == "" && c.LastModified == ""
+}
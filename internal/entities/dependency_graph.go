@@ -0,0 +1,230 @@
+package entities
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DependencyKind is the kind of a dependency edge.
+type DependencyKind string
+
+const (
+	DependencyProd         DependencyKind = "prod"
+	DependencyPeer         DependencyKind = "peer"
+	DependencyOptional     DependencyKind = "optional"
+	DependencyOptionalPeer DependencyKind = "optionalPeer"
+)
+
+// DependencyEdge is a dependency followed by the metadata workers: the version From, written
+// name@version, required the package To with a range.
+type DependencyEdge struct {
+	From  string         `json:"from"`
+	To    string         `json:"to"`
+	Range string         `json:"range"`
+	Kind  DependencyKind `json:"kind"`
+}
+
+// DependencyGraph is the graph of the dependencies discovered by the download runs.
+type DependencyGraph struct {
+	// Roots are the names of the packages given as input of the runs.
+	Roots []string         `json:"roots"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+// ChainLink is a step of a dependency chain: a version and the range it was required with by
+// the previous link. The root link has no range and the last link, the explained package, has
+// no version since the edges lead to packages.
+type ChainLink struct {
+	Package string `json:"package"`
+	Version string `json:"version,omitempty"`
+	Range   string `json:"range,omitempty"`
+}
+
+// DependencyChain leads from a root package to the explained package.
+type DependencyChain []ChainLink
+
+// String writes the chain as "express@4.18.2 > body-parser@1.20.1 (~1.20.1) > qs (^6.11.0)".
+func (c DependencyChain) String() string {
+	links := make([]string, 0, len(c))
+	for _, link := range c {
+		text := link.Package
+		if link.Version != "" {
+			text += "@" + link.Version
+		}
+		if link.Range != "" {
+			text += " (" + link.Range + ")"
+		}
+		links = append(links, text)
+	}
+	return strings.Join(links, " > ")
+}
+
+// Merge adds the roots and edges of another graph, without duplicates, and sorts the graph.
+func (g *DependencyGraph) Merge(other DependencyGraph) {
+	roots := map[string]bool{}
+	for _, root := range append(g.Roots, other.Roots...) {
+		roots[root] = true
+	}
+	edges := map[DependencyEdge]bool{}
+	for _, edge := range append(g.Edges, other.Edges...) {
+		edges[edge] = true
+	}
+
+	g.Roots = make([]string, 0, len(roots))
+	for root := range roots {
+		g.Roots = append(g.Roots, root)
+	}
+	sort.Strings(g.Roots)
+	g.Edges = make([]DependencyEdge, 0, len(edges))
+	for edge := range edges {
+		g.Edges = append(g.Edges, edge)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+}
+
+// RemoveVersions removes the edges of versions, written name@version, removed from the local
+// repository.
+func (g *DependencyGraph) RemoveVersions(versions []string) {
+	removed := map[string]bool{}
+	for _, version := range versions {
+		removed[version] = true
+	}
+	edges := g.Edges[:0]
+	for _, edge := range g.Edges {
+		if !removed[edge.From] {
+			edges = append(edges, edge)
+		}
+	}
+	g.Edges = edges
+}
+
+// RemoveRoot removes a package from the roots.
+func (g *DependencyGraph) RemoveRoot(name string) {
+	roots := g.Roots[:0]
+	for _, root := range g.Roots {
+		if root != name {
+			roots = append(roots, root)
+		}
+	}
+	g.Roots = roots
+}
+
+// Why returns the shortest chains leading from the roots to a package, at most limit chains
+// when limit is positive. A package given as input is explained by a chain of one link.
+func (g DependencyGraph) Why(name string, limit int) []DependencyChain {
+	dependents := map[string][]DependencyEdge{}
+	dependencies := map[string][]string{}
+	for _, edge := range g.Edges {
+		from, _ := splitNameVersion(edge.From)
+		dependents[edge.To] = append(dependents[edge.To], edge)
+		dependencies[from] = append(dependencies[from], edge.To)
+	}
+	for _, edges := range dependents {
+		sort.Slice(edges, func(i, j int) bool { return edges[i].From < edges[j].From })
+	}
+
+	// distance is the length of the shortest chain from a root to each package.
+	distance := map[string]int{}
+	var queue []string
+	for _, root := range g.Roots {
+		if _, ok := distance[root]; !ok {
+			distance[root] = 0
+			queue = append(queue, root)
+		}
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dep := range dependencies[pkg] {
+			if _, ok := distance[dep]; !ok {
+				distance[dep] = distance[pkg] + 1
+				queue = append(queue, dep)
+			}
+		}
+	}
+	if _, ok := distance[name]; !ok {
+		return nil
+	}
+
+	// walk goes up from a package through the dependents one step closer to a root, so that
+	// every path ends at a root: reversed holds the links from the package to the explained one.
+	var chains []DependencyChain
+	var walk func(pkg string, reversed DependencyChain)
+	walk = func(pkg string, reversed DependencyChain) {
+		if distance[pkg] == 0 {
+			chain := make(DependencyChain, len(reversed))
+			for i, link := range reversed {
+				chain[len(reversed)-1-i] = link
+			}
+			chains = append(chains, chain)
+			return
+		}
+		for _, edge := range dependents[pkg] {
+			if limit > 0 && len(chains) >= limit {
+				return
+			}
+			dependent, version := splitNameVersion(edge.From)
+			if d, ok := distance[dependent]; !ok || d != distance[pkg]-1 {
+				continue
+			}
+			reversed[len(reversed)-1].Range = edge.Range
+			walk(dependent, append(reversed, ChainLink{Package: dependent, Version: version}))
+		}
+	}
+	walk(name, DependencyChain{{Package: name}})
+	return chains
+}
+
+// DOT writes the graph in the Graphviz format, one node per package: the edges of the versions
+// of a package are merged, and their ranges joined in the labels.
+func (g DependencyGraph) DOT() string {
+	type pair struct{ from, to string }
+	ranges := map[pair][]string{}
+	var pairs []pair
+	for _, edge := range g.Edges {
+		from, _ := splitNameVersion(edge.From)
+		key := pair{from, edge.To}
+		if _, ok := ranges[key]; !ok {
+			pairs = append(pairs, key)
+		}
+		if !slices.Contains(ranges[key], edge.Range) {
+			ranges[key] = append(ranges[key], edge.Range)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].from != pairs[j].from {
+			return pairs[i].from < pairs[j].from
+		}
+		return pairs[i].to < pairs[j].to
+	})
+
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	for _, root := range g.Roots {
+		fmt.Fprintf(&b, "  %q [shape=box];\n", root)
+	}
+	for _, p := range pairs {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", p.from, p.to, strings.Join(ranges[p], " | "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// splitNameVersion splits name@version, the first "@" of a scoped name being part of the name.
+func splitNameVersion(value string) (string, string) {
+	if i := strings.LastIndex(value, "@"); i > 0 {
+		return value[:i], value[i+1:]
+	}
+	return value, ""
+}
@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	graph := DependencyGraph{
+		Roots: []string{"express", "qs"},
+		Edges: []DependencyEdge{
+			{From: "express@4.18.2", To: "body-parser", Range: "1.20.1", Kind: DependencyProd},
+			{From: "express@4.18.2", To: "@types/qs", Range: "*", Kind: DependencyPeer},
+			{From: "body-parser@1.20.1", To: "raw-body", Range: "2.5.1", Kind: DependencyProd},
+			{From: "body-parser@1.20.1", To: "iconv-lite", Range: "0.4.24", Kind: DependencyProd},
+			{From: "raw-body@2.5.1", To: "iconv-lite", Range: "0.4.24", Kind: DependencyProd},
+			{From: "raw-body@2.5.1", To: "body-parser", Range: "^1.0.0", Kind: DependencyProd},
+			{From: "@types/qs@6.9.7", To: "iconv-lite", Range: "^0.4.0", Kind: DependencyProd},
+		},
+	}
+
+	t.Run("Shortest chains back to the roots", func(t *testing.T) {
+		chains := graph.Why("iconv-lite", 0)
+
+		var lines []string
+		for _, chain := range chains {
+			lines = append(lines, chain.String())
+		}
+		assert.Equal(t, []string{
+			"express@4.18.2 > @types/qs@6.9.7 (*) > iconv-lite (^0.4.0)",
+			"express@4.18.2 > body-parser@1.20.1 (1.20.1) > iconv-lite (0.4.24)",
+		}, lines)
+	})
+
+	t.Run("Chains are limited", func(t *testing.T) {
+		assert.Len(t, graph.Why("iconv-lite", 1), 1)
+	})
+
+	t.Run("Root and unknown packages", func(t *testing.T) {
+		assert.Equal(t, []DependencyChain{{{Package: "qs"}}}, graph.Why("qs", 0))
+		assert.Empty(t, graph.Why("left-pad", 0))
+	})
+
+	t.Run("Merge removes duplicates", func(t *testing.T) {
+		merged := DependencyGraph{Roots: []string{"qs"}, Edges: graph.Edges[:1]}
+
+		merged.Merge(graph)
+
+		assert.Equal(t, []string{"express", "qs"}, merged.Roots)
+		assert.Len(t, merged.Edges, len(graph.Edges))
+		assert.Equal(t, "@types/qs@6.9.7", merged.Edges[0].From)
+	})
+
+	t.Run("DOT export merges the versions of a package", func(t *testing.T) {
+		dot := DependencyGraph{
+			Roots: []string{"a"},
+			Edges: []DependencyEdge{
+				{From: "a@1.0.0", To: "b", Range: "^1.0.0"},
+				{From: "a@2.0.0", To: "b", Range: "^2.0.0"},
+			},
+		}.DOT()
+
+		assert.Equal(t, "digraph dependencies {\n  \"a\" [shape=box];\n  \"a\" -> \"b\" [label=\"^1.0.0 | ^2.0.0\"];\n}\n", dot)
+	})
+}
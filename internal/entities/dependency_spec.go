@@ -0,0 +1,108 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DependencySpecType is the kind of source a dependency is installed from.
+type DependencySpecType string
+
+const (
+	// RegistryDependency is a version range or a dist-tag of a package of the registry.
+	RegistryDependency DependencySpecType = "registry"
+	// AliasDependency installs another package of the registry under the dependency name ("npm:pkg@range").
+	AliasDependency DependencySpecType = "alias"
+	// TarballDependency is a tarball downloaded from an URL.
+	TarballDependency DependencySpecType = "tarball"
+	// GitDependency is a git repository, either as an URL or a hosted shortcut ("github:user/repo").
+	GitDependency DependencySpecType = "git"
+	// FileDependency is a local directory or tarball ("file:../lib", "link:../lib").
+	FileDependency DependencySpecType = "file"
+)
+
+var (
+	gitPrefixes = []string{"git+ssh://", "git+https://", "git+http://", "git+file://", "git://", "github:", "gitlab:", "bitbucket:", "gist:"}
+	// gitShortcut matches the "user/repo" GitHub shortcut and the scp-like "git@host:user/repo" syntax.
+	gitShortcut  = regexp.MustCompile(`^(?:[A-Za-z0-9][\w.-]*/[\w.-]+|[\w.-]+@[\w.-]+:[^/].*)(?:#.*)?$`)
+	filePrefixes = []string{"file:", "link:", "./", "../", "/", "~/"}
+	// windowsPath matches absolute Windows paths such as C:\lib.
+	windowsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+)
+
+// DependencySpec is a dependency as declared in the packument, classified by source.
+type DependencySpec struct {
+	// Name is the name under which the dependency is installed (the key in package.json).
+	Name string `json:"name"`
+	// Spec is the raw value declared in package.json.
+	Spec string `json:"spec"`
+	// Type is the source of the dependency.
+	Type DependencySpecType `json:"type"`
+	// Package is the registry package to retrieve, for registry and alias dependencies.
+	Package string `json:"package,omitempty"`
+	// Range is the version range or dist-tag, for registry and alias dependencies.
+	Range string `json:"range,omitempty"`
+	// Kind is the list of the package.json declaring the dependency.
+	Kind DependencyKind `json:"kind,omitempty"`
+}
+
+// ParseDependencySpec classifies a dependency following the rules of npm-package-arg.
+func ParseDependencySpec(name, spec string) DependencySpec {
+	dep := DependencySpec{Name: name, Spec: spec}
+	value := strings.TrimSpace(spec)
+
+	switch {
+	case strings.HasPrefix(value, "npm:"):
+		dep.Type = AliasDependency
+		dep.Package, dep.Range = splitPackageRange(strings.TrimPrefix(value, "npm:"))
+	case hasAnyPrefix(value, gitPrefixes):
+		dep.Type = GitDependency
+	case strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://"):
+		if u, _, _ := strings.Cut(value, "#"); strings.HasSuffix(u, ".git") {
+			dep.Type = GitDependency
+		} else {
+			dep.Type = TarballDependency
+		}
+	case value == "." || hasAnyPrefix(value, filePrefixes) || windowsPath.MatchString(value):
+		dep.Type = FileDependency
+	case gitShortcut.MatchString(value):
+		dep.Type = GitDependency
+	default:
+		dep.Type = RegistryDependency
+		dep.Package = name
+		dep.Range = value
+	}
+	return dep
+}
+
+// IsMirrorable returns true if the dependency can be downloaded to the local repository.
+func (d DependencySpec) IsMirrorable() bool {
+	switch d.Type {
+	case RegistryDependency, AliasDependency, TarballDependency:
+		return true
+	}
+	return false
+}
+
+// IsRegistry returns true if the dependency is resolved from the registry, directly or through an alias.
+func (d DependencySpec) IsRegistry() bool {
+	return d.Type == RegistryDependency || d.Type == AliasDependency
+}
+
+// splitPackageRange splits "pkg@range" or "@scope/pkg@range". The range defaults to "*".
+func splitPackageRange(value string) (string, string) {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return value, "*"
+	}
+	return value[:at], value[at+1:]
+}
+
+func hasAnyPrefix(value string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDependencySpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		depName  string
+		spec     string
+		expected DependencySpec
+	}{
+		{name: "Version range", depName: "express", spec: "^4.18.0", expected: DependencySpec{Type: RegistryDependency, Package: "express", Range: "^4.18.0"}},
+		{name: "Dist-tag", depName: "express", spec: "latest", expected: DependencySpec{Type: RegistryDependency, Package: "express", Range: "latest"}},
+		{name: "Empty range", depName: "express", spec: "", expected: DependencySpec{Type: RegistryDependency, Package: "express", Range: ""}},
+		{name: "Alias", depName: "string-width-cjs", spec: "npm:string-width@^4.2.0", expected: DependencySpec{Type: AliasDependency, Package: "string-width", Range: "^4.2.0"}},
+		{name: "Scoped alias", depName: "core", spec: "npm:@babel/core@7", expected: DependencySpec{Type: AliasDependency, Package: "@babel/core", Range: "7"}},
+		{name: "Alias without range", depName: "core", spec: "npm:@babel/core", expected: DependencySpec{Type: AliasDependency, Package: "@babel/core", Range: "*"}},
+		{name: "GitHub shortcut", depName: "lib", spec: "github:user/repo#abc123", expected: DependencySpec{Type: GitDependency}},
+		{name: "Implicit GitHub shortcut", depName: "lib", spec: "user/repo#semver:^1.0.0", expected: DependencySpec{Type: GitDependency}},
+		{name: "Git URL", depName: "lib", spec: "git+https://github.com/user/repo.git", expected: DependencySpec{Type: GitDependency}},
+		{name: "Scp-like git URL", depName: "lib", spec: "git@github.com:user/repo.git", expected: DependencySpec{Type: GitDependency}},
+		{name: "HTTPS git repository", depName: "lib", spec: "https://github.com/user/repo.git#v1", expected: DependencySpec{Type: GitDependency}},
+		{name: "Tarball URL", depName: "xlsx", spec: "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz", expected: DependencySpec{Type: TarballDependency}},
+		{name: "File", depName: "lib", spec: "file:../lib", expected: DependencySpec{Type: FileDependency}},
+		{name: "Link", depName: "lib", spec: "link:../lib", expected: DependencySpec{Type: FileDependency}},
+		{name: "Relative path", depName: "lib", spec: "./lib", expected: DependencySpec{Type: FileDependency}},
+		{name: "Windows path", depName: "lib", spec: `C:\lib`, expected: DependencySpec{Type: FileDependency}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.expected.Name = tt.depName
+			tt.expected.Spec = tt.spec
+
+			result := ParseDependencySpec(tt.depName, tt.spec)
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expected.Type != GitDependency && tt.expected.Type != FileDependency, result.IsMirrorable())
+		})
+	}
+}
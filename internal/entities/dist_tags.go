@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"slices"
+	"strings"
+)
+
+// DistTags maps the dist-tags of a package, such as "latest" or "next", to their version.
+type DistTags map[string]string
+
+// NewDistTags gathers the dist-tags set on the versions of a package.
+func NewDistTags(versions []NpmPackage) DistTags {
+	tags := DistTags{}
+	for _, version := range versions {
+		for _, tag := range version.DistTags {
+			tags[tag] = version.Version.String()
+		}
+	}
+	return tags
+}
+
+// ForLocalVersions points the dist-tags to the versions available locally. A tag keeps its
+// version when it is available; otherwise it points to the newest local version below it on the
+// same release channel: a stable version for "latest", a pre-release with the same identifier for
+// tags such as "next" or "beta". Tags without such a version are dropped, except "latest", which
+// falls back to the newest local stable version, else the newest local version.
+// Tags pointing to an invalid version are dropped.
+func (t DistTags) ForLocalVersions(local []SemVer) DistTags {
+	result := DistTags{}
+	if len(local) == 0 {
+		return result
+	}
+	for tag, value := range t {
+		target, err := NewSemVer(value)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(local, target) {
+			result[tag] = value
+			continue
+		}
+		if best, ok := newestVersion(local, func(v SemVer) bool {
+			return v.Compare(target) <= 0 && releaseChannel(v) == releaseChannel(target)
+		}); ok {
+			result[tag] = best.String()
+		}
+	}
+
+	if _, ok := result["latest"]; !ok {
+		best, ok := newestVersion(local, func(v SemVer) bool { return !v.IsPreRelease() })
+		if !ok {
+			best, _ = newestVersion(local, func(SemVer) bool { return true })
+		}
+		result["latest"] = best.String()
+	}
+	return result
+}
+
+// releaseChannel returns the channel of a version: empty for the stable versions, else the first
+// pre-release identifier without its number, e.g. "rc" for "2.0.0-rc.1" or "beta" for "2.0.0-beta3".
+func releaseChannel(v SemVer) string {
+	if !v.IsPreRelease() {
+		return ""
+	}
+	first, _, _ := strings.Cut(v.PreRelease, ".")
+	return "-" + strings.TrimRight(first, "0123456789")
+}
+
+func newestVersion(versions []SemVer, accept func(SemVer) bool) (SemVer, bool) {
+	var best SemVer
+	found := false
+	for _, v := range versions {
+		if accept(v) && (!found || v.Compare(best) > 0) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDistTags(t *testing.T) {
+	tags := NewDistTags([]NpmPackage{
+		{Version: mustSemVer(t, "18.3.1"), DistTags: []string{"latest", "stable"}},
+		{Version: mustSemVer(t, "18.2.0")},
+		{Version: mustSemVer(t, "19.0.0-rc.1"), DistTags: []string{"next"}},
+	})
+
+	assert.Equal(t, DistTags{"latest": "18.3.1", "stable": "18.3.1", "next": "19.0.0-rc.1"}, tags)
+}
+
+func TestDistTags_ForLocalVersions(t *testing.T) {
+	versions := func(values ...string) []SemVer {
+		var result []SemVer
+		for _, v := range values {
+			result = append(result, mustSemVer(t, v))
+		}
+		return result
+	}
+	upstream := DistTags{"latest": "2.1.0", "next": "3.0.0-rc.2", "beta": "3.0.0-beta.4", "legacy": "1.9.0"}
+
+	t.Run("Tags pointing to local versions are kept", func(t *testing.T) {
+		local := versions("1.9.0", "2.1.0", "3.0.0-rc.2", "3.0.0-beta.4")
+
+		assert.Equal(t, upstream, upstream.ForLocalVersions(local))
+	})
+
+	t.Run("Tags fall back to the newest older version of the same channel", func(t *testing.T) {
+		local := versions("1.5.0", "2.0.0", "3.0.0-rc.1", "3.0.0-beta.2", "3.0.0-alpha.9")
+
+		assert.Equal(t, DistTags{"latest": "2.0.0", "next": "3.0.0-rc.1", "beta": "3.0.0-beta.2", "legacy": "1.5.0"}, upstream.ForLocalVersions(local))
+	})
+
+	t.Run("Tags without a local version are dropped, but latest is always set", func(t *testing.T) {
+		assert.Equal(t, DistTags{"latest": "3.0.0-rc.2", "next": "3.0.0-rc.2"}, upstream.ForLocalVersions(versions("3.0.0-rc.2")))
+		assert.Equal(t, DistTags{"latest": "2.5.0"}, DistTags{"latest": "2.1.0"}.ForLocalVersions(versions("2.5.0")))
+	})
+
+	t.Run("No local version", func(t *testing.T) {
+		assert.Empty(t, upstream.ForLocalVersions(nil))
+	})
+}
@@ -0,0 +1,355 @@
+package entities
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// NoAssertion is the SPDX license of the versions which do not declare any license.
+const NoAssertion = "NOASSERTION"
+
+// LicenseExpression is a parsed SPDX license expression, such as "(MIT OR Apache-2.0) AND BSD-3-Clause".
+// A leaf holds a license and its optional exception, a node combines its operands with AND or OR.
+type LicenseExpression struct {
+	License   string
+	Exception string
+	Operator  string
+	Operands  []LicenseExpression
+}
+
+// ParseLicenseExpression parses an SPDX license expression. The operators are case-insensitive,
+// AND binding tighter than OR.
+func ParseLicenseExpression(value string) (LicenseExpression, error) {
+	parser := licenseParser{tokens: tokenizeLicense(value)}
+	if len(parser.tokens) == 0 {
+		return LicenseExpression{}, fmt.Errorf("empty license expression")
+	}
+	expression, err := parser.parseOr()
+	if err != nil {
+		return LicenseExpression{}, fmt.Errorf("invalid license expression %q: %v", value, err)
+	}
+	if token, ok := parser.peek(); ok {
+		return LicenseExpression{}, fmt.Errorf("invalid license expression %q: unexpected %q", value, token)
+	}
+	return expression, nil
+}
+
+// NewLicenseExpression returns the normalised expression of a declared license. A missing license
+// gives NOASSERTION, and a license which is not a valid expression, such as
+// "SEE LICENSE IN LICENSE.md", is kept as a single license.
+func NewLicenseExpression(value string) LicenseExpression {
+	normalized, _ := NormalizeLicense(value)
+	expression, err := ParseLicenseExpression(normalized)
+	if err != nil {
+		return LicenseExpression{License: normalized}
+	}
+	return expression
+}
+
+// NormalizeLicense returns the SPDX expression of a declared license: the identifiers take their
+// canonical case, the deprecated ones such as "GPL-2.0+" are replaced, and common free-form
+// licenses such as "Apache 2.0" or "The MIT License" are recognised. It returns false when the
+// license is missing, which gives NOASSERTION, or is not made of known SPDX identifiers, which
+// keeps the declared license.
+func NormalizeLicense(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return NoAssertion, false
+	}
+	key := aliasKey(value)
+	if license, ok := licenseAliases[key]; ok {
+		return license, true
+	}
+	if license, ok := canonicalLicenses[strings.ToUpper(key)]; ok {
+		return license, true
+	}
+	expression, err := ParseLicenseExpression(value)
+	if err != nil {
+		return value, false
+	}
+	known := true
+	expression = expression.mapLicenses(func(license string) string {
+		canonical, ok := canonicalLicense(license)
+		known = known && ok
+		return canonical
+	})
+	expression = expression.mapExceptions(func(exception string) string {
+		canonical, ok := canonicalExceptions[strings.ToUpper(exception)]
+		if !ok {
+			known = false
+			return exception
+		}
+		return canonical
+	})
+	return expression.String(), known
+}
+
+// canonicalLicense returns the SPDX identifier of a license, alias or deprecated identifier.
+func canonicalLicense(license string) (string, bool) {
+	upper := strings.ToUpper(license)
+	if strings.HasPrefix(upper, "LICENSEREF-") || strings.HasPrefix(upper, "DOCUMENTREF-") {
+		return license, true
+	}
+	if canonical, ok := canonicalLicenses[upper]; ok {
+		return canonical, true
+	}
+	for deprecated, replacement := range deprecatedLicenses {
+		if strings.EqualFold(deprecated, license) {
+			return replacement, true
+		}
+	}
+	if alias, ok := licenseAliases[aliasKey(license)]; ok {
+		return alias, true
+	}
+	// "Apache-2.0+" is the Apache-2.0 license or any later version.
+	if base, ok := strings.CutSuffix(license, "+"); ok {
+		if canonical, ok := canonicalLicenses[strings.ToUpper(base)]; ok {
+			return canonical + "+", true
+		}
+	}
+	return license, false
+}
+
+// aliasKey returns the key of a free-form license in licenseAliases.
+func aliasKey(license string) string {
+	key := strings.ToLower(strings.TrimSpace(license))
+	key = strings.TrimPrefix(key, "the ")
+	for _, suffix := range []string{" license", " licence"} {
+		key = strings.TrimSuffix(key, suffix)
+	}
+	return key
+}
+
+// mapLicenses returns a copy of the expression whose licenses are replaced.
+func (e LicenseExpression) mapLicenses(replace func(license string) string) LicenseExpression {
+	if e.Operator == "" {
+		e.License = replace(e.License)
+		return e
+	}
+	operands := make([]LicenseExpression, len(e.Operands))
+	for i, operand := range e.Operands {
+		operands[i] = operand.mapLicenses(replace)
+	}
+	e.Operands = operands
+	return e
+}
+
+// mapExceptions returns a copy of the expression whose license exceptions are replaced.
+func (e LicenseExpression) mapExceptions(replace func(exception string) string) LicenseExpression {
+	if e.Operator == "" {
+		if e.Exception != "" {
+			e.Exception = replace(e.Exception)
+		}
+		return e
+	}
+	operands := make([]LicenseExpression, len(e.Operands))
+	for i, operand := range e.Operands {
+		operands[i] = operand.mapExceptions(replace)
+	}
+	e.Operands = operands
+	return e
+}
+
+// String writes the expression back, with the parentheses its operators need.
+func (e LicenseExpression) String() string {
+	if e.Operator == "" {
+		if e.Exception != "" {
+			return e.License + " WITH " + e.Exception
+		}
+		return e.License
+	}
+	operands := make([]string, len(e.Operands))
+	for i, operand := range e.Operands {
+		operands[i] = operand.String()
+		if operand.Operator != "" && operand.Operator != e.Operator {
+			operands[i] = "(" + operands[i] + ")"
+		}
+	}
+	return strings.Join(operands, " "+e.Operator+" ")
+}
+
+// Licenses returns the licenses of the expression, without duplicates.
+func (e LicenseExpression) Licenses() []string {
+	var licenses []string
+	seen := map[string]bool{}
+	var walk func(LicenseExpression)
+	walk = func(expression LicenseExpression) {
+		if expression.Operator == "" {
+			if !seen[expression.License] {
+				seen[expression.License] = true
+				licenses = append(licenses, expression.License)
+			}
+			return
+		}
+		for _, operand := range expression.Operands {
+			walk(operand)
+		}
+	}
+	walk(e)
+	return licenses
+}
+
+// IsSatisfiedBy returns true if the licenses can be chosen among the accepted ones: one operand
+// of each OR and all the operands of each AND must be accepted.
+func (e LicenseExpression) IsSatisfiedBy(accepted func(license LicenseExpression) bool) bool {
+	switch e.Operator {
+	case "":
+		return accepted(e)
+	case "AND":
+		for _, operand := range e.Operands {
+			if !operand.IsSatisfiedBy(accepted) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, operand := range e.Operands {
+			if operand.IsSatisfiedBy(accepted) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchesLicense returns true if the license of a leaf matches one of the patterns. A pattern
+// is a license, such as "MIT", or a glob, such as "GPL-*", and is compared case-insensitively.
+// A pattern with an exception only matches the leaves with this exception.
+func (e LicenseExpression) MatchesLicense(patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToUpper(pattern)
+		license, exception, hasException := strings.Cut(pattern, " WITH ")
+		if hasException && !strings.EqualFold(strings.TrimSpace(exception), e.Exception) {
+			continue
+		}
+		if matched, _ := path.Match(strings.TrimSpace(license), strings.ToUpper(e.License)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeLicense splits an expression into parentheses and words.
+func tokenizeLicense(value string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(value) {
+		for field != "" {
+			i := strings.IndexAny(field, "()")
+			switch {
+			case i < 0:
+				tokens = append(tokens, field)
+				field = ""
+			case i == 0:
+				tokens = append(tokens, field[:1])
+				field = field[1:]
+			default:
+				tokens = append(tokens, field[:i])
+				field = field[i:]
+			}
+		}
+	}
+	return tokens
+}
+
+type licenseParser struct {
+	tokens []string
+	next   int
+}
+
+func (p *licenseParser) peek() (string, bool) {
+	if p.next >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.next], true
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *licenseParser) accept(operator string) bool {
+	if token, ok := p.peek(); ok && strings.EqualFold(token, operator) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *licenseParser) parseOr() (LicenseExpression, error) {
+	return p.parseOperator("OR", p.parseAnd)
+}
+
+func (p *licenseParser) parseAnd() (LicenseExpression, error) {
+	return p.parseOperator("AND", p.parseWith)
+}
+
+// parseOperator parses operands separated by the operator, flattening the chained ones.
+func (p *licenseParser) parseOperator(operator string, parseOperand func() (LicenseExpression, error)) (LicenseExpression, error) {
+	var operands []LicenseExpression
+	for {
+		operand, err := parseOperand()
+		if err != nil {
+			return operand, err
+		}
+		if operand.Operator == operator {
+			operands = append(operands, operand.Operands...)
+		} else {
+			operands = append(operands, operand)
+		}
+		if !p.accept(operator) {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return LicenseExpression{Operator: operator, Operands: operands}, nil
+}
+
+func (p *licenseParser) parseWith() (LicenseExpression, error) {
+	token, ok := p.peek()
+	if !ok {
+		return LicenseExpression{}, fmt.Errorf("missing license")
+	}
+	if token == "(" {
+		p.next++
+		expression, err := p.parseOr()
+		if err != nil {
+			return expression, err
+		}
+		if !p.accept(")") {
+			return expression, fmt.Errorf("missing closing parenthesis")
+		}
+		return expression, nil
+	}
+	if !isLicenseID(token) {
+		return LicenseExpression{}, fmt.Errorf("unexpected %q", token)
+	}
+	p.next++
+	leaf := LicenseExpression{License: token}
+	if p.accept("WITH") {
+		exception, ok := p.peek()
+		if !ok || !isLicenseID(exception) {
+			return leaf, fmt.Errorf("missing exception after WITH")
+		}
+		p.next++
+		leaf.Exception = exception
+	}
+	return leaf, nil
+}
+
+// isLicenseID checks the characters of a license or exception identifier. The operators are
+// not identifiers, and "+" is only allowed at the end, as in "GPL-2.0+".
+func isLicenseID(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", "WITH", "(", ")":
+		return false
+	}
+	for i, r := range token {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == ':':
+		case r == '+' && i == len(token)-1 && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLicenseExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		licenses []string
+	}{
+		{name: "Single license", value: "MIT", expected: "MIT", licenses: []string{"MIT"}},
+		{name: "AND binds tighter than OR", value: "MIT OR Apache-2.0 AND BSD-3-Clause", expected: "MIT OR (Apache-2.0 AND BSD-3-Clause)", licenses: []string{"MIT", "Apache-2.0", "BSD-3-Clause"}},
+		{name: "Parentheses", value: "(MIT OR Apache-2.0) AND BSD-3-Clause", expected: "(MIT OR Apache-2.0) AND BSD-3-Clause", licenses: []string{"MIT", "Apache-2.0", "BSD-3-Clause"}},
+		{name: "Chained operators are flattened", value: "(MIT OR ISC) or (0BSD OR MIT)", expected: "MIT OR ISC OR 0BSD OR MIT", licenses: []string{"MIT", "ISC", "0BSD"}},
+		{name: "Exception and or-later suffix", value: "GPL-2.0+ WITH Classpath-exception-2.0", expected: "GPL-2.0+ WITH Classpath-exception-2.0", licenses: []string{"GPL-2.0+"}},
+		{name: "License reference", value: "LicenseRef-Company", expected: "LicenseRef-Company", licenses: []string{"LicenseRef-Company"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := ParseLicenseExpression(tt.value)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expression.String())
+			assert.Equal(t, tt.licenses, expression.Licenses())
+		})
+	}
+
+	t.Run("Invalid expressions", func(t *testing.T) {
+		for _, value := range []string{"", "MIT OR", "(MIT", "MIT)", "MIT Apache-2.0", "GPL-2.0 WITH", "SEE LICENSE IN LICENSE.md"} {
+			_, err := ParseLicenseExpression(value)
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("Declared licenses which are not expressions are kept", func(t *testing.T) {
+		assert.Equal(t, LicenseExpression{License: NoAssertion}, NewLicenseExpression(" "))
+		assert.Equal(t, LicenseExpression{License: "SEE LICENSE IN LICENSE.md"}, NewLicenseExpression("SEE LICENSE IN LICENSE.md"))
+	})
+}
+
+func TestLicenseExpression_IsSatisfiedBy(t *testing.T) {
+	expression, err := ParseLicenseExpression("(MIT OR GPL-3.0-only) AND Apache-2.0")
+	require.NoError(t, err)
+	accept := func(patterns ...string) func(LicenseExpression) bool {
+		return func(leaf LicenseExpression) bool { return leaf.MatchesLicense(patterns) }
+	}
+
+	assert.True(t, expression.IsSatisfiedBy(accept("mit", "Apache-*")))
+	assert.True(t, expression.IsSatisfiedBy(accept("GPL-*", "Apache-2.0")))
+	assert.False(t, expression.IsSatisfiedBy(accept("MIT")))
+	assert.False(t, expression.IsSatisfiedBy(accept("GPL-3.0-only WITH Classpath-exception-2.0", "Apache-2.0")))
+}
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := []struct {
+		declared string
+		expected string
+		known    bool
+	}{
+		{declared: "mit", expected: "MIT", known: true},
+		{declared: "The MIT License", expected: "MIT", known: true},
+		{declared: "Apache License, Version 2.0", expected: "Apache-2.0", known: true},
+		{declared: "GPL-2.0+", expected: "GPL-2.0-or-later", known: true},
+		{declared: "(mit or apache-2.0)", expected: "MIT OR Apache-2.0", known: true},
+		{declared: "BSD-3-Clause AND Apache 2", expected: "BSD-3-Clause AND Apache 2", known: false},
+		{declared: "Apache-2.0 WITH LLVM-exception", expected: "Apache-2.0 WITH LLVM-exception", known: true},
+		{declared: "LicenseRef-Company", expected: "LicenseRef-Company", known: true},
+		{declared: "DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", expected: "DocumentRef-spdx-tool-1.2:LicenseRef-MIT-Style-2", known: true},
+		{declared: "gpl-2.0-only with classpath-exception-2.0", expected: "GPL-2.0-only WITH Classpath-exception-2.0", known: true},
+		{declared: "GPL-3.0-or-later WITH Custom-exception", expected: "GPL-3.0-or-later WITH Custom-exception", known: false},
+		{declared: "BlueOak-1.0.0 OR 3D-Slicer-1.0", expected: "BlueOak-1.0.0 OR 3D-Slicer-1.0", known: true},
+		{declared: "MIT OR Custom", expected: "MIT OR Custom", known: false},
+		{declared: "BSD", expected: "BSD", known: false},
+		{declared: "SEE LICENSE IN LICENSE.md", expected: "SEE LICENSE IN LICENSE.md", known: false},
+		{declared: "UNLICENSED", expected: "UNLICENSED", known: false},
+		{declared: "", expected: NoAssertion, known: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.declared, func(t *testing.T) {
+			license, known := NormalizeLicense(tt.declared)
+
+			assert.Equal(t, tt.expected, license)
+			assert.Equal(t, tt.known, known)
+		})
+	}
+}
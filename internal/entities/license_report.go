@@ -0,0 +1,102 @@
+package entities
+
+import "sort"
+
+// LicenseStatus is the compliance status of the license of a version.
+type LicenseStatus string
+
+const (
+	// LicenseAllowed is a license made of known SPDX identifiers, allowed by the policy.
+	LicenseAllowed LicenseStatus = "allowed"
+	// LicenseUnknown is a missing license, or a license which is not made of known SPDX identifiers.
+	LicenseUnknown LicenseStatus = "unknown"
+	// LicenseDenied is a license violating the policy.
+	LicenseDenied LicenseStatus = "denied"
+)
+
+// LicenseEntry is the license of a stored version.
+type LicenseEntry struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// License is the SPDX expression of the license, or the declared license when it is unknown.
+	License string        `json:"license"`
+	Status  LicenseStatus `json:"status"`
+	// Reason is the reason of the policy rule, for the denied licenses.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewLicenseEntry classifies the license of a version. A denied license is reported as denied
+// even if it is also unknown.
+func NewLicenseEntry(version NpmPackage, policy PackagePolicy) LicenseEntry {
+	license, known := NormalizeLicense(version.License)
+	entry := LicenseEntry{
+		Package: version.Name,
+		Version: version.Version.String(),
+		License: license,
+		Status:  LicenseAllowed,
+	}
+	if !known {
+		entry.Status = LicenseUnknown
+	}
+	if decision, violated := policy.LicenseViolation(version); violated {
+		entry.Status = LicenseDenied
+		entry.Reason = decision.Reason
+	}
+	return entry
+}
+
+// LicenseCount is the number of versions under a license.
+type LicenseCount struct {
+	License string        `json:"license"`
+	Status  LicenseStatus `json:"status"`
+	Count   int           `json:"count"`
+}
+
+// LicenseReport lists the licenses of the versions of a repository.
+type LicenseReport struct {
+	Entries []LicenseEntry `json:"entries"`
+}
+
+// Sort sorts the entries by package and version.
+func (r *LicenseReport) Sort() {
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		if r.Entries[i].Package != r.Entries[j].Package {
+			return r.Entries[i].Package < r.Entries[j].Package
+		}
+		return r.Entries[i].Version < r.Entries[j].Version
+	})
+}
+
+// Summary counts the versions of each license and status, the most frequent first.
+func (r LicenseReport) Summary() []LicenseCount {
+	counts := map[LicenseCount]int{}
+	for _, entry := range r.Entries {
+		counts[LicenseCount{License: entry.License, Status: entry.Status}]++
+	}
+	summary := make([]LicenseCount, 0, len(counts))
+	for key, count := range counts {
+		key.Count = count
+		summary = append(summary, key)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Count != summary[j].Count {
+			return summary[i].Count > summary[j].Count
+		}
+		if summary[i].License != summary[j].License {
+			return summary[i].License < summary[j].License
+		}
+		return summary[i].Status < summary[j].Status
+	})
+	return summary
+}
+
+// Flagged returns the entries whose license is unknown or denied.
+func (r LicenseReport) Flagged() []LicenseEntry {
+	var flagged []LicenseEntry
+	for _, entry := range r.Entries {
+		if entry.Status != LicenseAllowed {
+			flagged = append(flagged, entry)
+		}
+	}
+	return flagged
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLicenseEntry(t *testing.T) {
+	policy := PackagePolicy{Default: PolicyDeny, Rules: []PolicyRule{
+		{Action: PolicyAllow, Name: "@company/*"},
+		{Action: PolicyAllow, Licenses: []string{"MIT", "ISC"}},
+	}}
+	require.NoError(t, policy.Compile())
+	version := func(name string, license string) NpmPackage {
+		return NpmPackage{Name: name, Version: SemVer{Major: 1}, License: license}
+	}
+
+	assert.Equal(t, LicenseAllowed, NewLicenseEntry(version("a", "isc"), policy).Status)
+	assert.Equal(t, LicenseAllowed, NewLicenseEntry(version("@company/b", "LicenseRef-Company"), policy).Status)
+	assert.Equal(t, LicenseEntry{Package: "c", Version: "1.0.0", License: "Apache-2.0", Status: LicenseDenied, Reason: "not allowed by any rule"}, NewLicenseEntry(version("c", "Apache-2.0"), policy))
+	assert.Equal(t, LicenseUnknown, NewLicenseEntry(version("d", "Custom"), PackagePolicy{}).Status)
+}
+
+func TestLicenseReport_Summary(t *testing.T) {
+	report := LicenseReport{Entries: []LicenseEntry{
+		{Package: "a", License: "MIT", Status: LicenseAllowed},
+		{Package: "b", License: "ISC", Status: LicenseAllowed},
+		{Package: "c", License: "MIT", Status: LicenseAllowed},
+		{Package: "d", License: "Custom", Status: LicenseUnknown},
+	}}
+
+	assert.Equal(t, []LicenseCount{
+		{License: "MIT", Status: LicenseAllowed, Count: 2},
+		{License: "Custom", Status: LicenseUnknown, Count: 1},
+		{License: "ISC", Status: LicenseAllowed, Count: 1},
+	}, report.Summary())
+	assert.Equal(t, []LicenseEntry{{Package: "d", License: "Custom", Status: LicenseUnknown}}, report.Flagged())
+}
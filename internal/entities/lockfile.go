@@ -0,0 +1,86 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LockedVersions are the versions of each package installed by lockfiles.
+type LockedVersions map[string]map[string]bool
+
+// Add records an installed version.
+func (l LockedVersions) Add(name string, version string) {
+	if l[name] == nil {
+		l[name] = map[string]bool{}
+	}
+	l[name][version] = true
+}
+
+// Contains returns true if a lockfile installs the version.
+func (l LockedVersions) Contains(name string, version string) bool {
+	return l[name][version]
+}
+
+// packageLock holds the fields of package-lock.json and npm-shrinkwrap.json: lockfile version 1
+// nests the dependencies, versions 2 and 3 list the packages by node_modules path.
+type packageLock struct {
+	LockfileVersion int                    `json:"lockfileVersion"`
+	Packages        map[string]lockedEntry `json:"packages"`
+	Dependencies    map[string]lockedEntry `json:"dependencies"`
+}
+
+type lockedEntry struct {
+	// Name is set on the aliased packages of the packages section.
+	Name         string                 `json:"name"`
+	Version      string                 `json:"version"`
+	Link         bool                   `json:"link"`
+	Dependencies map[string]lockedEntry `json:"dependencies"`
+}
+
+// ParsePackageLock adds the registry versions installed by a package-lock.json to the locked
+// versions. Links, and the git and tarball dependencies whose version is not a semantic
+// version, are ignored.
+func (l LockedVersions) ParsePackageLock(data []byte) error {
+	var lock packageLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return fmt.Errorf("invalid package-lock.json: %v", err)
+	}
+	if lock.Packages == nil && lock.Dependencies == nil {
+		return fmt.Errorf("invalid package-lock.json: no packages nor dependencies")
+	}
+	add := func(name string, entry lockedEntry) {
+		if entry.Link {
+			return
+		}
+		version := entry.Version
+		// Aliases of lockfile version 1 are written "npm:name@version".
+		if alias, ok := strings.CutPrefix(version, "npm:"); ok {
+			name, version = splitNameVersion(alias)
+		}
+		if _, err := NewSemVer(version); err == nil {
+			l.Add(name, version)
+		}
+	}
+
+	for path, entry := range lock.Packages {
+		i := strings.LastIndex(path, "node_modules/")
+		if i < 0 {
+			continue // The root project or a workspace.
+		}
+		name := path[i+len("node_modules/"):]
+		if entry.Name != "" {
+			name = entry.Name
+		}
+		add(name, entry)
+	}
+	var walk func(deps map[string]lockedEntry)
+	walk = func(deps map[string]lockedEntry) {
+		for name, entry := range deps {
+			add(name, entry)
+			walk(entry.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+	return nil
+}
@@ -7,21 +7,51 @@ import (
 )
 
 type NpmPackage struct {
-	Name         string    `json:"name"`
-	Version      SemVer    `json:"version"`
-	Dependencies []string  `json:"dependencies"`
-	PeerDeps     []string  `json:"peerDeps"`
-	Integrity    string    `json:"integrity"`
-	Url          string    `json:"url"`
-	ReleaseDate  time.Time `json:"releaseDate"`
+	Name             string              `json:"name"`
+	Version          SemVer              `json:"version"`
+	Dependencies     []string            `json:"dependencies"`
+	OptionalDeps     []string            `json:"optionalDeps"`
+	PeerDeps         []string            `json:"peerDeps"`
+	OptionalPeerDeps []string            `json:"optionalPeerDeps"`
+	BundledDeps      []string            `json:"bundledDeps"`
+	ExternalDeps     []DependencySpec    `json:"externalDeps"`
+	DependencyRanges map[string]string   `json:"dependencyRanges,omitempty"` // Version ranges of the registry dependencies, by package.
+	Deprecated       string              `json:"deprecated,omitempty"`       // Deprecation message, empty if not deprecated.
+	DistTags         []string            `json:"distTags,omitempty"`         // Dist-tags pointing to the version, such as "latest".
+	License          string              `json:"license,omitempty"`          // License expression, such as "MIT" or "(MIT OR Apache-2.0)".
+	Maintainers      []string            `json:"maintainers,omitempty"`      // Names of the maintainers of the package.
+	InstallScripts   []string            `json:"installScripts,omitempty"`   // Install scripts run by npm, such as "postinstall".
+	Platform         PlatformConstraints `json:"platform"`
+	Integrity        string              `json:"integrity"`
+	Shasum           string              `json:"shasum,omitempty"`       // SHA-1 of the tarball in hexadecimal, the only checksum of old packuments.
+	UnpackedSize     int64               `json:"unpackedSize,omitempty"` // Size of the files of the tarball, 0 when the registry does not set it.
+	Url              string              `json:"url"`
+	ReleaseDate      time.Time           `json:"releaseDate"`
+	IsRemoteTarball  bool                `json:"remoteTarball,omitempty"` // Tarball dependency downloaded from an URL instead of the registry.
+}
+
+// InstallScripts are the lifecycle scripts npm runs when a package is installed.
+var InstallScripts = []string{"preinstall", "install", "postinstall"}
+
+// NewRemoteTarballPackage creates the package to download for a tarball dependency.
+func NewRemoteTarballPackage(spec DependencySpec) NpmPackage {
+	return NpmPackage{
+		Name:            spec.Name,
+		Url:             spec.Spec,
+		IsRemoteTarball: true,
+	}
 }
 
 type RetrievePackage struct {
-	Name              string
+	Name string
+	// Spec is the dist-tag or the version range requested with the name, e.g. "next" for "react@next".
+	// It is empty when the package is requested without a spec.
+	Spec              string
 	allowedPreVersion *regexp.Regexp
 	fullName          string
 }
 
+// NewRetrievePackage parses a requested package, written name[@spec][|pre-release regex].
 func NewRetrievePackage(name string) RetrievePackage {
 	parts := strings.Split(name, "|")
 
@@ -30,16 +60,22 @@ func NewRetrievePackage(name string) RetrievePackage {
 		re, _ = regexp.Compile(parts[1])
 	}
 
-	nme := parts[0]
-	var fullName string
-	if re == nil {
-		fullName = nme
-	} else {
-		fullName = nme + "|" + re.String()
+	// The first "@" of a scoped name is not a spec separator.
+	nme, spec := parts[0], ""
+	if i := strings.LastIndex(parts[0], "@"); i > 0 {
+		nme, spec = parts[0][:i], parts[0][i+1:]
+	}
+	fullName := nme
+	if spec != "" {
+		fullName += "@" + spec
+	}
+	if re != nil {
+		fullName += "|" + re.String()
 	}
 
 	return RetrievePackage{
 		Name:              nme,
+		Spec:              spec,
 		allowedPreVersion: re,
 		fullName:          fullName,
 	}
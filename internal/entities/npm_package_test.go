@@ -80,3 +80,31 @@ func TestRetrievePackage_String(t *testing.T) {
 	})
 
 }
+
+func TestRetrievePackage_Spec(t *testing.T) {
+
+	t.Run("The package name contains a dist-tag", func(t *testing.T) {
+		rp := NewRetrievePackage("react@next")
+
+		assert.Equal(t, "react", rp.Name)
+		assert.Equal(t, "next", rp.Spec)
+		assert.Equal(t, "react@next", rp.String())
+	})
+
+	t.Run("The scoped package name contains a range and a regex", func(t *testing.T) {
+		rp := NewRetrievePackage("@types/node@^20|rc")
+
+		assert.Equal(t, "@types/node", rp.Name)
+		assert.Equal(t, "^20", rp.Spec)
+		assert.True(t, rp.IsMatchingPreRelease("rc"))
+		assert.Equal(t, "@types/node@^20|rc", rp.String())
+	})
+
+	t.Run("The scoped package name does not contain a spec", func(t *testing.T) {
+		rp := NewRetrievePackage("@types/node")
+
+		assert.Equal(t, "@types/node", rp.Name)
+		assert.Empty(t, rp.Spec)
+	})
+
+}
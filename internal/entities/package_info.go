@@ -0,0 +1,71 @@
+package entities
+
+import "time"
+
+// IntegrityStatus is the result of the verification of a stored tarball.
+type IntegrityStatus string
+
+const (
+	// IntegrityOK is a tarball matching all the hashes of its packument.
+	IntegrityOK IntegrityStatus = "ok"
+	// IntegrityMismatch is a tarball not matching a hash of its packument.
+	IntegrityMismatch IntegrityStatus = "mismatch"
+	// IntegrityUnknown is a tarball whose packument has no hash.
+	IntegrityUnknown IntegrityStatus = "unknown"
+)
+
+// PackageSummary is the content of a package directory of the local repository.
+type PackageSummary struct {
+	Name string `json:"name"`
+	// Versions is the number of stored tarballs.
+	Versions int `json:"versions"`
+	// Size is the disk usage of the package directory, in bytes.
+	Size int64 `json:"size"`
+}
+
+// LocalVersionInfo is a version whose tarball is stored.
+type LocalVersionInfo struct {
+	Version   string          `json:"version"`
+	Size      int64           `json:"size"`
+	Integrity string          `json:"integrity,omitempty"`
+	Status    IntegrityStatus `json:"integrityStatus"`
+	// DownloadedAt is the modification time of the tarball.
+	DownloadedAt time.Time `json:"downloadedAt"`
+	Deprecated   string    `json:"deprecated,omitempty"`
+}
+
+// PackageInfo describes a package of the local repository.
+type PackageInfo struct {
+	Name     string             `json:"name"`
+	Versions []LocalVersionInfo `json:"versions"`
+	// Missing are the versions of the stored package.json without a tarball.
+	Missing []string `json:"missing"`
+	// DistTags are the dist-tags of the stored package.json, pointing to local versions.
+	DistTags DistTags `json:"distTags"`
+	// RegistryDistTags are the dist-tags of the registry at the last sync.
+	RegistryDistTags DistTags `json:"registryDistTags,omitempty"`
+	// Tracked is true when the package is listed in the download state, and updated by the
+	// runs with --update-local-repository.
+	Tracked  bool      `json:"tracked"`
+	LastSync time.Time `json:"lastSync,omitempty"`
+}
+
+// Size returns the size of the stored tarballs.
+func (p PackageInfo) Size() int64 {
+	var size int64
+	for _, version := range p.Versions {
+		size += version.Size
+	}
+	return size
+}
+
+// Corrupted returns the versions whose tarball does not match its integrity.
+func (p PackageInfo) Corrupted() []string {
+	var corrupted []string
+	for _, version := range p.Versions {
+		if version.Status == IntegrityMismatch {
+			corrupted = append(corrupted, version.Version)
+		}
+	}
+	return corrupted
+}
@@ -0,0 +1,125 @@
+package entities
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const maxPackageNameLength = 214
+
+// reservedPackageNames cannot be used as package names.
+var reservedPackageNames = map[string]bool{
+	"node_modules": true,
+	"favicon.ico":  true,
+}
+
+// PackageName is a validated npm package name, optionally scoped (e.g. "@babel/core").
+type PackageName struct {
+	// Scope is the scope of the package, without the leading "@" (empty if not scoped).
+	Scope string
+	// Name is the name of the package inside its scope.
+	Name string
+}
+
+// NewPackageName parses and validates an npm package name.
+// The rules are the ones of the npm registry: at most 214 characters, no leading dot or
+// underscore, no space and only URL-safe characters. Uppercase letters and the special
+// characters ~'!()* are accepted since legacy packages still use them.
+func NewPackageName(name string) (PackageName, error) {
+	if name == "" {
+		return PackageName{}, fmt.Errorf("invalid package name: name is empty")
+	}
+	if len(name) > maxPackageNameLength {
+		return PackageName{}, fmt.Errorf("invalid package name %q: longer than %d characters", name, maxPackageNameLength)
+	}
+	if strings.TrimSpace(name) != name {
+		return PackageName{}, fmt.Errorf("invalid package name %q: leading or trailing spaces", name)
+	}
+
+	var pkg PackageName
+	if strings.HasPrefix(name, "@") {
+		scope, rest, found := strings.Cut(name[1:], "/")
+		if !found {
+			return PackageName{}, fmt.Errorf("invalid package name %q: scoped name must be @scope/name", name)
+		}
+		pkg = PackageName{Scope: scope, Name: rest}
+		if err := validateNamePart(scope); err != nil {
+			return PackageName{}, fmt.Errorf("invalid package name %q: scope %v", name, err)
+		}
+	} else {
+		pkg = PackageName{Name: name}
+		if strings.HasPrefix(name, "_") {
+			return PackageName{}, fmt.Errorf("invalid package name %q: cannot start with an underscore", name)
+		}
+		if reservedPackageNames[strings.ToLower(name)] {
+			return PackageName{}, fmt.Errorf("invalid package name %q: reserved name", name)
+		}
+	}
+
+	if err := validateNamePart(pkg.Name); err != nil {
+		return PackageName{}, fmt.Errorf("invalid package name %q: %v", name, err)
+	}
+	return pkg, nil
+}
+
+// validateNamePart checks a scope or a name is not empty, does not start with a dot
+// and only contains URL-safe characters.
+func validateNamePart(part string) error {
+	if part == "" {
+		return fmt.Errorf("is empty")
+	}
+	if strings.HasPrefix(part, ".") {
+		return fmt.Errorf("cannot start with a dot")
+	}
+	for _, c := range part {
+		if !isURLSafe(c) {
+			return fmt.Errorf("contains the invalid character %q", c)
+		}
+	}
+	return nil
+}
+
+// isURLSafe returns true for the characters left untouched by encodeURIComponent.
+func isURLSafe(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.ContainsRune("-_.~!*'()", c)
+}
+
+// IsScoped returns true if the package belongs to a scope.
+func (p PackageName) IsScoped() bool {
+	return p.Scope != ""
+}
+
+// String returns the package name as written in package.json.
+func (p PackageName) String() string {
+	if p.IsScoped() {
+		return "@" + p.Scope + "/" + p.Name
+	}
+	return p.Name
+}
+
+// RegistryPath returns the path of the packument on the registry.
+// The slash of scoped names is encoded as "%2f", which all registries accept.
+func (p PackageName) RegistryPath() string {
+	if p.IsScoped() {
+		return "@" + p.Scope + "%2f" + p.Name
+	}
+	return p.Name
+}
+
+// DirPath returns the relative directory of the package in the local repository.
+func (p PackageName) DirPath() string {
+	if p.IsScoped() {
+		return filepath.Join("@"+p.Scope, p.Name)
+	}
+	return p.Name
+}
+
+// TarballFileName returns the file name of the tarball of the given version.
+func (p PackageName) TarballFileName(version string) string {
+	return fmt.Sprintf("%s-%s.tgz", p.Name, version)
+}
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPackageName(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expected      PackageName
+		expectedError string
+	}{
+		{name: "Simple name", input: "express", expected: PackageName{Name: "express"}},
+		{name: "Scoped name", input: "@babel/core", expected: PackageName{Scope: "babel", Name: "core"}},
+		{name: "Name with dots and dashes", input: "lodash.merge-x", expected: PackageName{Name: "lodash.merge-x"}},
+		{name: "Legacy uppercase name", input: "JSONStream", expected: PackageName{Name: "JSONStream"}},
+		{name: "Legacy special characters", input: "@scope/it's(ok)", expected: PackageName{Scope: "scope", Name: "it's(ok)"}},
+		{name: "Empty name", input: "", expectedError: "name is empty"},
+		{name: "Too long", input: strings.Repeat("a", 215), expectedError: "longer than 214"},
+		{name: "Leading space", input: " express", expectedError: "leading or trailing spaces"},
+		{name: "Leading dot", input: ".bin", expectedError: "cannot start with a dot"},
+		{name: "Leading underscore", input: "_private", expectedError: "cannot start with an underscore"},
+		{name: "Reserved name", input: "node_modules", expectedError: "reserved name"},
+		{name: "Missing scoped name", input: "@babel", expectedError: "scoped name must be @scope/name"},
+		{name: "Empty scope", input: "@/core", expectedError: "scope is empty"},
+		{name: "Empty scoped name", input: "@babel/", expectedError: "is empty"},
+		{name: "Nested path", input: "@babel/core/extra", expectedError: "invalid character '/'"},
+		{name: "Path traversal", input: "@babel/..", expectedError: "cannot start with a dot"},
+		{name: "Unscoped slash", input: "babel/core", expectedError: "invalid character '/'"},
+		{name: "Not URL-safe", input: "caf%C3%A9", expectedError: "invalid character '%'"},
+		{name: "Non ASCII", input: "café", expectedError: "invalid character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewPackageName(tt.input)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+				assert.Equal(t, tt.input, result.String())
+			}
+		})
+	}
+}
+
+func TestPackageName_Paths(t *testing.T) {
+	t.Run("Unscoped package", func(t *testing.T) {
+		pkg := PackageName{Name: "express"}
+
+		assert.False(t, pkg.IsScoped())
+		assert.Equal(t, "express", pkg.RegistryPath())
+		assert.Equal(t, "express", pkg.DirPath())
+		assert.Equal(t, "express-4.18.2.tgz", pkg.TarballFileName("4.18.2"))
+	})
+
+	t.Run("Scoped package", func(t *testing.T) {
+		pkg := PackageName{Scope: "babel", Name: "core"}
+
+		assert.True(t, pkg.IsScoped())
+		assert.Equal(t, "@babel%2fcore", pkg.RegistryPath())
+		assert.Equal(t, filepath.Join("@babel", "core"), pkg.DirPath())
+		assert.Equal(t, "core-7.24.0.tgz", pkg.TarballFileName("7.24.0"))
+	})
+}
@@ -0,0 +1,310 @@
+package entities
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PolicyAction is the action of a package policy rule.
+type PolicyAction string
+
+const (
+	PolicyAllow PolicyAction = "allow"
+	PolicyDeny  PolicyAction = "deny"
+)
+
+// PolicyRule matches package versions by name, version range, license, maintainers and
+// install scripts. All the matchers given in a rule must match.
+type PolicyRule struct {
+	Action PolicyAction `yaml:"action"`
+	// Reason explains the rule in the run report.
+	Reason string `yaml:"reason,omitempty"`
+	// Name is a package name or a glob, such as "@evil/*". The scope and the name of a scoped
+	// package are matched separately, as "*" never matches the "/" between them: "@*/core"
+	// matches the core package of any scope, "@*/*" any scoped package, while a pattern
+	// without scope, such as "*" or "lodash", only matches the packages without scope.
+	Name string `yaml:"name,omitempty"`
+	// Regex is a regular expression matching the package name.
+	Regex string `yaml:"regex,omitempty"`
+	// Versions is a version range, such as "<4.17.21".
+	Versions string `yaml:"versions,omitempty"`
+	// Licenses are licenses or globs, such as "GPL-*". A deny rule matches the versions whose
+	// license expression cannot be satisfied without one of them, an allow rule the versions
+	// whose expression can be satisfied with them only. NOASSERTION matches the versions
+	// without a license.
+	Licenses []string `yaml:"licenses,omitempty"`
+	// Maintainers are maintainer names or globs: a version matches if one of its maintainers does.
+	Maintainers []string `yaml:"maintainers,omitempty"`
+	// InstallScripts matches the versions with (true) or without (false) a preinstall, install
+	// or postinstall script.
+	InstallScripts *bool `yaml:"installScripts,omitempty"`
+
+	regex    *regexp.Regexp
+	versions SemVerRange
+}
+
+// PackagePolicy decides which package versions may enter the local repository. The rules are
+// evaluated in order and the first matching rule decides.
+type PackagePolicy struct {
+	// Default is the action for the versions no rule matches: allow unless set to deny, which
+	// turns the allow rules into an allow-list.
+	Default PolicyAction `yaml:"default,omitempty"`
+	Rules   []PolicyRule `yaml:"rules,omitempty"`
+	// Tombstones are the packages and versions removed from the local repository. They are not
+	// part of the policy file and are evaluated before the rules.
+	Tombstones []Tombstone `yaml:"-"`
+}
+
+// PolicyDecision is the decision of the policy on a package version.
+type PolicyDecision struct {
+	Package string `json:"package"`
+	// Version is empty when the package was decided by its name, before its metadata was fetched.
+	Version string       `json:"version,omitempty"`
+	Action  PolicyAction `json:"action"`
+	// Rule is the number of the matching rule, starting at 1, or 0 for the default action.
+	Rule   int    `json:"rule"`
+	Reason string `json:"reason"`
+	// Tombstone is true when the version was blocked by a tombstone rather than a rule.
+	Tombstone bool `json:"tombstone,omitempty"`
+}
+
+// Allowed returns true if the version may be downloaded.
+func (d PolicyDecision) Allowed() bool {
+	return d.Action != PolicyDeny
+}
+
+// Compile validates the policy and compiles its regular expressions and version ranges.
+func (p *PackagePolicy) Compile() error {
+	if p.Default != "" && p.Default != PolicyAllow && p.Default != PolicyDeny {
+		return fmt.Errorf("invalid default action %q: expected allow or deny", p.Default)
+	}
+	for i := range p.Rules {
+		if err := p.Rules[i].compile(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if the policy allows every version.
+func (p PackagePolicy) IsEmpty() bool {
+	return len(p.Rules) == 0 && len(p.Tombstones) == 0 && p.Default != PolicyDeny
+}
+
+// NeedsFullMetadata returns true if a rule matches the licenses, the maintainers or the install
+// scripts of the versions, which the abbreviated documents of the registry do not carry.
+func (p PackagePolicy) NeedsFullMetadata() bool {
+	for _, rule := range p.Rules {
+		if len(rule.Licenses) > 0 || len(rule.Maintainers) > 0 || rule.InstallScripts != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateName decides on a package from its name, before its metadata is fetched. It returns
+// false when the decision needs the metadata of the versions: a rule matching the name also
+// has version matchers.
+func (p PackagePolicy) EvaluateName(name string) (PolicyDecision, bool) {
+	for _, tombstone := range p.Tombstones {
+		if tombstone.Name != name {
+			continue
+		}
+		if !tombstone.IsWholePackage() {
+			return PolicyDecision{}, false
+		}
+		return tombstone.decision(name, ""), true
+	}
+	for i, rule := range p.Rules {
+		if !rule.matchesName(name) {
+			continue
+		}
+		if rule.hasVersionMatchers() {
+			return PolicyDecision{}, false
+		}
+		return rule.decision(i, name, ""), true
+	}
+	return p.defaultDecision(name, ""), true
+}
+
+// Evaluate decides on a version.
+func (p PackagePolicy) Evaluate(version NpmPackage) PolicyDecision {
+	for _, tombstone := range p.Tombstones {
+		if tombstone.Matches(version) {
+			return tombstone.decision(version.Name, version.Version.String())
+		}
+	}
+	for i, rule := range p.Rules {
+		if rule.matches(version) {
+			return rule.decision(i, version.Name, version.Version.String())
+		}
+	}
+	return p.defaultDecision(version.Name, version.Version.String())
+}
+
+// LicenseViolation returns the decision blocking a version because of its license: a deny rule
+// on licenses, or the default deny action of a policy allowing some licenses.
+func (p PackagePolicy) LicenseViolation(version NpmPackage) (PolicyDecision, bool) {
+	decision := p.Evaluate(version)
+	if decision.Allowed() || decision.Tombstone {
+		return decision, false
+	}
+	if decision.Rule > 0 {
+		return decision, len(p.Rules[decision.Rule-1].Licenses) > 0
+	}
+	for _, rule := range p.Rules {
+		if rule.Action == PolicyAllow && len(rule.Licenses) > 0 {
+			return decision, true
+		}
+	}
+	return decision, false
+}
+
+func (p PackagePolicy) defaultDecision(name string, version string) PolicyDecision {
+	if p.Default == PolicyDeny {
+		return PolicyDecision{Package: name, Version: version, Action: PolicyDeny, Reason: "not allowed by any rule"}
+	}
+	return PolicyDecision{Package: name, Version: version, Action: PolicyAllow}
+}
+
+func (r *PolicyRule) compile() error {
+	if r.Action != PolicyAllow && r.Action != PolicyDeny {
+		return fmt.Errorf("invalid action %q: expected allow or deny", r.Action)
+	}
+	if r.Name == "" && r.Regex == "" && !r.hasVersionMatchers() {
+		return fmt.Errorf("no matcher: set name, regex, versions, licenses, maintainers or installScripts")
+	}
+	if _, err := path.Match(r.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %v", r.Name, err)
+	}
+	if scope, _, scoped := strings.Cut(r.Name, "/"); scoped && !strings.HasPrefix(scope, "@") || strings.Count(r.Name, "/") > 1 {
+		return fmt.Errorf("invalid name pattern %q: expected a name or @scope/name", r.Name)
+	}
+	if r.Regex != "" {
+		regex, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", r.Regex, err)
+		}
+		r.regex = regex
+	}
+	if r.Versions != "" {
+		versions, err := ParseSemVerRange(r.Versions)
+		if err != nil {
+			return fmt.Errorf("invalid versions %q: %v", r.Versions, err)
+		}
+		r.versions = versions
+	}
+	for _, pattern := range append(append([]string{}, r.Licenses...), r.Maintainers...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// hasVersionMatchers returns true if the rule needs the metadata of the versions.
+func (r PolicyRule) hasVersionMatchers() bool {
+	return r.Versions != "" || len(r.Licenses) > 0 || len(r.Maintainers) > 0 || r.InstallScripts != nil
+}
+
+func (r PolicyRule) matchesName(name string) bool {
+	if r.Name != "" && !matchesNamePattern(r.Name, name) {
+		return false
+	}
+	return r.regex == nil || r.regex.MatchString(name)
+}
+
+// matchesNamePattern matches the scope and the name of a package against those of the pattern.
+// A pattern without scope only matches the packages without scope.
+func matchesNamePattern(pattern string, name string) bool {
+	patternScope, patternName, patternScoped := strings.Cut(pattern, "/")
+	scope, bare, scoped := strings.Cut(name, "/")
+	if patternScoped != scoped {
+		return false
+	}
+	if !scoped {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+	scopeMatched, _ := path.Match(patternScope, scope)
+	nameMatched, _ := path.Match(patternName, bare)
+	return scopeMatched && nameMatched
+}
+
+func (r PolicyRule) matches(version NpmPackage) bool {
+	if !r.matchesName(version.Name) {
+		return false
+	}
+	if r.Versions != "" && !r.versions.Matches(version.Version) {
+		return false
+	}
+	if len(r.Licenses) > 0 && !r.matchesLicense(version.License) {
+		return false
+	}
+	if len(r.Maintainers) > 0 && !r.matchesMaintainers(version.Maintainers) {
+		return false
+	}
+	if r.InstallScripts != nil && *r.InstallScripts != (len(version.InstallScripts) > 0) {
+		return false
+	}
+	return true
+}
+
+func (r PolicyRule) matchesLicense(license string) bool {
+	expression := NewLicenseExpression(license)
+	listed := func(leaf LicenseExpression) bool { return leaf.MatchesLicense(r.Licenses) }
+	if r.Action == PolicyAllow {
+		return expression.IsSatisfiedBy(listed)
+	}
+	return !expression.IsSatisfiedBy(func(leaf LicenseExpression) bool { return !listed(leaf) })
+}
+
+func (r PolicyRule) matchesMaintainers(maintainers []string) bool {
+	for _, maintainer := range maintainers {
+		for _, pattern := range r.Maintainers {
+			if matched, _ := path.Match(pattern, maintainer); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decision returns the decision of the rule at the given index.
+func (r PolicyRule) decision(index int, name string, version string) PolicyDecision {
+	reason := r.Reason
+	if reason == "" {
+		reason = r.describe()
+	}
+	return PolicyDecision{Package: name, Version: version, Action: r.Action, Rule: index + 1, Reason: reason}
+}
+
+// describe lists the matchers of the rule, for the rules without a reason.
+func (r PolicyRule) describe() string {
+	var matchers []string
+	if r.Name != "" {
+		matchers = append(matchers, "name "+r.Name)
+	}
+	if r.Regex != "" {
+		matchers = append(matchers, "regex "+r.Regex)
+	}
+	if r.Versions != "" {
+		matchers = append(matchers, "versions "+r.Versions)
+	}
+	if len(r.Licenses) > 0 {
+		matchers = append(matchers, "licenses "+strings.Join(r.Licenses, ", "))
+	}
+	if len(r.Maintainers) > 0 {
+		matchers = append(matchers, "maintainers "+strings.Join(r.Maintainers, ", "))
+	}
+	if r.InstallScripts != nil {
+		if *r.InstallScripts {
+			matchers = append(matchers, "install scripts")
+		} else {
+			matchers = append(matchers, "no install scripts")
+		}
+	}
+	return strings.Join(matchers, "; ")
+}
@@ -0,0 +1,152 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackagePolicy_Evaluate(t *testing.T) {
+	yes := true
+	policy := PackagePolicy{Rules: []PolicyRule{
+		{Action: PolicyAllow, Name: "@company/*", Reason: "internal packages"},
+		{Action: PolicyDeny, Regex: "^(event-stream|flatmap-stream)$", Reason: "malicious"},
+		{Action: PolicyDeny, Name: "lodash", Versions: "<4.17.21", Reason: "prototype pollution"},
+		{Action: PolicyDeny, Licenses: []string{"GPL-*", "AGPL-*", NoAssertion}},
+		{Action: PolicyDeny, Maintainers: []string{"evil-*"}},
+		{Action: PolicyDeny, InstallScripts: &yes, Reason: "install scripts"},
+	}}
+	require.NoError(t, policy.Compile())
+
+	version := func(name string, v string, license string) NpmPackage {
+		return NpmPackage{Name: name, Version: mustSemVer(t, v), License: license}
+	}
+	withScripts := version("esbuild", "0.20.0", "MIT")
+	withScripts.InstallScripts = []string{"postinstall"}
+	maintained := version("colors", "1.4.1", "MIT")
+	maintained.Maintainers = []string{"someone", "evil-bob"}
+
+	tests := []struct {
+		name     string
+		version  NpmPackage
+		action   PolicyAction
+		rule     int
+		reason   string
+		expected bool
+	}{
+		{name: "Allowed by the first matching rule", version: version("@company/ui", "1.0.0", "GPL-3.0-only"), action: PolicyAllow, rule: 1, reason: "internal packages", expected: true},
+		{name: "Denied name", version: version("event-stream", "3.3.6", "MIT"), action: PolicyDeny, rule: 2, reason: "malicious"},
+		{name: "Denied version range", version: version("lodash", "4.17.20", "MIT"), action: PolicyDeny, rule: 3, reason: "prototype pollution"},
+		{name: "Version out of the denied range", version: version("lodash", "4.17.21", "MIT"), action: PolicyAllow, expected: true},
+		{name: "Denied license", version: version("readline-sync", "1.0.0", "GPL-2.0-or-later"), action: PolicyDeny, rule: 4, reason: "licenses GPL-*, AGPL-*, NOASSERTION"},
+		{name: "Denied license can be avoided", version: version("jszip", "3.10.1", "(MIT OR GPL-3.0-or-later)"), action: PolicyAllow, expected: true},
+		{name: "Missing license", version: version("left-pad", "1.0.0", ""), action: PolicyDeny, rule: 4, reason: "licenses GPL-*, AGPL-*, NOASSERTION"},
+		{name: "Denied maintainer", version: maintained, action: PolicyDeny, rule: 5, reason: "maintainers evil-*"},
+		{name: "Install scripts", version: withScripts, action: PolicyDeny, rule: 6, reason: "install scripts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.Evaluate(tt.version)
+
+			assert.Equal(t, tt.action, decision.Action)
+			assert.Equal(t, tt.rule, decision.Rule)
+			assert.Equal(t, tt.reason, decision.Reason)
+			assert.Equal(t, tt.expected, decision.Allowed())
+		})
+	}
+}
+
+func TestPackagePolicy_AllowList(t *testing.T) {
+	policy := PackagePolicy{Default: PolicyDeny, Rules: []PolicyRule{
+		{Action: PolicyAllow, Licenses: []string{"MIT", "ISC", "Apache-2.0"}},
+	}}
+	require.NoError(t, policy.Compile())
+
+	assert.True(t, policy.Evaluate(NpmPackage{Name: "a", License: "MIT OR GPL-3.0-only"}).Allowed())
+	assert.False(t, policy.Evaluate(NpmPackage{Name: "b", License: "MIT AND GPL-3.0-only"}).Allowed())
+	assert.Equal(t, PolicyDecision{Package: "c", Version: "0.0.0", Action: PolicyDeny, Reason: "not allowed by any rule"}, policy.Evaluate(NpmPackage{Name: "c"}))
+}
+
+func TestPackagePolicy_EvaluateName(t *testing.T) {
+	policy := PackagePolicy{Rules: []PolicyRule{
+		{Action: PolicyDeny, Name: "lodash", Versions: "<4.17.21"},
+		{Action: PolicyDeny, Name: "@evil/*"},
+	}}
+	require.NoError(t, policy.Compile())
+
+	decision, decided := policy.EvaluateName("@evil/pkg")
+	assert.True(t, decided)
+	assert.False(t, decision.Allowed())
+
+	_, decided = policy.EvaluateName("lodash")
+	assert.False(t, decided, "the versions of lodash must be evaluated")
+
+	decision, decided = policy.EvaluateName("react")
+	assert.True(t, decided)
+	assert.True(t, decision.Allowed())
+}
+
+func TestPackagePolicy_NeedsFullMetadata(t *testing.T) {
+	withScripts := true
+	assert.False(t, PackagePolicy{}.NeedsFullMetadata())
+	assert.False(t, PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Name: "lodash", Versions: "<4.17.21"}}}.NeedsFullMetadata())
+	assert.True(t, PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Licenses: []string{"GPL-*"}}}}.NeedsFullMetadata())
+	assert.True(t, PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Maintainers: []string{"evil"}}}}.NeedsFullMetadata())
+	assert.True(t, PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, InstallScripts: &withScripts}}}.NeedsFullMetadata())
+}
+
+func TestPolicyRule_matchesName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "lodash", name: "lodash", want: true},
+		{pattern: "lodash", name: "@types/lodash", want: false},
+		{pattern: "*", name: "react", want: true},
+		{pattern: "*", name: "@babel/core", want: false},
+		{pattern: "*-loader", name: "css-loader", want: true},
+		{pattern: "@babel/*", name: "@babel/core", want: true},
+		{pattern: "@babel/*", name: "@babel-x/core", want: false},
+		{pattern: "@babel/*", name: "babel", want: false},
+		{pattern: "@*/core", name: "@babel/core", want: true},
+		{pattern: "@*/core", name: "@angular/core", want: true},
+		{pattern: "@*/core", name: "@babel/core-js", want: false},
+		{pattern: "@*/core", name: "core", want: false},
+		{pattern: "@*/*", name: "@types/node", want: true},
+		{pattern: "@*/*", name: "node", want: false},
+		{pattern: "@types/?ode", name: "@types/node", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			rule := PolicyRule{Action: PolicyDeny, Name: tt.pattern}
+			require.NoError(t, rule.compile())
+
+			assert.Equal(t, tt.want, rule.matchesName(tt.name))
+		})
+	}
+}
+
+func TestPackagePolicy_Compile(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy PackagePolicy
+		err    string
+	}{
+		{name: "Invalid default", policy: PackagePolicy{Default: "block"}, err: `invalid default action "block": expected allow or deny`},
+		{name: "Invalid action", policy: PackagePolicy{Rules: []PolicyRule{{Action: "block", Name: "x"}}}, err: `rule 1: invalid action "block": expected allow or deny`},
+		{name: "Rule without matcher", policy: PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny}}}, err: "rule 1: no matcher: set name, regex, versions, licenses, maintainers or installScripts"},
+		{name: "Name pattern without scope", policy: PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Name: "babel/*"}}}, err: `rule 1: invalid name pattern "babel/*": expected a name or @scope/name`},
+		{name: "Name pattern with a path", policy: PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Name: "@babel/core/*"}}}, err: `rule 1: invalid name pattern "@babel/core/*"`},
+		{name: "Invalid regex", policy: PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Regex: "("}}}, err: "rule 1: invalid regex"},
+		{name: "Invalid range", policy: PackagePolicy{Rules: []PolicyRule{{Action: PolicyDeny, Versions: "^abc"}}}, err: `rule 1: invalid versions "^abc"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.policy.Compile(), tt.err)
+		})
+	}
+}
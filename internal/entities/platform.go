@@ -0,0 +1,139 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownOS and knownCPU are the values of process.platform and process.arch used in the
+// os and cpu fields of package.json.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "freebsd": true, "linux": true,
+		"openbsd": true, "netbsd": true, "sunos": true, "win32": true,
+	}
+	knownCPU = map[string]bool{
+		"arm": true, "arm64": true, "ia32": true, "loong64": true, "mips": true, "mipsel": true,
+		"ppc": true, "ppc64": true, "riscv64": true, "s390": true, "s390x": true, "x64": true,
+	}
+	knownLibc = map[string]bool{"glibc": true, "musl": true}
+)
+
+// Platform is a target platform on which the downloaded packages will be installed.
+type Platform struct {
+	OS   string
+	CPU  string
+	Libc string // Empty when any libc is accepted, or when the OS is not Linux.
+}
+
+// ParsePlatform parses a platform written as os-cpu or os-cpu-libc, e.g. "linux-x64-glibc".
+func ParsePlatform(value string) (Platform, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Platform{}, fmt.Errorf("invalid platform %q: expected os-cpu or os-cpu-libc", value)
+	}
+
+	platform := Platform{OS: parts[0], CPU: parts[1]}
+	if !knownOS[platform.OS] {
+		return Platform{}, fmt.Errorf("invalid platform %q: unknown os %q", value, platform.OS)
+	}
+	if !knownCPU[platform.CPU] {
+		return Platform{}, fmt.Errorf("invalid platform %q: unknown cpu %q", value, platform.CPU)
+	}
+	if len(parts) == 3 {
+		platform.Libc = parts[2]
+		if !knownLibc[platform.Libc] {
+			return Platform{}, fmt.Errorf("invalid platform %q: unknown libc %q", value, platform.Libc)
+		}
+		if platform.OS != "linux" {
+			return Platform{}, fmt.Errorf("invalid platform %q: libc is only meaningful on linux", value)
+		}
+	}
+	return platform, nil
+}
+
+// ParsePlatforms parses a list of platforms, each value may contain several comma-separated platforms.
+func ParsePlatforms(values []string) ([]Platform, error) {
+	var platforms []Platform
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			platform, err := ParsePlatform(item)
+			if err != nil {
+				return nil, err
+			}
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// String returns the platform as written on the command line.
+func (p Platform) String() string {
+	if p.Libc != "" {
+		return p.OS + "-" + p.CPU + "-" + p.Libc
+	}
+	return p.OS + "-" + p.CPU
+}
+
+// PlatformConstraints are the os, cpu and libc fields of a package version.
+// Each list follows the npm semantics: an empty list accepts everything, "!value" blocks
+// a value, and when at least one value is not negated only the listed values are accepted.
+type PlatformConstraints struct {
+	OS   []string `json:"os,omitempty"`
+	CPU  []string `json:"cpu,omitempty"`
+	Libc []string `json:"libc,omitempty"`
+}
+
+// IsEmpty returns true if the version can be installed on any platform.
+func (c PlatformConstraints) IsEmpty() bool {
+	return len(c.OS) == 0 && len(c.CPU) == 0 && len(c.Libc) == 0
+}
+
+// Matches returns true if the version can be installed on the given platform.
+// The libc constraint is ignored for platforms which do not specify a libc.
+func (c PlatformConstraints) Matches(platform Platform) bool {
+	if !matchesList(platform.OS, c.OS) || !matchesList(platform.CPU, c.CPU) {
+		return false
+	}
+	if platform.Libc == "" {
+		return true
+	}
+	return matchesList(platform.Libc, c.Libc)
+}
+
+// IsInstallableOn returns true if the version can be installed on at least one of the platforms.
+// Any version is installable when no platform is given.
+func (c PlatformConstraints) IsInstallableOn(platforms []Platform) bool {
+	if len(platforms) == 0 || c.IsEmpty() {
+		return true
+	}
+	for _, platform := range platforms {
+		if c.Matches(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesList implements the checkList function of npm-install-checks.
+func matchesList(value string, list []string) bool {
+	if len(list) == 0 || (len(list) == 1 && list[0] == "any") {
+		return true
+	}
+	negated := 0
+	match := false
+	for _, entry := range list {
+		if strings.HasPrefix(entry, "!") {
+			negated++
+			if entry[1:] == value {
+				return false
+			}
+		} else if entry == value {
+			match = true
+		}
+	}
+	return match || negated == len(list)
+}
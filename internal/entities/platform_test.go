@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	t.Run("Valid platforms", func(t *testing.T) {
+		platforms, err := ParsePlatforms([]string{"linux-x64-glibc,linux-arm64", "darwin-arm64"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Platform{
+			{OS: "linux", CPU: "x64", Libc: "glibc"},
+			{OS: "linux", CPU: "arm64"},
+			{OS: "darwin", CPU: "arm64"},
+		}, platforms)
+		assert.Equal(t, "linux-x64-glibc", platforms[0].String())
+		assert.Equal(t, "darwin-arm64", platforms[2].String())
+	})
+
+	t.Run("Invalid platforms", func(t *testing.T) {
+		for input, expectedError := range map[string]string{
+			"linux":             "expected os-cpu",
+			"linux-x64-musl-v2": "expected os-cpu",
+			"windows-x64":       "unknown os",
+			"linux-amd64":       "unknown cpu",
+			"linux-x64-uclibc":  "unknown libc",
+			"darwin-x64-glibc":  "only meaningful on linux",
+		} {
+			_, err := ParsePlatforms([]string{input})
+
+			assert.Error(t, err, input)
+			assert.Contains(t, err.Error(), expectedError, input)
+		}
+	})
+}
+
+func TestPlatformConstraints_IsInstallableOn(t *testing.T) {
+	linuxGlibc := Platform{OS: "linux", CPU: "x64", Libc: "glibc"}
+	linuxAnyLibc := Platform{OS: "linux", CPU: "x64"}
+	darwinArm := Platform{OS: "darwin", CPU: "arm64"}
+
+	tests := []struct {
+		name        string
+		constraints PlatformConstraints
+		platforms   []Platform
+		expected    bool
+	}{
+		{name: "No platform configured", constraints: PlatformConstraints{OS: []string{"win32"}}, expected: true},
+		{name: "No constraint", platforms: []Platform{darwinArm}, expected: true},
+		{name: "Allowed os and cpu", constraints: PlatformConstraints{OS: []string{"linux"}, CPU: []string{"x64"}}, platforms: []Platform{linuxGlibc}, expected: true},
+		{name: "Other os", constraints: PlatformConstraints{OS: []string{"win32"}}, platforms: []Platform{linuxGlibc, darwinArm}, expected: false},
+		{name: "Other cpu", constraints: PlatformConstraints{OS: []string{"linux"}, CPU: []string{"arm64"}}, platforms: []Platform{linuxGlibc}, expected: false},
+		{name: "One of the platforms matches", constraints: PlatformConstraints{OS: []string{"darwin"}}, platforms: []Platform{linuxGlibc, darwinArm}, expected: true},
+		{name: "Negated os", constraints: PlatformConstraints{OS: []string{"!win32"}}, platforms: []Platform{linuxGlibc}, expected: true},
+		{name: "Blocked os", constraints: PlatformConstraints{OS: []string{"!linux"}}, platforms: []Platform{linuxGlibc}, expected: false},
+		{name: "Blocked value wins over allowed one", constraints: PlatformConstraints{OS: []string{"linux", "!linux"}}, platforms: []Platform{linuxGlibc}, expected: false},
+		{name: "Other libc", constraints: PlatformConstraints{OS: []string{"linux"}, Libc: []string{"musl"}}, platforms: []Platform{linuxGlibc}, expected: false},
+		{name: "Libc ignored when the platform accepts any", constraints: PlatformConstraints{OS: []string{"linux"}, Libc: []string{"musl"}}, platforms: []Platform{linuxAnyLibc}, expected: true},
+		{name: "Any keyword", constraints: PlatformConstraints{CPU: []string{"any"}}, platforms: []Platform{darwinArm}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.constraints.IsInstallableOn(tt.platforms))
+		})
+	}
+}
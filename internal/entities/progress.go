@@ -0,0 +1,149 @@
+package entities
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a stage of a download run.
+type Stage int
+
+const (
+	// MetadataStage retrieves the metadata of the packages.
+	MetadataStage Stage = iota
+	// DownloadStage downloads the tarballs.
+	DownloadStage
+)
+
+// String returns the name of the stage.
+func (s Stage) String() string {
+	if s == DownloadStage {
+		return "download"
+	}
+	return "metadata"
+}
+
+// stageCounters holds the live counters of a stage.
+type stageCounters struct {
+	queued   atomic.Int64
+	done     atomic.Int64
+	active   atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+}
+
+// Progress tracks the progress of a download run. It is safe for concurrent use.
+type Progress struct {
+	startedAt time.Time
+	stages    [2]stageCounters
+	bytes     atomic.Int64
+}
+
+// NewProgress creates the progress of a run started at the given time.
+func NewProgress(startedAt time.Time) *Progress {
+	return &Progress{startedAt: startedAt}
+}
+
+// Enqueue records an item queued for a stage.
+func (p *Progress) Enqueue(stage Stage) {
+	p.stages[stage].queued.Add(1)
+}
+
+// Start records a worker starting an item of a stage.
+func (p *Progress) Start(stage Stage) {
+	p.stages[stage].active.Add(1)
+}
+
+// Finish records a worker done with an item of a stage, failed or not.
+func (p *Progress) Finish(stage Stage, failed bool) {
+	counters := &p.stages[stage]
+	counters.active.Add(-1)
+	counters.done.Add(1)
+	if failed {
+		counters.failures.Add(1)
+	}
+}
+
+// Retry records a new attempt on an item of a stage.
+func (p *Progress) Retry(stage Stage) {
+	p.stages[stage].retries.Add(1)
+}
+
+// AddBytes records bytes received from the registry.
+func (p *Progress) AddBytes(n int64) {
+	p.bytes.Add(n)
+}
+
+// StageProgress is a snapshot of the counters of a stage.
+type StageProgress struct {
+	Queued   int64
+	Done     int64
+	Active   int64
+	Retries  int64
+	Failures int64
+}
+
+// Remaining returns the number of items queued and not done yet.
+func (s StageProgress) Remaining() int64 {
+	return s.Queued - s.Done
+}
+
+// ETA estimates the time left to complete the queued items from the average rate over the
+// elapsed time. It returns -1 while nothing was done yet.
+func (s StageProgress) ETA(elapsed time.Duration) time.Duration {
+	if s.Done == 0 {
+		return -1
+	}
+	return time.Duration(float64(elapsed) * float64(s.Remaining()) / float64(s.Done))
+}
+
+// ProgressSnapshot is the state of the progress of a run at a given time. The counters are
+// read one by one while the workers run, so they may be off by the items in flight.
+type ProgressSnapshot struct {
+	Elapsed  time.Duration
+	Metadata StageProgress
+	Download StageProgress
+	Bytes    int64
+}
+
+// Snapshot returns the state of the progress at the given time.
+func (p *Progress) Snapshot(now time.Time) ProgressSnapshot {
+	return ProgressSnapshot{
+		Elapsed:  now.Sub(p.startedAt),
+		Metadata: p.stages[MetadataStage].snapshot(),
+		Download: p.stages[DownloadStage].snapshot(),
+		Bytes:    p.bytes.Load(),
+	}
+}
+
+// BytesPerSecond returns the average download rate of the run.
+func (s ProgressSnapshot) BytesPerSecond() int64 {
+	if s.Elapsed < time.Second {
+		return s.Bytes
+	}
+	return int64(float64(s.Bytes) / s.Elapsed.Seconds())
+}
+
+// ETA estimates the time left to complete the run: the tarballs are only known once the
+// metadata are retrieved, so the longest of the two stages is reported.
+func (s ProgressSnapshot) ETA() time.Duration {
+	metadata := s.Metadata.ETA(s.Elapsed)
+	download := s.Download.ETA(s.Elapsed)
+	if metadata < 0 && s.Metadata.Remaining() > 0 {
+		return -1
+	}
+	if download < 0 && s.Download.Remaining() > 0 {
+		return -1
+	}
+	return max(metadata, download, 0)
+}
+
+func (c *stageCounters) snapshot() StageProgress {
+	return StageProgress{
+		Queued:   c.queued.Load(),
+		Done:     c.done.Load(),
+		Active:   c.active.Load(),
+		Retries:  c.retries.Load(),
+		Failures: c.failures.Load(),
+	}
+}
@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Concurrent updates are counted per stage", func(t *testing.T) {
+		progress := NewProgress(start)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				progress.Enqueue(MetadataStage)
+				progress.Enqueue(DownloadStage)
+				progress.Start(DownloadStage)
+				progress.Retry(DownloadStage)
+				progress.AddBytes(100)
+				progress.Finish(DownloadStage, i%5 == 0)
+			}(i)
+		}
+		wg.Wait()
+		progress.Start(MetadataStage)
+
+		snapshot := progress.Snapshot(start.Add(2 * time.Second))
+
+		assert.Equal(t, 2*time.Second, snapshot.Elapsed)
+		assert.Equal(t, StageProgress{Queued: 10, Active: 1}, snapshot.Metadata)
+		assert.Equal(t, StageProgress{Queued: 10, Done: 10, Retries: 10, Failures: 2}, snapshot.Download)
+		assert.Equal(t, int64(1000), snapshot.Bytes)
+		assert.Equal(t, int64(500), snapshot.BytesPerSecond())
+	})
+
+	t.Run("ETA from the average rate", func(t *testing.T) {
+		stage := StageProgress{Queued: 40, Done: 10}
+
+		assert.Equal(t, 30*time.Second, stage.ETA(10*time.Second))
+		assert.Equal(t, time.Duration(-1), StageProgress{Queued: 5}.ETA(10*time.Second))
+	})
+
+	t.Run("ETA of the run is the longest of the stages", func(t *testing.T) {
+		snapshot := ProgressSnapshot{
+			Elapsed:  10 * time.Second,
+			Metadata: StageProgress{Queued: 20, Done: 10},
+			Download: StageProgress{Queued: 50, Done: 10},
+		}
+		assert.Equal(t, 40*time.Second, snapshot.ETA())
+
+		snapshot.Download = StageProgress{Queued: 5}
+		assert.Equal(t, time.Duration(-1), snapshot.ETA())
+
+		assert.Equal(t, time.Duration(0), ProgressSnapshot{Elapsed: time.Second}.ETA())
+	})
+}
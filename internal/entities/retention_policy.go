@@ -0,0 +1,81 @@
+package entities
+
+import (
+	"sort"
+	"time"
+)
+
+// RetentionPolicy tells which stored versions are pruned from the local repository. A version
+// is pruned when it is not among the KeepLast highest versions of its package, or when it is a
+// pre-release older than PrereleaseMaxAge, unless a lockfile installs it.
+type RetentionPolicy struct {
+	// KeepLast is the number of highest versions kept per package, 0 for all.
+	KeepLast int
+	// PrereleaseMaxAge is the age after which pre-releases are pruned, 0 to keep them.
+	PrereleaseMaxAge time.Duration
+	// Locked are the versions installed by the lockfiles, always kept.
+	Locked LockedVersions
+}
+
+// IsEmpty returns true if the policy keeps every version.
+func (p RetentionPolicy) IsEmpty() bool {
+	return p.KeepLast <= 0 && p.PrereleaseMaxAge <= 0
+}
+
+// Prune returns the versions of a package to remove, sorted.
+func (p RetentionPolicy) Prune(versions []NpmPackage, now time.Time) []NpmPackage {
+	sorted := append([]NpmPackage{}, versions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version.Compare(sorted[j].Version) > 0 })
+
+	var pruned []NpmPackage
+	for i, version := range sorted {
+		if p.Locked.Contains(version.Name, version.Version.String()) {
+			continue
+		}
+		tooMany := p.KeepLast > 0 && i >= p.KeepLast
+		tooOld := p.PrereleaseMaxAge > 0 && version.Version.IsPreRelease() &&
+			!version.ReleaseDate.IsZero() && now.Sub(version.ReleaseDate) > p.PrereleaseMaxAge
+		if tooMany || tooOld {
+			pruned = append(pruned, version)
+		}
+	}
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i].Version.Compare(pruned[j].Version) < 0 })
+	return pruned
+}
+
+// PrunedPackage is the result of the pruning of a package.
+type PrunedPackage struct {
+	Name    string   `json:"name"`
+	Removed []string `json:"removed"`
+	// Kept is the number of tarballs left.
+	Kept int `json:"kept"`
+	// Freed is the number of bytes freed on disk: the removed tarballs, the versions removed
+	// from the package.json and, when no tarball is left, the whole package directory.
+	Freed int64 `json:"freed"`
+	// Deleted is true when the package directory was removed.
+	Deleted bool `json:"deleted"`
+}
+
+// PruneReport is the result of the pruning of the local repository.
+type PruneReport struct {
+	DryRun   bool            `json:"dryRun"`
+	Packages []PrunedPackage `json:"packages"`
+}
+
+// Removed returns the number of removed versions.
+func (r PruneReport) Removed() int {
+	removed := 0
+	for _, pkg := range r.Packages {
+		removed += len(pkg.Removed)
+	}
+	return removed
+}
+
+// Freed returns the number of bytes freed on disk.
+func (r PruneReport) Freed() int64 {
+	var freed int64
+	for _, pkg := range r.Packages {
+		freed += pkg.Freed
+	}
+	return freed
+}
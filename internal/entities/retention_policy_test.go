@@ -0,0 +1,104 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy_Prune(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	pkg := func(version string, age time.Duration) NpmPackage {
+		v, _ := NewSemVer(version)
+		return NpmPackage{Name: "pkg", Version: v, ReleaseDate: now.Add(-age)}
+	}
+	versions := []NpmPackage{
+		pkg("1.0.0", 400*24*time.Hour),
+		pkg("2.0.0-rc.1", 300*24*time.Hour),
+		pkg("2.0.0", 200*24*time.Hour),
+		pkg("3.0.0-beta.1", 10*24*time.Hour),
+		pkg("2.1.0", 5*24*time.Hour),
+	}
+	names := func(packages []NpmPackage) []string {
+		var result []string
+		for _, p := range packages {
+			result = append(result, p.Version.String())
+		}
+		return result
+	}
+
+	t.Run("Keep last", func(t *testing.T) {
+		policy := RetentionPolicy{KeepLast: 2}
+
+		assert.Equal(t, []string{"1.0.0", "2.0.0-rc.1", "2.0.0"}, names(policy.Prune(versions, now)))
+	})
+
+	t.Run("Old pre-releases", func(t *testing.T) {
+		policy := RetentionPolicy{PrereleaseMaxAge: 30 * 24 * time.Hour}
+
+		assert.Equal(t, []string{"2.0.0-rc.1"}, names(policy.Prune(versions, now)))
+	})
+
+	t.Run("Locked versions are kept", func(t *testing.T) {
+		locked := LockedVersions{}
+		locked.Add("pkg", "1.0.0")
+		policy := RetentionPolicy{KeepLast: 2, PrereleaseMaxAge: 30 * 24 * time.Hour, Locked: locked}
+
+		assert.Equal(t, []string{"2.0.0-rc.1", "2.0.0"}, names(policy.Prune(versions, now)))
+	})
+
+	t.Run("Empty policy", func(t *testing.T) {
+		assert.True(t, RetentionPolicy{}.IsEmpty())
+		assert.Empty(t, RetentionPolicy{}.Prune(versions, now))
+	})
+}
+
+func TestLockedVersions_ParsePackageLock(t *testing.T) {
+	t.Run("Lockfile version 3", func(t *testing.T) {
+		locked := LockedVersions{}
+
+		err := locked.ParsePackageLock([]byte(`{
+			"lockfileVersion": 3,
+			"packages": {
+				"": {"name": "app", "version": "1.0.0"},
+				"node_modules/express": {"version": "4.18.2"},
+				"node_modules/express/node_modules/qs": {"version": "6.11.0"},
+				"node_modules/@scope/pkg": {"version": "1.0.0-beta.1"},
+				"node_modules/alias": {"name": "lodash", "version": "4.17.21"},
+				"node_modules/local": {"resolved": "packages/local", "link": true},
+				"node_modules/git": {"version": "git+https://github.com/a/b.git#abc"}
+			}
+		}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, LockedVersions{
+			"express":    {"4.18.2": true},
+			"qs":         {"6.11.0": true},
+			"@scope/pkg": {"1.0.0-beta.1": true},
+			"lodash":     {"4.17.21": true},
+		}, locked)
+	})
+
+	t.Run("Lockfile version 1", func(t *testing.T) {
+		locked := LockedVersions{}
+
+		err := locked.ParsePackageLock([]byte(`{
+			"lockfileVersion": 1,
+			"dependencies": {
+				"express": {"version": "4.18.2", "dependencies": {"qs": {"version": "6.11.0"}}},
+				"alias": {"version": "npm:@scope/pkg@1.0.0"}
+			}
+		}`))
+
+		assert.NoError(t, err)
+		assert.True(t, locked.Contains("qs", "6.11.0"))
+		assert.True(t, locked.Contains("@scope/pkg", "1.0.0"))
+		assert.False(t, locked.Contains("alias", "1.0.0"))
+	})
+
+	t.Run("Invalid lockfile", func(t *testing.T) {
+		assert.ErrorContains(t, LockedVersions{}.ParsePackageLock([]byte(`{"name": "app"}`)), "invalid package-lock.json")
+		assert.ErrorContains(t, LockedVersions{}.ParsePackageLock([]byte(`[`)), "invalid package-lock.json")
+	})
+}
@@ -0,0 +1,312 @@
+package entities
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UnmirrorableDependency is a dependency which cannot be stored in the local repository,
+// such as a git repository or a local directory.
+type UnmirrorableDependency struct {
+	// Dependent is the package version declaring the dependency, as name@version.
+	Dependent string `json:"dependent"`
+	DependencySpec
+}
+
+// RemoteTarball is a tarball downloaded from an URL instead of the registry.
+type RemoteTarball struct {
+	URL       string `json:"url"`
+	Integrity string `json:"integrity"`
+	// Path is the location of the tarball, relative to the local repository.
+	Path string `json:"path"`
+}
+
+// DeprecatedVersion is a deprecated version met during the run.
+type DeprecatedVersion struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Message string `json:"message"`
+	// Downloaded is false when the version was skipped by the deprecated policy.
+	Downloaded bool `json:"downloaded"`
+}
+
+// UnpublishedPackage is a package which was unpublished from the registry.
+type UnpublishedPackage struct {
+	Name     string    `json:"name"`
+	Time     time.Time `json:"time"`
+	Versions []string  `json:"versions"`
+}
+
+// PlannedDownload is a tarball a dry run would download.
+type PlannedDownload struct {
+	Package string `json:"package"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url"`
+	// Size is the Content-Length of the tarball, -1 when the registry did not send it.
+	Size int64 `json:"size"`
+	// UnpackedSize is the dist.unpackedSize of the version, 0 when the registry does not set it.
+	UnpackedSize int64 `json:"unpackedSize,omitempty"`
+}
+
+// DownloadEstimate sums up the tarballs a dry run would download.
+type DownloadEstimate struct {
+	Packages int `json:"packages"`
+	Versions int `json:"versions"`
+	// Size is the size of the tarballs to transfer and store. The unpacked size, an upper bound,
+	// is counted for the tarballs without a Content-Length.
+	Size int64 `json:"size"`
+	// UnpackedSize is the size of the files of the tarballs setting dist.unpackedSize.
+	UnpackedSize int64 `json:"unpackedSize"`
+	// Unknown is the number of tarballs whose size is unknown, not counted in Size.
+	Unknown int `json:"unknown"`
+}
+
+// RunReport collects what happened during a download run. It is safe for concurrent use by the workers.
+type RunReport struct {
+	mutex          sync.Mutex
+	unmirrorable   map[string]UnmirrorableDependency
+	remoteTarballs map[string]RemoteTarball
+	deprecated     map[string]DeprecatedVersion
+	unpublished    map[string]UnpublishedPackage
+	policy         map[string]PolicyDecision
+	roots          map[string]bool
+	edges          map[DependencyEdge]bool
+	planned        map[string]PlannedDownload
+}
+
+// NewRunReport creates an empty report.
+func NewRunReport() *RunReport {
+	return &RunReport{
+		unmirrorable:   map[string]UnmirrorableDependency{},
+		remoteTarballs: map[string]RemoteTarball{},
+		deprecated:     map[string]DeprecatedVersion{},
+		unpublished:    map[string]UnpublishedPackage{},
+		policy:         map[string]PolicyDecision{},
+		roots:          map[string]bool{},
+		edges:          map[DependencyEdge]bool{},
+		planned:        map[string]PlannedDownload{},
+	}
+}
+
+// AddUnmirrorable records a dependency which cannot be mirrored.
+func (r *RunReport) AddUnmirrorable(dependent string, spec DependencySpec) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.unmirrorable[dependent+"|"+spec.Name+"|"+spec.Spec] = UnmirrorableDependency{Dependent: dependent, DependencySpec: spec}
+}
+
+// AddRemoteTarball records a downloaded remote tarball.
+func (r *RunReport) AddRemoteTarball(tarball RemoteTarball) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.remoteTarballs[tarball.URL] = tarball
+}
+
+// AddDeprecated records a deprecated version. A version skipped first and downloaded later
+// is recorded as downloaded.
+func (r *RunReport) AddDeprecated(version DeprecatedVersion) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := version.Package + "@" + version.Version
+	if previous, ok := r.deprecated[key]; ok && previous.Downloaded {
+		version.Downloaded = true
+	}
+	r.deprecated[key] = version
+}
+
+// AddUnpublished records an unpublished package.
+func (r *RunReport) AddUnpublished(pkg UnpublishedPackage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.unpublished[pkg.Name] = pkg
+}
+
+// AddPolicyDecision records a decision of the package policy.
+func (r *RunReport) AddPolicyDecision(decision PolicyDecision) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.policy[decision.Package+"@"+decision.Version] = decision
+}
+
+// AddRoot records a package given as input of the run.
+func (r *RunReport) AddRoot(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.roots[name] = true
+}
+
+// AddDependencyEdge records a dependency followed by the metadata workers.
+func (r *RunReport) AddDependencyEdge(edge DependencyEdge) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.edges[edge] = true
+}
+
+// AddPlannedDownload records a tarball a dry run would download.
+func (r *RunReport) AddPlannedDownload(download PlannedDownload) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.planned[download.URL] = download
+}
+
+// Unmirrorable returns the dependencies which could not be mirrored, sorted by dependent.
+func (r *RunReport) Unmirrorable() []UnmirrorableDependency {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	deps := make([]UnmirrorableDependency, 0, len(r.unmirrorable))
+	for _, dep := range r.unmirrorable {
+		deps = append(deps, dep)
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Dependent != deps[j].Dependent {
+			return deps[i].Dependent < deps[j].Dependent
+		}
+		return deps[i].Name < deps[j].Name
+	})
+	return deps
+}
+
+// RemoteTarballs returns the downloaded remote tarballs, sorted by URL.
+func (r *RunReport) RemoteTarballs() []RemoteTarball {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	tarballs := make([]RemoteTarball, 0, len(r.remoteTarballs))
+	for _, tarball := range r.remoteTarballs {
+		tarballs = append(tarballs, tarball)
+	}
+	sort.Slice(tarballs, func(i, j int) bool { return tarballs[i].URL < tarballs[j].URL })
+	return tarballs
+}
+
+// Deprecated returns the deprecated versions, sorted by package and version.
+func (r *RunReport) Deprecated() []DeprecatedVersion {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	versions := make([]DeprecatedVersion, 0, len(r.deprecated))
+	for _, version := range r.deprecated {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Package != versions[j].Package {
+			return versions[i].Package < versions[j].Package
+		}
+		return versions[i].Version < versions[j].Version
+	})
+	return versions
+}
+
+// Unpublished returns the unpublished packages, sorted by name.
+func (r *RunReport) Unpublished() []UnpublishedPackage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	packages := make([]UnpublishedPackage, 0, len(r.unpublished))
+	for _, pkg := range r.unpublished {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages
+}
+
+// PolicyDecisions returns the decisions of the package policy, sorted by package and version.
+func (r *RunReport) PolicyDecisions() []PolicyDecision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	decisions := make([]PolicyDecision, 0, len(r.policy))
+	for _, decision := range r.policy {
+		decisions = append(decisions, decision)
+	}
+	sort.Slice(decisions, func(i, j int) bool {
+		if decisions[i].Package != decisions[j].Package {
+			return decisions[i].Package < decisions[j].Package
+		}
+		return decisions[i].Version < decisions[j].Version
+	})
+	return decisions
+}
+
+// PlannedDownloads returns the tarballs a dry run would download, sorted by package and version.
+func (r *RunReport) PlannedDownloads() []PlannedDownload {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	downloads := make([]PlannedDownload, 0, len(r.planned))
+	for _, download := range r.planned {
+		downloads = append(downloads, download)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		if downloads[i].Package != downloads[j].Package {
+			return downloads[i].Package < downloads[j].Package
+		}
+		if downloads[i].Version != downloads[j].Version {
+			return downloads[i].Version < downloads[j].Version
+		}
+		return downloads[i].URL < downloads[j].URL
+	})
+	return downloads
+}
+
+// DownloadEstimate sums up the tarballs a dry run would download.
+func (r *RunReport) DownloadEstimate() DownloadEstimate {
+	downloads := r.PlannedDownloads()
+	estimate := DownloadEstimate{Versions: len(downloads)}
+	packages := map[string]bool{}
+	for _, download := range downloads {
+		packages[download.Package] = true
+		estimate.UnpackedSize += download.UnpackedSize
+		switch {
+		case download.Size >= 0:
+			estimate.Size += download.Size
+		case download.UnpackedSize > 0:
+			estimate.Size += download.UnpackedSize
+		default:
+			estimate.Unknown++
+		}
+	}
+	estimate.Packages = len(packages)
+	return estimate
+}
+
+// DependencyGraph returns the dependencies discovered during the run, sorted.
+func (r *RunReport) DependencyGraph() DependencyGraph {
+	r.mutex.Lock()
+	var graph DependencyGraph
+	for root := range r.roots {
+		graph.Roots = append(graph.Roots, root)
+	}
+	for edge := range r.edges {
+		graph.Edges = append(graph.Edges, edge)
+	}
+	r.mutex.Unlock()
+	graph.Merge(DependencyGraph{})
+	return graph
+}
+
+// MarshalJSON writes the report as a JSON document.
+func (r *RunReport) MarshalJSON() ([]byte, error) {
+	// The planned downloads are only reported by dry runs.
+	var planned []PlannedDownload
+	var estimate *DownloadEstimate
+	if downloads := r.PlannedDownloads(); len(downloads) > 0 {
+		planned = downloads
+		summary := r.DownloadEstimate()
+		estimate = &summary
+	}
+	return json.Marshal(struct {
+		Unmirrorable   []UnmirrorableDependency `json:"unmirrorable"`
+		RemoteTarballs []RemoteTarball          `json:"remoteTarballs"`
+		Deprecated     []DeprecatedVersion      `json:"deprecated"`
+		Unpublished    []UnpublishedPackage     `json:"unpublished"`
+		Policy         []PolicyDecision         `json:"policy"`
+		Planned        []PlannedDownload        `json:"planned,omitempty"`
+		Estimate       *DownloadEstimate        `json:"estimate,omitempty"`
+	}{
+		Unmirrorable:   r.Unmirrorable(),
+		RemoteTarballs: r.RemoteTarballs(),
+		Deprecated:     r.Deprecated(),
+		Unpublished:    r.Unpublished(),
+		Policy:         r.PolicyDecisions(),
+		Planned:        planned,
+		Estimate:       estimate,
+	})
+}
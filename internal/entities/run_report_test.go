@@ -0,0 +1,81 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReport(t *testing.T) {
+	t.Run("Concurrent additions are deduplicated and sorted", func(t *testing.T) {
+		report := NewRunReport()
+		git := ParseDependencySpec("lib", "github:user/repo")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				report.AddUnmirrorable(fmt.Sprintf("pkg@1.0.%d", i%2), git)
+				report.AddRemoteTarball(RemoteTarball{URL: fmt.Sprintf("https://host/%d.tgz", i%3)})
+			}(i)
+		}
+		wg.Wait()
+
+		unmirrorable := report.Unmirrorable()
+		require.Len(t, unmirrorable, 2)
+		assert.Equal(t, "pkg@1.0.0", unmirrorable[0].Dependent)
+		assert.Equal(t, "pkg@1.0.1", unmirrorable[1].Dependent)
+		tarballs := report.RemoteTarballs()
+		require.Len(t, tarballs, 3)
+		assert.Equal(t, "https://host/0.tgz", tarballs[0].URL)
+	})
+
+	t.Run("Deprecated version downloaded after being skipped", func(t *testing.T) {
+		report := NewRunReport()
+		report.AddDeprecated(DeprecatedVersion{Package: "request", Version: "2.88.2", Message: "deprecated", Downloaded: true})
+		report.AddDeprecated(DeprecatedVersion{Package: "request", Version: "2.88.2", Message: "deprecated", Downloaded: false})
+		report.AddDeprecated(DeprecatedVersion{Package: "har-validator", Version: "5.1.5", Message: "deprecated", Downloaded: false})
+
+		deprecated := report.Deprecated()
+		require.Len(t, deprecated, 2)
+		assert.Equal(t, "har-validator", deprecated[0].Package)
+		assert.False(t, deprecated[0].Downloaded)
+		assert.True(t, deprecated[1].Downloaded)
+	})
+
+	t.Run("JSON document", func(t *testing.T) {
+		report := NewRunReport()
+		report.AddUnmirrorable("pkg@1.0.0", ParseDependencySpec("lib", "file:../lib"))
+		report.AddRemoteTarball(RemoteTarball{URL: "https://host/x.tgz", Integrity: "sha512-x", Path: "_remote/x.tgz"})
+		report.AddPolicyDecision(PolicyDecision{Package: "evil", Action: PolicyDeny, Rule: 1, Reason: "malicious"})
+
+		data, err := json.Marshal(report)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"unmirrorable": [{"dependent": "pkg@1.0.0", "name": "lib", "spec": "file:../lib", "type": "file"}],
+			"remoteTarballs": [{"url": "https://host/x.tgz", "integrity": "sha512-x", "path": "_remote/x.tgz"}],
+			"deprecated": [],
+			"unpublished": [],
+			"policy": [{"package": "evil", "action": "deny", "rule": 1, "reason": "malicious"}]
+		}`, string(data))
+	})
+
+	t.Run("Download estimate", func(t *testing.T) {
+		report := NewRunReport()
+		report.AddPlannedDownload(PlannedDownload{Package: "b", Version: "1.0.0", URL: "https://host/b-1.0.0.tgz", Size: 100, UnpackedSize: 400})
+		report.AddPlannedDownload(PlannedDownload{Package: "a", Version: "2.0.0", URL: "https://host/a-2.0.0.tgz", Size: -1, UnpackedSize: 300})
+		report.AddPlannedDownload(PlannedDownload{Package: "a", Version: "1.0.0", URL: "https://host/a-1.0.0.tgz", Size: -1})
+
+		planned := report.PlannedDownloads()
+		require.Len(t, planned, 3)
+		assert.Equal(t, "a", planned[0].Package)
+		assert.Equal(t, "1.0.0", planned[0].Version)
+		assert.Equal(t, DownloadEstimate{Packages: 2, Versions: 3, Size: 400, UnpackedSize: 700, Unknown: 1}, report.DownloadEstimate())
+	})
+}
@@ -0,0 +1,114 @@
+package entities
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Hash is a checksum of a tarball.
+type Hash struct {
+	// Algorithm is the CycloneDX name of the algorithm, such as "SHA-512".
+	Algorithm string `json:"algorithm"`
+	// Value is the checksum in hexadecimal.
+	Value string `json:"value"`
+}
+
+// integrityAlgorithms maps the algorithms of the Subresource Integrity strings to their CycloneDX name.
+var integrityAlgorithms = map[string]string{
+	"sha1":   "SHA-1",
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// IntegrityHashes decodes the hashes of an integrity string, such as "sha512-<base64>", which
+// may hold several space-separated hashes, and adds the hexadecimal SHA-1 shasum of old
+// packuments. Invalid hashes are skipped.
+func IntegrityHashes(integrity string, shasum string) []Hash {
+	var hashes []Hash
+	seen := map[string]bool{}
+	add := func(hash Hash) {
+		if !seen[hash.Algorithm] {
+			seen[hash.Algorithm] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	for _, field := range strings.Fields(integrity) {
+		prefix, digest, ok := strings.Cut(field, "-")
+		algorithm, known := integrityAlgorithms[prefix]
+		if !ok || !known {
+			continue
+		}
+		// Options may follow the digest, such as "sha512-<base64>?opt".
+		digest, _, _ = strings.Cut(digest, "?")
+		value, err := base64.StdEncoding.DecodeString(digest)
+		if err != nil {
+			continue
+		}
+		add(Hash{Algorithm: algorithm, Value: hex.EncodeToString(value)})
+	}
+	if _, err := hex.DecodeString(shasum); err == nil && len(shasum) == 40 {
+		add(Hash{Algorithm: "SHA-1", Value: strings.ToLower(shasum)})
+	}
+	return hashes
+}
+
+// PackageURL returns the purl of a version, such as "pkg:npm/%40scope/name@1.0.0".
+func PackageURL(name string, version string) string {
+	name = strings.Replace(name, "@", "%40", 1)
+	return "pkg:npm/" + name + "@" + strings.ReplaceAll(version, "+", "%2B")
+}
+
+// SBOMComponent is a stored version listed in a software bill of materials.
+type SBOMComponent struct {
+	Package string
+	Version string
+	PURL    string
+	// License is the SPDX expression of the license, or the declared license when it is unknown.
+	License      string
+	KnownLicense bool
+	// Tarball is the URL the tarball was downloaded from.
+	Tarball string
+	Hashes  []Hash
+	// DependsOn are the purls of the stored versions resolving the dependencies of the version.
+	DependsOn []string
+}
+
+// NewSBOMComponent creates the component of a stored version, without its dependencies.
+func NewSBOMComponent(version NpmPackage) SBOMComponent {
+	license, known := NormalizeLicense(version.License)
+	return SBOMComponent{
+		Package:      version.Name,
+		Version:      version.Version.String(),
+		PURL:         PackageURL(version.Name, version.Version.String()),
+		License:      license,
+		KnownLicense: known,
+		Tarball:      version.Url,
+		Hashes:       IntegrityHashes(version.Integrity, version.Shasum),
+	}
+}
+
+// SBOM is the software bill of materials of the local repository.
+type SBOM struct {
+	Components []SBOMComponent
+}
+
+// Sort sorts the components by package and version, and their dependencies.
+func (s *SBOM) Sort() {
+	for _, component := range s.Components {
+		sort.Strings(component.DependsOn)
+	}
+	sort.SliceStable(s.Components, func(i, j int) bool {
+		if s.Components[i].Package != s.Components[j].Package {
+			return s.Components[i].Package < s.Components[j].Package
+		}
+		vi, erri := NewSemVer(s.Components[i].Version)
+		vj, errj := NewSemVer(s.Components[j].Version)
+		if erri != nil || errj != nil {
+			return s.Components[i].Version < s.Components[j].Version
+		}
+		return vi.Compare(vj) < 0
+	})
+}
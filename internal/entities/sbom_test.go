@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrityHashes(t *testing.T) {
+	t.Run("Integrity and shasum", func(t *testing.T) {
+		hashes := IntegrityHashes("sha512-AAEC sha1-AwQ= md5-BQY=", "0A0B0C0D0E0F101112131415161718191A1B1C1D")
+
+		assert.Equal(t, []Hash{
+			{Algorithm: "SHA-512", Value: "000102"},
+			{Algorithm: "SHA-1", Value: "0304"},
+		}, hashes)
+	})
+
+	t.Run("Shasum only", func(t *testing.T) {
+		hashes := IntegrityHashes("", "0a0b0c0d0e0f101112131415161718191a1b1c1d")
+
+		assert.Equal(t, []Hash{{Algorithm: "SHA-1", Value: "0a0b0c0d0e0f101112131415161718191a1b1c1d"}}, hashes)
+	})
+
+	t.Run("Invalid hashes are skipped", func(t *testing.T) {
+		assert.Empty(t, IntegrityHashes("sha512-!!! sha512", "xyz"))
+	})
+}
+
+func TestPackageURL(t *testing.T) {
+	assert.Equal(t, "pkg:npm/lodash@4.17.21", PackageURL("lodash", "4.17.21"))
+	assert.Equal(t, "pkg:npm/%40babel/core@7.0.0-beta.1%2Bbuild", PackageURL("@babel/core", "7.0.0-beta.1+build"))
+}
+
+func TestNewSBOMComponent(t *testing.T) {
+	component := NewSBOMComponent(NpmPackage{
+		Name:      "@scope/pkg",
+		Version:   SemVer{Major: 1, Minor: 2},
+		License:   "Apache 2.0",
+		Integrity: "sha512-AAEC",
+		Url:       "https://registry.npmjs.org/@scope/pkg/-/pkg-1.2.0.tgz",
+	})
+
+	assert.Equal(t, SBOMComponent{
+		Package:      "@scope/pkg",
+		Version:      "1.2.0",
+		PURL:         "pkg:npm/%40scope/pkg@1.2.0",
+		License:      "Apache-2.0",
+		KnownLicense: true,
+		Tarball:      "https://registry.npmjs.org/@scope/pkg/-/pkg-1.2.0.tgz",
+		Hashes:       []Hash{{Algorithm: "SHA-512", Value: "000102"}},
+	}, component)
+}
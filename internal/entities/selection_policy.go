@@ -0,0 +1,139 @@
+package entities
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SelectionPolicy selects the versions of a package to download.
+// ReleasedAfter restricts the versions considered. The selectors (Latest, LatestPerMajor,
+// DistTagsOnly and RequiredOnly) are combined: a version is selected when any of them selects it.
+// Without any selector, every version is selected.
+type SelectionPolicy struct {
+	// Latest selects the N highest versions.
+	Latest int `yaml:"latest,omitempty" json:"latest,omitempty"`
+	// LatestPerMajor selects the highest version of each major version.
+	LatestPerMajor bool `yaml:"latestPerMajor,omitempty" json:"latestPerMajor,omitempty"`
+	// ReleasedAfter ignores the versions released before the given date.
+	ReleasedAfter time.Time `yaml:"releasedAfter,omitempty" json:"releasedAfter,omitempty"`
+	// DistTagsOnly selects the versions pointed to by a dist-tag, such as "latest" or "next".
+	DistTagsOnly bool `yaml:"distTagsOnly,omitempty" json:"distTagsOnly,omitempty"`
+	// RequiredOnly selects the versions npm would install for the ranges required by the dependents,
+	// or the latest version for the packages requested directly.
+	RequiredOnly bool `yaml:"requiredOnly,omitempty" json:"requiredOnly,omitempty"`
+}
+
+// HasSelectors returns true if at least one selector is enabled.
+func (p SelectionPolicy) HasSelectors() bool {
+	return p.Latest > 0 || p.LatestPerMajor || p.DistTagsOnly || p.RequiredOnly
+}
+
+// Validate checks the policy values.
+func (p SelectionPolicy) Validate() error {
+	if p.Latest < 0 {
+		return fmt.Errorf("latest must be positive, got %d", p.Latest)
+	}
+	return nil
+}
+
+// Select returns the versions selected by the date restriction and the static selectors.
+// The versions only selected by RequiredOnly depend on the dependents and are not returned.
+func (p SelectionPolicy) Select(versions []NpmPackage) []NpmPackage {
+	var considered []NpmPackage
+	for _, version := range versions {
+		if p.ReleasedAfter.IsZero() || version.ReleaseDate.After(p.ReleasedAfter) {
+			considered = append(considered, version)
+		}
+	}
+	if !p.HasSelectors() {
+		return considered
+	}
+
+	// Sort from the highest version, without modifying the given slice.
+	sorted := append([]NpmPackage(nil), considered...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Version.Compare(sorted[j].Version) > 0 })
+
+	selected := make([]bool, len(sorted))
+	seenMajors := map[int]bool{}
+	for i, version := range sorted {
+		if i < p.Latest {
+			selected[i] = true
+		}
+		if p.LatestPerMajor && !seenMajors[version.Version.Major] {
+			seenMajors[version.Version.Major] = true
+			selected[i] = true
+		}
+		if p.DistTagsOnly && len(version.DistTags) > 0 {
+			selected[i] = true
+		}
+	}
+
+	var result []NpmPackage
+	for i, version := range sorted {
+		if selected[i] {
+			result = append(result, version)
+		}
+	}
+	return result
+}
+
+// SelectionPolicies holds the default selection policy and the policies of some packages.
+type SelectionPolicies struct {
+	Default SelectionPolicy `yaml:"default" json:"default"`
+	// Packages are the policies by package name. Names may be glob patterns, e.g. "@types/*".
+	// A package policy replaces the default policy.
+	Packages map[string]SelectionPolicy `yaml:"packages,omitempty" json:"packages,omitempty"`
+}
+
+// Validate checks every policy and pattern.
+func (p SelectionPolicies) Validate() error {
+	if err := p.Default.Validate(); err != nil {
+		return fmt.Errorf("default policy: %v", err)
+	}
+	for name, policy := range p.Packages {
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("invalid package pattern %q: %v", name, err)
+		}
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("policy of %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// HasRequiredOnly returns true if a policy selects the versions required by the dependents.
+func (p SelectionPolicies) HasRequiredOnly() bool {
+	if p.Default.RequiredOnly {
+		return true
+	}
+	for _, policy := range p.Packages {
+		if policy.RequiredOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns the policy of a package: the policy of its exact name, else the policy of the
+// longest matching pattern, else the default policy.
+func (p SelectionPolicies) For(packageName string) SelectionPolicy {
+	if policy, ok := p.Packages[packageName]; ok {
+		return policy
+	}
+	best := ""
+	for pattern := range p.Packages {
+		if !strings.ContainsAny(pattern, "*?[") {
+			continue
+		}
+		if matched, _ := path.Match(pattern, packageName); matched && (len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best)) {
+			best = pattern
+		}
+	}
+	if best != "" {
+		return p.Packages[best]
+	}
+	return p.Default
+}
@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectionPolicy_Select(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, time.January, d, 0, 0, 0, 0, time.UTC) }
+	version := func(v string, released time.Time, tags ...string) NpmPackage {
+		return NpmPackage{Name: "pkg", Version: mustSemVer(t, v), ReleaseDate: released, DistTags: tags}
+	}
+	v1 := version("1.0.0", day(1))
+	v12 := version("1.2.0", day(3))
+	v2 := version("2.0.0", day(2), "next")
+	v21 := version("2.1.0", day(5), "latest")
+	v3 := version("3.0.0", day(4))
+	all := []NpmPackage{v1, v12, v2, v21, v3}
+
+	versions := func(packages []NpmPackage) []string {
+		var result []string
+		for _, pkg := range packages {
+			result = append(result, pkg.Version.String())
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		policy   SelectionPolicy
+		expected []string
+	}{
+		{name: "No selector", policy: SelectionPolicy{}, expected: []string{"1.0.0", "1.2.0", "2.0.0", "2.1.0", "3.0.0"}},
+		{name: "Latest", policy: SelectionPolicy{Latest: 2}, expected: []string{"3.0.0", "2.1.0"}},
+		{name: "Latest per major", policy: SelectionPolicy{LatestPerMajor: true}, expected: []string{"3.0.0", "2.1.0", "1.2.0"}},
+		{name: "Dist-tags only", policy: SelectionPolicy{DistTagsOnly: true}, expected: []string{"2.1.0", "2.0.0"}},
+		{name: "Released after", policy: SelectionPolicy{ReleasedAfter: day(2)}, expected: []string{"1.2.0", "2.1.0", "3.0.0"}},
+		{name: "Selectors are combined", policy: SelectionPolicy{Latest: 1, DistTagsOnly: true}, expected: []string{"3.0.0", "2.1.0", "2.0.0"}},
+		{name: "Released after restricts the selectors", policy: SelectionPolicy{LatestPerMajor: true, ReleasedAfter: day(2)}, expected: []string{"3.0.0", "2.1.0", "1.2.0"}},
+		{name: "Required only selects nothing by itself", policy: SelectionPolicy{RequiredOnly: true}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, versions(tt.policy.Select(all)))
+		})
+	}
+}
+
+func TestSelectionPolicies_For(t *testing.T) {
+	policies := SelectionPolicies{
+		Default: SelectionPolicy{Latest: 3},
+		Packages: map[string]SelectionPolicy{
+			"react":          {Latest: 1},
+			"@types/*":       {DistTagsOnly: true},
+			"@types/node*":   {LatestPerMajor: true},
+			"@types/nodemon": {RequiredOnly: true},
+		},
+	}
+
+	assert.Equal(t, SelectionPolicy{Latest: 1}, policies.For("react"))
+	assert.Equal(t, SelectionPolicy{DistTagsOnly: true}, policies.For("@types/react"))
+	assert.Equal(t, SelectionPolicy{LatestPerMajor: true}, policies.For("@types/node"))
+	assert.Equal(t, SelectionPolicy{RequiredOnly: true}, policies.For("@types/nodemon"))
+	assert.Equal(t, SelectionPolicy{Latest: 3}, policies.For("lodash"))
+	assert.True(t, policies.HasRequiredOnly())
+}
+
+func TestSelectionPolicies_Validate(t *testing.T) {
+	assert.NoError(t, SelectionPolicies{Packages: map[string]SelectionPolicy{"@types/*": {Latest: 2}}}.Validate())
+	assert.Error(t, SelectionPolicies{Default: SelectionPolicy{Latest: -1}}.Validate())
+	assert.Error(t, SelectionPolicies{Packages: map[string]SelectionPolicy{"[a-": {}}}.Validate())
+	assert.Error(t, SelectionPolicies{Packages: map[string]SelectionPolicy{"react": {Latest: -2}}}.Validate())
+}
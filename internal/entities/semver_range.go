@@ -0,0 +1,308 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// comparator is a single condition of a range, such as ">=1.2.3".
+type comparator struct {
+	operator string // One of "<", "<=", ">", ">=", "=".
+	version  SemVer
+}
+
+func (c comparator) matches(v SemVer) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// SemVerRange is an npm version range: a union of comparator sets, each one being
+// an intersection of comparators, e.g. ">=1.2.3 <2.0.0 || ^3.0.0".
+type SemVerRange struct {
+	raw  string
+	sets [][]comparator
+}
+
+// partialVersion matches a full or partial version with optional wildcards, e.g. "1", "1.2.x", "1.2.3-beta.1".
+var partialVersion = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ParseSemVerRange parses an npm version range, following the grammar of node-semver:
+// "||" unions, hyphen ranges, caret and tilde ranges, wildcards and partial versions.
+// Dist-tags such as "latest" are not ranges and are rejected.
+func ParseSemVerRange(value string) (SemVerRange, error) {
+	r := SemVerRange{raw: value}
+	for _, part := range strings.Split(value, "||") {
+		set, err := parseComparatorSet(strings.TrimSpace(part))
+		if err != nil {
+			return SemVerRange{}, fmt.Errorf("invalid version range %q: %v", value, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as written.
+func (r SemVerRange) String() string {
+	return r.raw
+}
+
+// Matches returns true if the version satisfies the range.
+// Like npm, a pre-release version only satisfies a comparator set in which a comparator
+// has a pre-release on the same major.minor.patch.
+func (r SemVerRange) Matches(v SemVer) bool {
+	for _, set := range r.sets {
+		if matchesSet(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxMatching returns the highest of the versions satisfying the range.
+func (r SemVerRange) MaxMatching(versions []SemVer) (SemVer, bool) {
+	var best SemVer
+	found := false
+	for _, v := range versions {
+		if r.Matches(v) && (!found || v.Compare(best) > 0) {
+			best = v
+			found = true
+		}
+	}
+	return best, found
+}
+
+func matchesSet(set []comparator, v SemVer) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPreRelease() {
+		return true
+	}
+	for _, c := range set {
+		if c.version.IsPreRelease() && c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparatorSet parses the comparators of one side of a "||".
+func parseComparatorSet(value string) ([]comparator, error) {
+	if value == "" {
+		return []comparator{}, nil
+	}
+
+	// Hyphen range: "1.2 - 2.3.4".
+	if left, right, found := strings.Cut(value, " - "); found {
+		from, err := parsePartial(strings.TrimSpace(left))
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(strings.TrimSpace(right))
+		if err != nil {
+			return nil, err
+		}
+		var set []comparator
+		if !from.isAny() {
+			set = append(set, comparator{">=", from.lower()})
+		}
+		if !to.isAny() {
+			if to.isFull() {
+				set = append(set, comparator{"<=", to.version()})
+			} else {
+				set = append(set, comparator{"<", to.upperExclusive()})
+			}
+		}
+		return set, nil
+	}
+
+	// Operators may be separated from their version by spaces, e.g. ">= 1.2.3".
+	fields := strings.Fields(value)
+	var tokens []string
+	for i := 0; i < len(fields); i++ {
+		token := fields[i]
+		if strings.Trim(token, "<>=~^") == "" && i+1 < len(fields) {
+			token += fields[i+1]
+			i++
+		}
+		tokens = append(tokens, token)
+	}
+
+	set := []comparator{}
+	for _, token := range tokens {
+		comparators, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// parseComparator expands a single token into primitive comparators.
+func parseComparator(token string) ([]comparator, error) {
+	operator := ""
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~>", "~"} {
+		if strings.HasPrefix(token, op) {
+			operator = op
+			token = token[len(op):]
+			break
+		}
+	}
+
+	p, err := parsePartial(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "^":
+		if p.isAny() {
+			return nil, nil
+		}
+		return []comparator{{">=", p.lower()}, {"<", p.caretUpper()}}, nil
+	case "~", "~>":
+		if p.isAny() {
+			return nil, nil
+		}
+		return []comparator{{">=", p.lower()}, {"<", p.tildeUpper()}}, nil
+	case ">":
+		if p.isAny() {
+			// Nothing is greater than any version.
+			return []comparator{{"<", SemVer{}}}, nil
+		}
+		if p.isFull() {
+			return []comparator{{">", p.version()}}, nil
+		}
+		return []comparator{{">=", p.upperExclusive()}}, nil
+	case ">=":
+		if p.isAny() {
+			return nil, nil
+		}
+		return []comparator{{">=", p.lower()}}, nil
+	case "<":
+		if p.isAny() {
+			return []comparator{{"<", SemVer{}}}, nil
+		}
+		return []comparator{{"<", p.lower()}}, nil
+	case "<=":
+		if p.isAny() {
+			return nil, nil
+		}
+		if p.isFull() {
+			return []comparator{{"<=", p.version()}}, nil
+		}
+		return []comparator{{"<", p.upperExclusive()}}, nil
+	default:
+		if p.isAny() {
+			return nil, nil
+		}
+		if p.isFull() {
+			return []comparator{{"=", p.version()}}, nil
+		}
+		return []comparator{{">=", p.lower()}, {"<", p.upperExclusive()}}, nil
+	}
+}
+
+// partial is a version in which the minor and patch numbers may be missing or wildcards.
+type partial struct {
+	parts      []int // The numbers given, without the wildcards.
+	preRelease string
+}
+
+func parsePartial(value string) (partial, error) {
+	matches := partialVersion.FindStringSubmatch(value)
+	if matches == nil {
+		return partial{}, fmt.Errorf("invalid version %q", value)
+	}
+	var p partial
+	for _, m := range matches[1:4] {
+		if m == "" || m == "x" || m == "X" || m == "*" {
+			break
+		}
+		n, err := strconv.Atoi(m)
+		if err != nil {
+			return partial{}, err
+		}
+		p.parts = append(p.parts, n)
+	}
+	if len(p.parts) == 3 {
+		p.preRelease = matches[4]
+	}
+	return p, nil
+}
+
+func (p partial) isAny() bool  { return len(p.parts) == 0 }
+func (p partial) isFull() bool { return len(p.parts) == 3 }
+
+func (p partial) number(i int) int {
+	if i < len(p.parts) {
+		return p.parts[i]
+	}
+	return 0
+}
+
+// version returns the partial as a version, the missing numbers being zeros.
+func (p partial) version() SemVer {
+	return SemVer{Major: p.number(0), Minor: p.number(1), Patch: p.number(2), PreRelease: p.preRelease}
+}
+
+// lower returns the lowest version matching the partial. Ranges without a pre-release
+// exclude the pre-releases of their lower bound, as in node-semver.
+func (p partial) lower() SemVer {
+	v := p.version()
+	if !p.isFull() {
+		v.PreRelease = ""
+	}
+	return v
+}
+
+// upperExclusive returns the first version above the partial, e.g. 1.3.0 for "1.2".
+// The "0" pre-release makes the bound exclude the pre-releases of the next version.
+func (p partial) upperExclusive() SemVer {
+	switch len(p.parts) {
+	case 1:
+		return SemVer{Major: p.parts[0] + 1, PreRelease: "0"}
+	case 2:
+		return SemVer{Major: p.parts[0], Minor: p.parts[1] + 1, PreRelease: "0"}
+	default:
+		return SemVer{Major: p.parts[0], Minor: p.parts[1], Patch: p.parts[2] + 1, PreRelease: "0"}
+	}
+}
+
+// caretUpper returns the exclusive upper bound of "^partial": changes to the left-most
+// non-zero number are not allowed.
+func (p partial) caretUpper() SemVer {
+	switch {
+	case p.number(0) > 0 || len(p.parts) == 1:
+		return SemVer{Major: p.number(0) + 1, PreRelease: "0"}
+	case p.number(1) > 0 || len(p.parts) == 2:
+		return SemVer{Minor: p.number(1) + 1, PreRelease: "0"}
+	default:
+		return SemVer{Patch: p.number(2) + 1, PreRelease: "0"}
+	}
+}
+
+// tildeUpper returns the exclusive upper bound of "~partial": patch changes are allowed
+// when a minor number is given, minor changes otherwise.
+func (p partial) tildeUpper() SemVer {
+	if len(p.parts) == 1 {
+		return SemVer{Major: p.number(0) + 1, PreRelease: "0"}
+	}
+	return SemVer{Major: p.number(0), Minor: p.number(1) + 1, PreRelease: "0"}
+}
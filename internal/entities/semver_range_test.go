@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemVerRange_Matches(t *testing.T) {
+	tests := []struct {
+		rng      string
+		matching []string
+		others   []string
+	}{
+		{rng: "", matching: []string{"0.0.1", "5.0.0"}, others: []string{"1.0.0-beta"}},
+		{rng: "*", matching: []string{"1.2.3"}, others: []string{"2.0.0-rc.1"}},
+		{rng: "1.2.3", matching: []string{"1.2.3"}, others: []string{"1.2.4"}},
+		{rng: "=v1.2.3", matching: []string{"1.2.3"}, others: []string{"1.2.2"}},
+		{rng: "^1.2.3", matching: []string{"1.2.3", "1.9.0"}, others: []string{"1.2.2", "2.0.0", "2.0.0-0", "1.3.0-beta"}},
+		{rng: "^0.2.3", matching: []string{"0.2.3", "0.2.9"}, others: []string{"0.3.0"}},
+		{rng: "^0.0.3", matching: []string{"0.0.3"}, others: []string{"0.0.4"}},
+		{rng: "^1.2.3-beta.2", matching: []string{"1.2.3-beta.3", "1.2.3", "1.5.0"}, others: []string{"1.2.4-beta.1", "1.2.3-alpha"}},
+		{rng: "~1.2.3", matching: []string{"1.2.3", "1.2.9"}, others: []string{"1.3.0"}},
+		{rng: "~1", matching: []string{"1.0.0", "1.9.9"}, others: []string{"2.0.0"}},
+		{rng: "1.x", matching: []string{"1.0.0", "1.9.9"}, others: []string{"2.0.0", "0.9.0"}},
+		{rng: "1.2", matching: []string{"1.2.0", "1.2.9"}, others: []string{"1.3.0"}},
+		{rng: ">=1.2.3 <2", matching: []string{"1.2.3", "1.99.0"}, others: []string{"2.0.0", "1.2.2"}},
+		{rng: ">= 1.2.3", matching: []string{"1.2.3"}, others: []string{"1.2.2"}},
+		{rng: ">1.2", matching: []string{"1.3.0"}, others: []string{"1.2.9"}},
+		{rng: "<=1.2", matching: []string{"1.2.9"}, others: []string{"1.3.0"}},
+		{rng: "1.2 - 2.3.4", matching: []string{"1.2.0", "2.3.4"}, others: []string{"2.3.5", "1.1.9"}},
+		{rng: "1.2.3 - 2", matching: []string{"2.9.0"}, others: []string{"3.0.0"}},
+		{rng: "^1.0.0 || ^3.0.0", matching: []string{"1.5.0", "3.1.0"}, others: []string{"2.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			r, err := ParseSemVerRange(tt.rng)
+			require.NoError(t, err)
+
+			for _, v := range tt.matching {
+				assert.True(t, r.Matches(mustSemVer(t, v)), "%s should match %s", v, tt.rng)
+			}
+			for _, v := range tt.others {
+				assert.False(t, r.Matches(mustSemVer(t, v)), "%s should not match %s", v, tt.rng)
+			}
+		})
+	}
+}
+
+func TestParseSemVerRange_Invalid(t *testing.T) {
+	for _, rng := range []string{"latest", "next", "1.2.3.4", "^abc", ">=1.0.0 <"} {
+		_, err := ParseSemVerRange(rng)
+		assert.Error(t, err, rng)
+	}
+}
+
+func TestSemVerRange_MaxMatching(t *testing.T) {
+	r, err := ParseSemVerRange("^1.0.0")
+	require.NoError(t, err)
+
+	best, found := r.MaxMatching([]SemVer{mustSemVer(t, "1.0.0"), mustSemVer(t, "1.4.0"), mustSemVer(t, "2.0.0"), mustSemVer(t, "1.2.0")})
+	assert.True(t, found)
+	assert.Equal(t, "1.4.0", best.String())
+
+	_, found = r.MaxMatching([]SemVer{mustSemVer(t, "2.0.0")})
+	assert.False(t, found)
+}
+
+func mustSemVer(t *testing.T, version string) SemVer {
+	v, err := NewSemVer(version)
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,216 @@
+package entities
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// SnapshotVersion is a stored version, with the fields reviewed before importing a repository.
+type SnapshotVersion struct {
+	Version        string   `json:"version"`
+	License        string   `json:"license,omitempty"`
+	InstallScripts []string `json:"installScripts,omitempty"`
+	Maintainers    []string `json:"maintainers,omitempty"`
+	Integrity      string   `json:"integrity,omitempty"`
+}
+
+// SnapshotPackage is a package of a snapshot, with its versions sorted.
+type SnapshotPackage struct {
+	Name     string            `json:"name"`
+	Versions []SnapshotVersion `json:"versions"`
+}
+
+// Snapshot is the manifest of the content of the local repository at a point in time.
+type Snapshot struct {
+	CreatedAt time.Time         `json:"createdAt"`
+	Packages  []SnapshotPackage `json:"packages"`
+}
+
+// NewSnapshot creates the snapshot of the given stored versions.
+func NewSnapshot(versions []NpmPackage, createdAt time.Time) Snapshot {
+	sorted := append([]NpmPackage{}, versions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].Version.Compare(sorted[j].Version) < 0
+	})
+
+	snapshot := Snapshot{CreatedAt: createdAt, Packages: []SnapshotPackage{}}
+	for _, version := range sorted {
+		if n := len(snapshot.Packages); n == 0 || snapshot.Packages[n-1].Name != version.Name {
+			snapshot.Packages = append(snapshot.Packages, SnapshotPackage{Name: version.Name})
+		}
+		pkg := &snapshot.Packages[len(snapshot.Packages)-1]
+		maintainers := append([]string{}, version.Maintainers...)
+		sort.Strings(maintainers)
+		pkg.Versions = append(pkg.Versions, SnapshotVersion{
+			Version:        version.Version.String(),
+			License:        version.License,
+			InstallScripts: version.InstallScripts,
+			Maintainers:    maintainers,
+			Integrity:      version.Integrity,
+		})
+	}
+	return snapshot
+}
+
+// PackageVersion is a version of a package.
+type PackageVersion struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+}
+
+// LicenseChange is an added version whose license differs from the previous version.
+type LicenseChange struct {
+	Package string `json:"package"`
+	// Previous is the highest version of the old snapshot.
+	Previous string `json:"previous"`
+	Version  string `json:"version"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// InstallScriptsChange is an added version running install scripts the previous version did not.
+type InstallScriptsChange struct {
+	Package string `json:"package"`
+	// Previous is the highest version of the old snapshot, empty for a new package.
+	Previous string   `json:"previous,omitempty"`
+	Version  string   `json:"version"`
+	Scripts  []string `json:"scripts"`
+}
+
+// MaintainersChange compares the maintainers of the highest versions of a package.
+type MaintainersChange struct {
+	Package  string   `json:"package"`
+	Previous string   `json:"previous"`
+	Version  string   `json:"version"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+// SnapshotDiff lists the changes between two snapshots.
+type SnapshotDiff struct {
+	AddedPackages      []string               `json:"addedPackages"`
+	RemovedPackages    []string               `json:"removedPackages"`
+	AddedVersions      []PackageVersion       `json:"addedVersions"`
+	RemovedVersions    []PackageVersion       `json:"removedVersions"`
+	LicenseChanges     []LicenseChange        `json:"licenseChanges"`
+	InstallScripts     []InstallScriptsChange `json:"installScripts"`
+	MaintainersChanges []MaintainersChange    `json:"maintainersChanges"`
+}
+
+// IsEmpty returns true if the snapshots hold the same versions.
+func (d SnapshotDiff) IsEmpty() bool {
+	return len(d.AddedVersions) == 0 && len(d.RemovedVersions) == 0
+}
+
+// Diff lists the packages and versions added and removed since an older snapshot. The added
+// versions are compared with the highest version of the package in the old snapshot: a different
+// license or new install scripts are reported, as are all the install scripts of a new package.
+// The maintainers of the highest versions are compared when the highest version changed.
+func (s Snapshot) Diff(old Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{
+		AddedPackages:      []string{},
+		RemovedPackages:    []string{},
+		AddedVersions:      []PackageVersion{},
+		RemovedVersions:    []PackageVersion{},
+		LicenseChanges:     []LicenseChange{},
+		InstallScripts:     []InstallScriptsChange{},
+		MaintainersChanges: []MaintainersChange{},
+	}
+	previous := map[string]SnapshotPackage{}
+	for _, pkg := range old.Packages {
+		previous[pkg.Name] = pkg
+	}
+	current := map[string]bool{}
+
+	for _, pkg := range s.Packages {
+		current[pkg.Name] = true
+		before, existed := previous[pkg.Name]
+		if !existed {
+			diff.AddedPackages = append(diff.AddedPackages, pkg.Name)
+		}
+		oldVersions := map[string]bool{}
+		for _, version := range before.Versions {
+			oldVersions[version.Version] = true
+		}
+		newVersions := map[string]bool{}
+		var reference *SnapshotVersion
+		if n := len(before.Versions); n > 0 {
+			reference = &before.Versions[n-1]
+		}
+
+		for _, version := range pkg.Versions {
+			newVersions[version.Version] = true
+			if oldVersions[version.Version] {
+				continue
+			}
+			diff.AddedVersions = append(diff.AddedVersions, PackageVersion{Package: pkg.Name, Version: version.Version})
+			if reference == nil {
+				if len(version.InstallScripts) > 0 {
+					diff.InstallScripts = append(diff.InstallScripts, InstallScriptsChange{
+						Package: pkg.Name, Version: version.Version, Scripts: version.InstallScripts,
+					})
+				}
+				continue
+			}
+			from, _ := NormalizeLicense(reference.License)
+			to, _ := NormalizeLicense(version.License)
+			if from != to {
+				diff.LicenseChanges = append(diff.LicenseChanges, LicenseChange{
+					Package: pkg.Name, Previous: reference.Version, Version: version.Version,
+					From: reference.License, To: version.License,
+				})
+			}
+			if added := missingFrom(version.InstallScripts, reference.InstallScripts); len(added) > 0 {
+				diff.InstallScripts = append(diff.InstallScripts, InstallScriptsChange{
+					Package: pkg.Name, Previous: reference.Version, Version: version.Version, Scripts: added,
+				})
+			}
+		}
+		for _, version := range before.Versions {
+			if !newVersions[version.Version] {
+				diff.RemovedVersions = append(diff.RemovedVersions, PackageVersion{Package: pkg.Name, Version: version.Version})
+			}
+		}
+
+		if n := len(pkg.Versions); reference != nil && n > 0 && pkg.Versions[n-1].Version != reference.Version {
+			highest := pkg.Versions[n-1]
+			added := missingFrom(highest.Maintainers, reference.Maintainers)
+			removed := missingFrom(reference.Maintainers, highest.Maintainers)
+			if len(added) > 0 || len(removed) > 0 {
+				diff.MaintainersChanges = append(diff.MaintainersChanges, MaintainersChange{
+					Package: pkg.Name, Previous: reference.Version, Version: highest.Version,
+					Added: added, Removed: removed,
+				})
+			}
+		}
+	}
+
+	for _, pkg := range old.Packages {
+		if current[pkg.Name] {
+			continue
+		}
+		diff.RemovedPackages = append(diff.RemovedPackages, pkg.Name)
+		for _, version := range pkg.Versions {
+			diff.RemovedVersions = append(diff.RemovedVersions, PackageVersion{Package: pkg.Name, Version: version.Version})
+		}
+	}
+	sort.SliceStable(diff.RemovedVersions, func(i, j int) bool {
+		return diff.RemovedVersions[i].Package < diff.RemovedVersions[j].Package
+	})
+	return diff
+}
+
+// missingFrom returns the values missing from the others, never nil.
+func missingFrom(values []string, others []string) []string {
+	missing := []string{}
+	for _, value := range values {
+		if !slices.Contains(others, value) {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
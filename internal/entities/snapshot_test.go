@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	snapshot := NewSnapshot([]NpmPackage{
+		{Name: "b", Version: mustSemVer(t, "1.0.0")},
+		{Name: "a", Version: mustSemVer(t, "2.0.0"), Maintainers: []string{"zoe", "bob"}},
+		{Name: "a", Version: mustSemVer(t, "1.0.0"), License: "MIT", Integrity: "sha512-AAEC"},
+	}, createdAt)
+
+	assert.Equal(t, Snapshot{CreatedAt: createdAt, Packages: []SnapshotPackage{
+		{Name: "a", Versions: []SnapshotVersion{
+			{Version: "1.0.0", License: "MIT", Maintainers: []string{}, Integrity: "sha512-AAEC"},
+			{Version: "2.0.0", Maintainers: []string{"bob", "zoe"}},
+		}},
+		{Name: "b", Versions: []SnapshotVersion{{Version: "1.0.0", Maintainers: []string{}}}},
+	}}, snapshot)
+}
+
+func TestSnapshot_Diff(t *testing.T) {
+	old := Snapshot{Packages: []SnapshotPackage{
+		{Name: "gone", Versions: []SnapshotVersion{{Version: "1.0.0"}}},
+		{Name: "lib", Versions: []SnapshotVersion{
+			{Version: "1.0.0", License: "MIT", Maintainers: []string{"alice", "bob"}},
+			{Version: "1.1.0", License: "MIT", Maintainers: []string{"alice", "bob"}},
+		}},
+		{Name: "stable", Versions: []SnapshotVersion{{Version: "1.0.0", License: "ISC"}}},
+	}}
+	current := Snapshot{Packages: []SnapshotPackage{
+		{Name: "lib", Versions: []SnapshotVersion{
+			{Version: "1.1.0", License: "MIT", Maintainers: []string{"alice", "bob"}},
+			{Version: "1.2.0", License: "MIT", InstallScripts: []string{"postinstall"}},
+			{Version: "2.0.0", License: "BUSL-1.1", Maintainers: []string{"alice", "mallory"}},
+		}},
+		{Name: "native", Versions: []SnapshotVersion{{Version: "1.0.0", InstallScripts: []string{"install"}}}},
+		{Name: "stable", Versions: []SnapshotVersion{{Version: "1.0.0", License: "ISC"}}},
+	}}
+
+	diff := current.Diff(old)
+
+	assert.Equal(t, []string{"native"}, diff.AddedPackages)
+	assert.Equal(t, []string{"gone"}, diff.RemovedPackages)
+	assert.Equal(t, []PackageVersion{
+		{Package: "lib", Version: "1.2.0"}, {Package: "lib", Version: "2.0.0"}, {Package: "native", Version: "1.0.0"},
+	}, diff.AddedVersions)
+	assert.Equal(t, []PackageVersion{{Package: "gone", Version: "1.0.0"}, {Package: "lib", Version: "1.0.0"}}, diff.RemovedVersions)
+	assert.Equal(t, []LicenseChange{{Package: "lib", Previous: "1.1.0", Version: "2.0.0", From: "MIT", To: "BUSL-1.1"}}, diff.LicenseChanges)
+	assert.Equal(t, []InstallScriptsChange{
+		{Package: "lib", Previous: "1.1.0", Version: "1.2.0", Scripts: []string{"postinstall"}},
+		{Package: "native", Version: "1.0.0", Scripts: []string{"install"}},
+	}, diff.InstallScripts)
+	assert.Equal(t, []MaintainersChange{
+		{Package: "lib", Previous: "1.1.0", Version: "2.0.0", Added: []string{"mallory"}, Removed: []string{"bob"}},
+	}, diff.MaintainersChanges)
+	assert.False(t, diff.IsEmpty())
+
+	assert.True(t, current.Diff(current).IsEmpty())
+}
@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tombstone records a package, or the versions of a range, removed from the local repository
+// so that the next runs never download it again, even as a dependency.
+type Tombstone struct {
+	Name string `json:"name"`
+	// Range is the range of the removed versions, empty when the whole package was removed.
+	Range     string    `json:"range,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	versions SemVerRange
+}
+
+// NewTombstone creates a compiled tombstone.
+func NewTombstone(name string, versions string, reason string, createdAt time.Time) (Tombstone, error) {
+	tombstone := Tombstone{Name: name, Range: versions, Reason: reason, CreatedAt: createdAt}
+	return tombstone, tombstone.Compile()
+}
+
+// Compile validates the name and compiles the range of a loaded tombstone.
+func (t *Tombstone) Compile() error {
+	if _, err := NewPackageName(t.Name); err != nil {
+		return err
+	}
+	if t.Range == "" {
+		return nil
+	}
+	versions, err := ParseSemVerRange(t.Range)
+	if err != nil {
+		return fmt.Errorf("invalid range %q of %s: %v", t.Range, t.Name, err)
+	}
+	t.versions = versions
+	return nil
+}
+
+// IsWholePackage returns true if the tombstone covers all the versions of the package.
+func (t Tombstone) IsWholePackage() bool {
+	return t.Range == ""
+}
+
+// Matches returns true if the tombstone covers a version of its package.
+func (t Tombstone) Matches(version NpmPackage) bool {
+	return version.Name == t.Name && (t.IsWholePackage() || t.versions.Matches(version.Version))
+}
+
+// String writes the tombstone as name or name@range.
+func (t Tombstone) String() string {
+	if t.IsWholePackage() {
+		return t.Name
+	}
+	return t.Name + "@" + t.Range
+}
+
+// decision returns the decision blocking a version, or the whole package when version is empty.
+func (t Tombstone) decision(name string, version string) PolicyDecision {
+	reason := "removed from the local repository on " + t.CreatedAt.Format(time.DateOnly)
+	if t.Reason != "" {
+		reason += ": " + t.Reason
+	}
+	return PolicyDecision{Package: name, Version: version, Action: PolicyDeny, Reason: reason, Tombstone: true}
+}
+
+// AddTombstone adds a tombstone to a list, replacing the tombstone of the same package and range.
+func AddTombstone(tombstones []Tombstone, tombstone Tombstone) []Tombstone {
+	for i, existing := range tombstones {
+		if existing.Name == tombstone.Name && existing.Range == tombstone.Range {
+			tombstones[i] = tombstone
+			return tombstones
+		}
+	}
+	return append(tombstones, tombstone)
+}
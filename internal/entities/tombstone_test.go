@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTombstone(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tombstone, err := NewTombstone("event-stream", ">=3.3.6 <4", "compromised", createdAt)
+	require.NoError(t, err)
+	assert.Equal(t, "event-stream@>=3.3.6 <4", tombstone.String())
+	assert.True(t, tombstone.Matches(NpmPackage{Name: "event-stream", Version: mustSemVer(t, "3.3.6")}))
+	assert.False(t, tombstone.Matches(NpmPackage{Name: "event-stream", Version: mustSemVer(t, "3.3.5")}))
+	assert.False(t, tombstone.Matches(NpmPackage{Name: "other", Version: mustSemVer(t, "3.3.6")}))
+
+	whole, err := NewTombstone("@scope/pkg", "", "", createdAt)
+	require.NoError(t, err)
+	assert.True(t, whole.IsWholePackage())
+	assert.True(t, whole.Matches(NpmPackage{Name: "@scope/pkg", Version: mustSemVer(t, "1.0.0")}))
+
+	_, err = NewTombstone("event-stream", "next", "", createdAt)
+	assert.ErrorContains(t, err, `invalid range "next"`)
+	_, err = NewTombstone("../escape", "", "", createdAt)
+	assert.Error(t, err)
+}
+
+func TestPackagePolicy_Tombstones(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	whole, err := NewTombstone("left-pad", "", "", createdAt)
+	require.NoError(t, err)
+	versions, err := NewTombstone("event-stream", "3.3.6", "compromised", createdAt)
+	require.NoError(t, err)
+	policy := PackagePolicy{
+		Rules:      []PolicyRule{{Action: PolicyAllow, Name: "*"}},
+		Tombstones: []Tombstone{whole, versions},
+	}
+	require.NoError(t, policy.Compile())
+	assert.False(t, policy.IsEmpty())
+
+	decision, decided := policy.EvaluateName("left-pad")
+	assert.True(t, decided)
+	assert.Equal(t, PolicyDecision{Package: "left-pad", Action: PolicyDeny, Reason: "removed from the local repository on 2024-06-01", Tombstone: true}, decision)
+
+	_, decided = policy.EvaluateName("event-stream")
+	assert.False(t, decided)
+
+	decision = policy.Evaluate(NpmPackage{Name: "event-stream", Version: mustSemVer(t, "3.3.6")})
+	assert.False(t, decision.Allowed())
+	assert.Equal(t, "removed from the local repository on 2024-06-01: compromised", decision.Reason)
+	_, violation := policy.LicenseViolation(NpmPackage{Name: "event-stream", Version: mustSemVer(t, "3.3.6")})
+	assert.False(t, violation)
+
+	decision = policy.Evaluate(NpmPackage{Name: "event-stream", Version: mustSemVer(t, "4.0.0")})
+	assert.Equal(t, 1, decision.Rule)
+}
+
+func TestAddTombstone(t *testing.T) {
+	first := Tombstone{Name: "a", Reason: "first"}
+	tombstones := AddTombstone(nil, first)
+	tombstones = AddTombstone(tombstones, Tombstone{Name: "a", Range: "1.0.0"})
+	tombstones = AddTombstone(tombstones, Tombstone{Name: "a", Reason: "second"})
+
+	assert.Equal(t, []Tombstone{{Name: "a", Reason: "second"}, {Name: "a", Range: "1.0.0"}}, tombstones)
+}
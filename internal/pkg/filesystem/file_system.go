@@ -71,6 +71,11 @@ type FileSystem interface {
 	NewWriter(file io.Writer) Writer
 	NewScanner(file io.Reader) Scanner
 	MultiWriter(writers ...io.Writer) io.Writer
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	ReadDir(name string) ([]os.DirEntry, error)
+	Remove(name string) error
+	RemoveAll(path string) error
 }
 
 type osFileSystem struct{}
@@ -114,3 +119,23 @@ func (fs *osFileSystem) NewScanner(file io.Reader) Scanner {
 func (fs *osFileSystem) MultiWriter(writers ...io.Writer) io.Writer {
 	return io.MultiWriter(writers...)
 }
+
+func (fs *osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (fs *osFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (fs *osFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (fs *osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (fs *osFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
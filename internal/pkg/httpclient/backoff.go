@@ -0,0 +1,71 @@
+package httpclient
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponential retry delays with jitter.
+type Backoff struct {
+	// Base is the delay before the first retry, doubled at each attempt.
+	Base time.Duration
+	// Max caps the computed delay (and the honoured Retry-After).
+	Max time.Duration
+}
+
+// NewBackoff creates a new Backoff.
+func NewBackoff(base, max time.Duration) Backoff {
+	return Backoff{Base: base, Max: max}
+}
+
+// Delay returns the time to wait after the given failed attempt (starting at 1).
+// A Retry-After sent by the server takes precedence over the computed delay.
+func (b Backoff) Delay(attempt int, err error) time.Duration {
+	if retryAfter, ok := RetryAfter(err); ok {
+		return b.cap(retryAfter)
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	delay = b.cap(delay)
+	if delay <= 0 {
+		return 0
+	}
+
+	// Equal jitter: half of the delay is fixed, the other half is random.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Wait sleeps for the delay of the given attempt, or until the context is done.
+func (b Backoff) Wait(ctx context.Context, attempt int, err error) error {
+	return Sleep(ctx, b.Delay(attempt, err))
+}
+
+func (b Backoff) cap(delay time.Duration) time.Duration {
+	if b.Max > 0 && delay > b.Max {
+		return b.Max
+	}
+	return delay
+}
+
+// Sleep waits for the given duration, returning early with the context error if it is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := NewBackoff(100*time.Millisecond, time.Second)
+
+	t.Run("The delay doubles at each attempt, with equal jitter", func(t *testing.T) {
+		for attempt, full := range map[int]time.Duration{0: 100 * time.Millisecond, 1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond} {
+			for i := 0; i < 20; i++ {
+				delay := backoff.Delay(attempt, errors.New("network error"))
+				assert.GreaterOrEqual(t, delay, full/2)
+				assert.LessOrEqual(t, delay, full)
+			}
+		}
+	})
+
+	t.Run("The delay is capped", func(t *testing.T) {
+		assert.LessOrEqual(t, backoff.Delay(20, errors.New("network error")), time.Second)
+	})
+
+	t.Run("Retry-After takes precedence and is capped", func(t *testing.T) {
+		assert.Equal(t, 500*time.Millisecond, backoff.Delay(1, &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 500 * time.Millisecond}))
+		assert.Equal(t, time.Second, backoff.Delay(1, &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}))
+	})
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+	assert.NoError(t, Sleep(context.Background(), 0))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "120", want: 2 * time.Minute},
+		{value: " 3 ", want: 3 * time.Second},
+		{value: "-5", want: 0},
+		{value: "Fri, 01 Mar 2024 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Fri, 01 Mar 2024 11:59:00 GMT", want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseRetryAfter(tt.value, now))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError is returned when a server answers with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	URL        string
+	// RetryAfter is the delay requested by the server through the Retry-After header.
+	RetryAfter time.Duration
+}
+
+// NewStatusError builds a StatusError from a response, parsing its Retry-After header.
+func NewStatusError(resp *http.Response) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+	if resp.Request != nil && resp.Request.URL != nil {
+		statusErr.URL = resp.Request.URL.String()
+	}
+	statusErr.RetryAfter = ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return statusErr
+}
+
+func (e *StatusError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d for %s", e.StatusCode, e.URL)
+}
+
+// IsThrottled returns true if the server asks the client to slow down.
+func (e *StatusError) IsThrottled() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// IsRetryable returns true if the request may succeed when sent again.
+func (e *StatusError) IsRetryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// IsRetryable returns true if the error is worth retrying.
+// Network errors are retried, status errors only for transient status codes.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.IsRetryable()
+	}
+	return true
+}
+
+// RetryAfter returns the delay requested by the server, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// ParseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
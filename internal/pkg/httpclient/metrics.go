@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/npmoffline/internal/pkg/metrics"
+)
+
+// instrumentedClient is a Client recording the requests it sends in metrics.
+type instrumentedClient struct {
+	client   Client
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// NewInstrumentedClient wraps a Client to count the requests sent, by method and status code,
+// and to measure the time waited for the response headers.
+func NewInstrumentedClient(client Client, registry *metrics.Registry) Client {
+	return &instrumentedClient{
+		client: client,
+		requests: registry.NewCounter("npm_offline_http_requests_total",
+			"HTTP requests sent to the registry, by method and status code.", "method", "code"),
+		duration: registry.NewHistogram("npm_offline_http_request_duration_seconds",
+			"Time waited for the response headers of the registry.", metrics.DefaultDurationBuckets, "method"),
+	}
+}
+
+// Do sends the request and records it.
+func (c *instrumentedClient) Do(ctx context.Context, method, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.client.Do(ctx, method, url, body, headers)
+	c.duration.Observe(time.Since(start).Seconds(), method)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	c.requests.Inc(method, code)
+	return resp, err
+}
+
+// StatusLabel returns the label of the outcome of a request for the metrics: the status code
+// of a status error, or "error" for any other failure.
+func StatusLabel(err error) string {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return strconv.Itoa(statusErr.StatusCode)
+	}
+	return "error"
+}
@@ -0,0 +1,262 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// throttlePause is the pause applied to a host throttling us without Retry-After.
+	throttlePause = time.Second
+	// recoverySteps is the number of successful requests needed to recover the full rate.
+	recoverySteps = 50
+)
+
+// RateLimitOptions configures the shared rate limiter.
+type RateLimitOptions struct {
+	// RequestsPerSecond is the maximum request rate per host (0 means no limit).
+	RequestsPerSecond float64
+	// MinRequestsPerSecond is the floor of the adaptive rate when the host pushes back.
+	MinRequestsPerSecond float64
+	// MaxConcurrentPerHost is the maximum number of in-flight requests per host (0 means no limit).
+	// It also caps the number of workers sending requests to the same registry at once.
+	MaxConcurrentPerHost int
+}
+
+// Validate checks that the rates and the concurrency are consistent.
+func (o RateLimitOptions) Validate() error {
+	if o.RequestsPerSecond < 0 || o.MinRequestsPerSecond < 0 || o.MaxConcurrentPerHost < 0 {
+		return fmt.Errorf("the request rates and the concurrency per host must not be negative")
+	}
+	if o.RequestsPerSecond > 0 && o.MinRequestsPerSecond > o.RequestsPerSecond {
+		return fmt.Errorf("the minimum request rate %g is above the request rate %g", o.MinRequestsPerSecond, o.RequestsPerSecond)
+	}
+	return nil
+}
+
+// DefaultRateLimitOptions returns the default rate limiting options.
+func DefaultRateLimitOptions() RateLimitOptions {
+	return RateLimitOptions{
+		RequestsPerSecond:    50,
+		MinRequestsPerSecond: 1,
+		MaxConcurrentPerHost: 32,
+	}
+}
+
+// rateLimitedClient is a Client sharing a per-host adaptive rate limit between all its callers.
+type rateLimitedClient struct {
+	client  Client
+	options RateLimitOptions
+
+	mutex sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// NewRateLimitedClient wraps a Client with an adaptive per-host rate limiter.
+// The request rate is halved when a host answers 429 or 503, at most once for the requests
+// sent before the last decrease, the host is paused for its Retry-After delay, and the rate
+// slowly recovers on successful responses.
+func NewRateLimitedClient(client Client, options RateLimitOptions) Client {
+	return &rateLimitedClient{
+		client:  client,
+		options: options,
+		hosts:   make(map[string]*hostLimiter),
+	}
+}
+
+// Do waits for the host limiter before sending the request.
+// The concurrency slot is released when the response body is closed.
+func (c *rateLimitedClient) Do(ctx context.Context, method, rawURL string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	limiter := c.getHostLimiter(rawURL)
+
+	if err := limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	sentAt := time.Now()
+	resp, err := c.client.Do(ctx, method, rawURL, body, headers)
+	if err != nil {
+		limiter.release()
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		limiter.throttle(sentAt, ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	} else if resp.StatusCode < 400 {
+		limiter.recover()
+	}
+
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: limiter.release}
+	return resp, nil
+}
+
+// getHostLimiter returns the limiter of the URL host, creating it if needed.
+func (c *rateLimitedClient) getHostLimiter(rawURL string) *hostLimiter {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	limiter, ok := c.hosts[host]
+	if !ok {
+		limiter = newHostLimiter(c.options)
+		c.hosts[host] = limiter
+	}
+	return limiter
+}
+
+// hostLimiter is a token bucket with an adaptive rate and a concurrency semaphore.
+type hostLimiter struct {
+	mutex       sync.Mutex
+	rate        float64
+	maxRate     float64
+	minRate     float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	// decreasedAt is the time of the last decrease of the rate.
+	decreasedAt time.Time
+	slots       chan struct{}
+}
+
+func newHostLimiter(options RateLimitOptions) *hostLimiter {
+	limiter := &hostLimiter{
+		rate:    options.RequestsPerSecond,
+		maxRate: options.RequestsPerSecond,
+		minRate: options.MinRequestsPerSecond,
+		tokens:  burst(options.RequestsPerSecond),
+		last:    time.Now(),
+	}
+	if limiter.minRate <= 0 || (limiter.maxRate > 0 && limiter.minRate > limiter.maxRate) {
+		limiter.minRate = limiter.maxRate
+	}
+	if options.MaxConcurrentPerHost > 0 {
+		limiter.slots = make(chan struct{}, options.MaxConcurrentPerHost)
+	}
+	return limiter
+}
+
+// acquire waits for a concurrency slot and a token.
+func (h *hostLimiter) acquire(ctx context.Context) error {
+	if h.slots != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case h.slots <- struct{}{}:
+		}
+	}
+	if err := h.waitToken(ctx); err != nil {
+		h.release()
+		return err
+	}
+	return nil
+}
+
+// release frees the concurrency slot taken by acquire.
+func (h *hostLimiter) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// waitToken blocks until the host is not paused and a token is available.
+func (h *hostLimiter) waitToken(ctx context.Context) error {
+	for {
+		h.mutex.Lock()
+		now := time.Now()
+		if now.Before(h.pausedUntil) {
+			wait := h.pausedUntil.Sub(now)
+			h.mutex.Unlock()
+			if err := Sleep(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		if h.maxRate <= 0 {
+			h.mutex.Unlock()
+			return nil
+		}
+
+		h.tokens += now.Sub(h.last).Seconds() * h.rate
+		if burst := burst(h.rate); h.tokens > burst {
+			h.tokens = burst
+		}
+		h.last = now
+		if h.tokens >= 1 {
+			h.tokens--
+			h.mutex.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - h.tokens) / h.rate * float64(time.Second))
+		h.mutex.Unlock()
+
+		if err := Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// burst returns the capacity of the bucket for a rate: one second of requests, and at least
+// one request so that rates below one request per second still let requests through.
+func burst(rate float64) float64 {
+	return max(1, rate)
+}
+
+// throttle pauses the host for the requested delay and halves the rate. The requests sent
+// before the last decrease were sent at the rate which was already halved: the responses of
+// the requests in flight at the same time only halve the rate once.
+func (h *hostLimiter) throttle(sentAt time.Time, retryAfter time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if retryAfter <= 0 {
+		retryAfter = throttlePause
+	}
+	now := time.Now()
+	if until := now.Add(retryAfter); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+	if h.maxRate > 0 && !sentAt.Before(h.decreasedAt) {
+		h.decreasedAt = now
+		h.rate /= 2
+		if h.rate < h.minRate {
+			h.rate = h.minRate
+		}
+		h.tokens = 0
+	}
+}
+
+// recover increases the rate after a successful response.
+func (h *hostLimiter) recover() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.maxRate <= 0 || h.rate >= h.maxRate {
+		return
+	}
+	h.rate += h.maxRate / recoverySteps
+	if h.rate > h.maxRate {
+		h.rate = h.maxRate
+	}
+}
+
+// releaseOnClose releases the concurrency slot once the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
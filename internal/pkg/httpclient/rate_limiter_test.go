@@ -0,0 +1,201 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func response(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+	for key, value := range headers {
+		resp.Header.Set(key, value)
+	}
+	return resp
+}
+
+func TestHostLimiter(t *testing.T) {
+	t.Run("Tokens are refilled at the request rate", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 10})
+		limiter.tokens = 0
+		limiter.last = time.Now().Add(-500 * time.Millisecond)
+
+		require.NoError(t, limiter.waitToken(context.Background()))
+
+		assert.InDelta(t, 4, limiter.tokens, 0.1)
+	})
+
+	t.Run("Tokens are capped at one second of requests", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 10})
+		limiter.last = time.Now().Add(-time.Hour)
+
+		require.NoError(t, limiter.waitToken(context.Background()))
+
+		assert.InDelta(t, 9, limiter.tokens, 0.01)
+	})
+
+	t.Run("A rate below one request per second still lets requests through", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 0.5, MinRequestsPerSecond: 0.5})
+		limiter.tokens = 0
+		limiter.last = time.Now().Add(-time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, limiter.waitToken(ctx))
+
+		assert.InDelta(t, 0, limiter.tokens, 0.01)
+	})
+
+	t.Run("A request waits for the next token", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 1})
+		limiter.tokens = 0
+		limiter.last = time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, limiter.waitToken(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("No rate means no wait", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{})
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, limiter.waitToken(context.Background()))
+		}
+	})
+
+	t.Run("The rate is halved down to the floor and recovers", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 8, MinRequestsPerSecond: 3})
+
+		limiter.throttle(time.Now(), time.Millisecond)
+		assert.Equal(t, 4.0, limiter.rate)
+		assert.Equal(t, 0.0, limiter.tokens)
+		limiter.throttle(time.Now(), time.Millisecond)
+		assert.Equal(t, 3.0, limiter.rate)
+
+		limiter.recover()
+		assert.InDelta(t, 3+8.0/recoverySteps, limiter.rate, 1e-9)
+		for i := 0; i < recoverySteps; i++ {
+			limiter.recover()
+		}
+		assert.Equal(t, 8.0, limiter.rate)
+	})
+}
+
+func TestHostLimiter_throttle(t *testing.T) {
+	t.Run("Requests in flight at the same time halve the rate once", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 64, MinRequestsPerSecond: 1})
+		sentAt := time.Now()
+
+		for i := 0; i < 5; i++ {
+			limiter.throttle(sentAt, time.Millisecond)
+		}
+
+		assert.Equal(t, 32.0, limiter.rate)
+	})
+
+	t.Run("A request sent after the decrease halves the rate again", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 64, MinRequestsPerSecond: 1})
+		limiter.throttle(time.Now(), time.Millisecond)
+
+		limiter.throttle(time.Now(), time.Millisecond)
+
+		assert.Equal(t, 16.0, limiter.rate)
+	})
+
+	t.Run("The pause is extended by every response", func(t *testing.T) {
+		limiter := newHostLimiter(RateLimitOptions{RequestsPerSecond: 64})
+		sentAt := time.Now()
+		limiter.throttle(sentAt, time.Second)
+
+		limiter.throttle(sentAt, 3*time.Second)
+
+		assert.WithinDuration(t, time.Now().Add(3*time.Second), limiter.pausedUntil, 100*time.Millisecond)
+	})
+}
+
+func TestRateLimitedClient(t *testing.T) {
+	const url = "https://registry.example.com/left-pad"
+
+	t.Run("A 429 pauses the host for its Retry-After delay", func(t *testing.T) {
+		mockClient := NewMockClient(t)
+		client := NewRateLimitedClient(mockClient, RateLimitOptions{RequestsPerSecond: 10, MinRequestsPerSecond: 1}).(*rateLimitedClient)
+		mockClient.On("Do", mock.Anything, "GET", url, nil, mock.Anything).
+			Return(response(http.StatusTooManyRequests, map[string]string{"Retry-After": "2"}), nil).Once()
+
+		resp, err := client.Do(context.Background(), "GET", url, nil, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		limiter := client.getHostLimiter(url)
+		assert.WithinDuration(t, time.Now().Add(2*time.Second), limiter.pausedUntil, 100*time.Millisecond)
+		assert.Equal(t, 5.0, limiter.rate)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = client.Do(ctx, "GET", url, nil, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("A 503 without Retry-After pauses the host for the default pause", func(t *testing.T) {
+		mockClient := NewMockClient(t)
+		client := NewRateLimitedClient(mockClient, RateLimitOptions{RequestsPerSecond: 10}).(*rateLimitedClient)
+		mockClient.On("Do", mock.Anything, "GET", url, nil, mock.Anything).
+			Return(response(http.StatusServiceUnavailable, nil), nil).Once()
+
+		resp, err := client.Do(context.Background(), "GET", url, nil, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.WithinDuration(t, time.Now().Add(throttlePause), client.getHostLimiter(url).pausedUntil, 100*time.Millisecond)
+	})
+
+	t.Run("The concurrency slot is released when the body is closed", func(t *testing.T) {
+		mockClient := NewMockClient(t)
+		client := NewRateLimitedClient(mockClient, RateLimitOptions{MaxConcurrentPerHost: 1})
+		mockClient.On("Do", mock.Anything, "GET", url, nil, mock.Anything).Return(response(http.StatusOK, nil), nil).Twice()
+
+		first, err := client.Do(context.Background(), "GET", url, nil, nil)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = client.Do(ctx, "GET", url, nil, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		require.NoError(t, first.Body.Close())
+		second, err := client.Do(context.Background(), "GET", url, nil, nil)
+		require.NoError(t, err)
+		second.Body.Close()
+	})
+}
+
+func TestRateLimitOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options RateLimitOptions
+		wantErr string
+	}{
+		{name: "Defaults", options: DefaultRateLimitOptions()},
+		{name: "No limit", options: RateLimitOptions{MinRequestsPerSecond: 1}},
+		{name: "Rate below one request per second", options: RateLimitOptions{RequestsPerSecond: 0.5, MinRequestsPerSecond: 0.5}},
+		{name: "Floor above the rate", options: RateLimitOptions{RequestsPerSecond: 0.5, MinRequestsPerSecond: 1}, wantErr: "minimum request rate 1 is above the request rate 0.5"},
+		{name: "Negative rate", options: RateLimitOptions{RequestsPerSecond: -1}, wantErr: "must not be negative"},
+		{name: "Negative concurrency", options: RateLimitOptions{MaxConcurrentPerHost: -1}, wantErr: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
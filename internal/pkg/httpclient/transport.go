@@ -0,0 +1,271 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Options configures the outbound networking of the HTTP client.
+type Options struct {
+	// HTTPSProxy and HTTPProxy are the proxy URLs used for https and http requests.
+	HTTPSProxy string
+	HTTPProxy  string
+	// NoProxy is a comma-separated list of hosts, domains or CIDR blocks that bypass the proxy.
+	NoProxy string
+
+	// CAFiles are PEM bundles appended to the system certificate pool.
+	CAFiles []string
+	// ClientCertFile and ClientKeyFile enable mutual TLS when both are set.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	// MaxConnsPerHost limits the number of connections per host (0 means no limit).
+	MaxConnsPerHost int
+	// MaxIdleConnsPerHost is the number of idle connections kept open per host, which should
+	// match the number of concurrent requests per host. 0 falls back to MaxConnsPerHost, or to
+	// defaultMaxIdleConnsPerHost when there is no limit.
+	MaxIdleConnsPerHost int
+}
+
+// defaultMaxIdleConnsPerHost replaces the default of 2 idle connections per host of net/http,
+// which makes the workers reconnect to the registry for most requests.
+const defaultMaxIdleConnsPerHost = 32
+
+// DefaultOptions returns the default options, reading the proxy settings from the environment.
+func DefaultOptions() Options {
+	return Options{
+		HTTPSProxy:            getEnvAny("HTTPS_PROXY", "https_proxy"),
+		HTTPProxy:             getEnvAny("HTTP_PROXY", "http_proxy"),
+		NoProxy:               getEnvAny("NO_PROXY", "no_proxy"),
+		ConnectTimeout:        30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxConnsPerHost:       0,
+	}
+}
+
+// NewHttpClientWithOptions creates a new instance of HttpClient using a transport built from the options.
+func NewHttpClientWithOptions(opts Options) (Client, error) {
+	transport, err := NewTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewHttpClient(&http.Client{Transport: transport}), nil
+}
+
+// NewTransport builds an http.Transport honouring the proxy, TLS and timeout options.
+func NewTransport(opts Options) (*http.Transport, error) {
+	proxy, err := newProxyFunc(opts.HTTPSProxy, opts.HTTPProxy, opts.NoProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(opts.CAFiles, opts.ClientCertFile, opts.ClientKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		MaxIdleConnsPerHost:   opts.idleConnsPerHost(),
+		ForceAttemptHTTP2:     true,
+	}, nil
+}
+
+// idleConnsPerHost returns the number of idle connections kept open per host.
+func (opts Options) idleConnsPerHost() int {
+	switch {
+	case opts.MaxIdleConnsPerHost > 0:
+		return opts.MaxIdleConnsPerHost
+	case opts.MaxConnsPerHost > 0:
+		return opts.MaxConnsPerHost
+	}
+	return defaultMaxIdleConnsPerHost
+}
+
+// newTLSConfig loads the extra CA bundles and the optional client certificate.
+func newTLSConfig(caFiles []string, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(caFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, caFile := range caFiles {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle %s: %v", caFile, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no valid certificate found in CA bundle %s", caFile)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("both client certificate and client key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// newProxyFunc returns the proxy selection function used by the transport.
+func newProxyFunc(httpsProxy, httpProxy, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	httpsURL, err := parseProxyURL(httpsProxy)
+	if err != nil {
+		return nil, err
+	}
+	httpURL, err := parseProxyURL(httpProxy)
+	if err != nil {
+		return nil, err
+	}
+	bypass := parseNoProxy(noProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL := httpURL
+		if req.URL.Scheme == "https" {
+			proxyURL = httpsURL
+		}
+		if proxyURL == nil || bypass.matches(req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// parseProxyURL parses a proxy URL, defaulting to the http scheme when none is given.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address %q", proxy)
+	}
+	return proxyURL, nil
+}
+
+// noProxyRule is a single entry of the NO_PROXY list.
+type noProxyRule struct {
+	domain string
+	port   string
+	ipNet  *net.IPNet
+	ip     net.IP
+}
+
+type noProxyRules struct {
+	all   bool
+	rules []noProxyRule
+}
+
+// parseNoProxy parses a NO_PROXY value using the same conventions as curl and Go.
+func parseNoProxy(noProxy string) noProxyRules {
+	var result noProxyRules
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			result.all = true
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			result.rules = append(result.rules, noProxyRule{ipNet: ipNet})
+			continue
+		}
+		host, port, err := net.SplitHostPort(entry)
+		if err != nil {
+			host, port = entry, ""
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			result.rules = append(result.rules, noProxyRule{ip: ip, port: port})
+			continue
+		}
+		result.rules = append(result.rules, noProxyRule{domain: strings.TrimPrefix(host, "*"), port: port})
+	}
+	return result
+}
+
+// matches returns true if the URL must be requested without proxy.
+func (n noProxyRules) matches(u *url.URL) bool {
+	if n.all {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, rule := range n.rules {
+		if rule.port != "" && rule.port != port {
+			continue
+		}
+		switch {
+		case rule.ipNet != nil:
+			if ip != nil && rule.ipNet.Contains(ip) {
+				return true
+			}
+		case rule.ip != nil:
+			if ip != nil && rule.ip.Equal(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(rule.domain, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getEnvAny returns the value of the first environment variable set.
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoProxyRules_matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		noProxy string
+		url     string
+		want    bool
+	}{
+		{name: "Empty list", noProxy: "", url: "https://registry.npmjs.org/left-pad", want: false},
+		{name: "Wildcard", noProxy: "*", url: "https://registry.npmjs.org/left-pad", want: true},
+		{name: "Localhost", noProxy: "", url: "http://localhost:4873/left-pad", want: true},
+		{name: "Loopback address", noProxy: "", url: "http://127.0.0.1:4873/left-pad", want: true},
+		{name: "Exact host", noProxy: "registry.example.com", url: "https://registry.example.com/left-pad", want: true},
+		{name: "Subdomain of a host", noProxy: "example.com", url: "https://registry.example.com/left-pad", want: true},
+		{name: "Leading dot", noProxy: ".example.com", url: "https://registry.example.com/left-pad", want: true},
+		{name: "Leading wildcard", noProxy: "*.example.com", url: "https://registry.example.com/left-pad", want: true},
+		{name: "Suffix which is not a domain", noProxy: "example.com", url: "https://badexample.com/left-pad", want: false},
+		{name: "Case and spaces", noProxy: " other.org , Example.COM ", url: "https://registry.example.com/left-pad", want: true},
+		{name: "Matching port", noProxy: "registry.example.com:8443", url: "https://registry.example.com:8443/left-pad", want: true},
+		{name: "Other port", noProxy: "registry.example.com:8443", url: "https://registry.example.com/left-pad", want: false},
+		{name: "IP address", noProxy: "10.0.0.5", url: "http://10.0.0.5/left-pad", want: true},
+		{name: "Other IP address", noProxy: "10.0.0.5", url: "http://10.0.0.6/left-pad", want: false},
+		{name: "CIDR block", noProxy: "10.0.0.0/8", url: "http://10.1.2.3/left-pad", want: true},
+		{name: "Outside of the CIDR block", noProxy: "10.0.0.0/8", url: "http://192.168.1.1/left-pad", want: false},
+		{name: "IPv6 address", noProxy: "[::2]:8080", url: "http://[::2]:8080/left-pad", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, parseNoProxy(tt.noProxy).matches(u))
+		})
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidFile, []byte("not a certificate"), 0o644))
+
+	tests := []struct {
+		name     string
+		caFiles  []string
+		certFile string
+		keyFile  string
+		wantErr  string
+	}{
+		{name: "No options"},
+		{name: "CA bundle", caFiles: []string{certFile}},
+		{name: "Client certificate", certFile: certFile, keyFile: keyFile},
+		{name: "Missing CA bundle", caFiles: []string{filepath.Join(dir, "missing.pem")}, wantErr: "failed to read CA bundle"},
+		{name: "Invalid CA bundle", caFiles: []string{invalidFile}, wantErr: "no valid certificate found in CA bundle"},
+		{name: "Client certificate without key", certFile: certFile, wantErr: "both client certificate and client key must be set"},
+		{name: "Client key without certificate", keyFile: keyFile, wantErr: "both client certificate and client key must be set"},
+		{name: "Invalid client certificate", certFile: invalidFile, keyFile: keyFile, wantErr: "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newTLSConfig(tt.caFiles, tt.certFile, tt.keyFile)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.caFiles) > 0, config.RootCAs != nil)
+			assert.Equal(t, tt.certFile != "", len(config.Certificates) == 1)
+		})
+	}
+}
+
+func TestNewTransport_idleConnections(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want int
+	}{
+		{name: "No limit", opts: Options{}, want: defaultMaxIdleConnsPerHost},
+		{name: "Connection limit", opts: Options{MaxConnsPerHost: 8}, want: 8},
+		{name: "Idle connections", opts: Options{MaxConnsPerHost: 8, MaxIdleConnsPerHost: 4}, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(tt.opts)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, transport.MaxIdleConnsPerHost)
+		})
+	}
+}
+
+// writeCertificate writes a self-signed certificate and its key as PEM files.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "registry.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
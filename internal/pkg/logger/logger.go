@@ -3,6 +3,7 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -78,6 +79,11 @@ type logger struct {
 
 // NewLogger creates and returns a new instance of logger.
 func NewLogger(level zapcore.Level, useColor bool) Logger {
+	return NewLoggerWithOutput(level, useColor, os.Stdout)
+}
+
+// NewLoggerWithOutput creates a logger writing its console output to the given writer.
+func NewLoggerWithOutput(level zapcore.Level, useColor bool, out io.Writer) Logger {
 	// Configuration for console output (with or without color depending on the flag)
 	consoleEncoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "time",  // Clé de la date
@@ -96,7 +102,7 @@ func NewLogger(level zapcore.Level, useColor bool) Logger {
 
 	consoleCore := zapcore.NewCore(
 		zapcore.NewConsoleEncoder(consoleEncoderConfig),
-		zapcore.AddSync(zapcore.Lock(os.Stdout)),
+		zapcore.Lock(zapcore.AddSync(out)),
 		level,
 	)
 
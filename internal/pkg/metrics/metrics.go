@@ -0,0 +1,237 @@
+// Package metrics records counters, gauges and histograms and exposes them in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the duration histograms.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds the metrics exposed by a process. It is safe for concurrent use.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []*metric
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// metric is a family of series sharing a name and label names.
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+// series holds the value of a metric for a set of label values.
+type series struct {
+	labelValues []string
+	value       float64
+	// counts holds the number of observations per bucket of a histogram, the last one being +Inf.
+	counts []uint64
+	count  uint64
+}
+
+// Counter is a value which only goes up.
+type Counter struct {
+	metric *metric
+}
+
+// Gauge is a value which goes up and down.
+type Gauge struct {
+	metric *metric
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	metric *metric
+}
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{metric: r.register(name, help, "counter", labels, nil)}
+}
+
+// NewGauge registers a gauge with the given label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{metric: r.register(name, help, "gauge", labels, nil)}
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds and label names.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{metric: r.register(name, help, "histogram", labels, sorted)}
+}
+
+func (r *Registry) register(name, help, kind string, labels []string, buckets []float64) *metric {
+	m := &metric{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.mutex.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mutex.Unlock()
+	return m
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative value to the counter.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.metric.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Set sets the gauge.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.metric.update(labelValues, func(s *series) { s.value = value })
+}
+
+// Add adds a value, possibly negative, to the gauge.
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	g.metric.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Observe records an observation in the histogram.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.metric.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.metric.buckets)+1)
+		}
+		index := sort.SearchFloat64s(h.metric.buckets, value)
+		s.counts[index]++
+		s.count++
+		s.value += value
+	})
+}
+
+// update applies a change to the series of the label values. Missing label values are empty,
+// extra ones are ignored.
+func (m *metric) update(labelValues []string, change func(s *series)) {
+	values := make([]string, len(m.labels))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		m.series[key] = s
+	}
+	change(s)
+}
+
+// Write writes the metrics in the Prometheus text exposition format, the series of each
+// metric sorted by label values.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := append([]*metric(nil), r.metrics...)
+	r.mutex.Unlock()
+
+	out := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(out)
+	}
+	return out.Flush()
+}
+
+// Handler serves the metrics of the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.Write(w)
+	})
+}
+
+func (m *metric) write(out *bufio.Writer) {
+	fmt.Fprintf(out, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(out, "# TYPE %s %s\n", m.name, m.kind)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != "histogram" {
+			fmt.Fprintf(out, "%s%s %s\n", m.name, m.formatLabels(s.labelValues, "", ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, count := range s.counts {
+			cumulative += count
+			bound := math.Inf(1)
+			if i < len(m.buckets) {
+				bound = m.buckets[i]
+			}
+			fmt.Fprintf(out, "%s_bucket%s %d\n", m.name, m.formatLabels(s.labelValues, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(out, "%s_sum%s %s\n", m.name, m.formatLabels(s.labelValues, "", ""), formatFloat(s.value))
+		fmt.Fprintf(out, "%s_count%s %d\n", m.name, m.formatLabels(s.labelValues, "", ""), s.count)
+	}
+}
+
+// formatLabels formats the labels of a series, with an optional extra label.
+func (m *metric) formatLabels(values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range m.labels {
+		pairs = append(pairs, name+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+escapeLabelValue(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
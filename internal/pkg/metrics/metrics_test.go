@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounter("http_requests_total", "Requests sent, by method and code.", "method", "code")
+	bytes := registry.NewCounter("bytes_total", "Bytes received.")
+	queue := registry.NewGauge("queue_depth", "Items waiting in a queue.", "stage")
+	duration := registry.NewHistogram("duration_seconds", "Time to fetch\na document.", []float64{1, 0.1})
+	registry.NewCounter("unused_total", `Never incremented \ empty.`)
+
+	requests.Inc("GET", "200")
+	requests.Add(2, "GET", "404")
+	requests.Inc("GET", "200")
+	requests.Add(-1, "GET", "200")
+	requests.Inc(`PO"ST`, "200")
+	bytes.Add(1.5)
+	queue.Set(5, "metadata")
+	queue.Add(-2, "metadata")
+	queue.Set(1, "download")
+	duration.Observe(0.05)
+	duration.Observe(0.5)
+	duration.Observe(2)
+
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+
+	assert.Equal(t, `# HELP http_requests_total Requests sent, by method and code.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 2
+http_requests_total{method="GET",code="404"} 2
+http_requests_total{method="PO\"ST",code="200"} 1
+# HELP bytes_total Bytes received.
+# TYPE bytes_total counter
+bytes_total 1.5
+# HELP queue_depth Items waiting in a queue.
+# TYPE queue_depth gauge
+queue_depth{stage="download"} 1
+queue_depth{stage="metadata"} 3
+# HELP duration_seconds Time to fetch\na document.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 2.55
+duration_seconds_count 3
+# HELP unused_total Never incremented \\ empty.
+# TYPE unused_total counter
+`, out.String())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("up", "Whether the process is up.").Set(1)
+	recorder := httptest.NewRecorder()
+
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP up Whether the process is up.\n# TYPE up gauge\nup 1\n", recorder.Body.String())
+}
@@ -0,0 +1,308 @@
+package repositories
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+)
+
+// advisoriesFileName is the file of the local repository storing the imported advisories.
+// Its name is not a valid package name, so it cannot collide with a package directory.
+const advisoriesFileName = "_advisories.json"
+
+// OSVImport holds the npm advisories read from an OSV database.
+type OSVImport struct {
+	Advisories []entities.Advisory
+	// Withdrawn are the identifiers of the withdrawn advisories.
+	Withdrawn []string
+}
+
+// AdvisoryRepository reads advisory databases and stores the imported advisories.
+type AdvisoryRepository interface {
+	ReadOSV(path string) (OSVImport, error)
+	LoadAdvisories() (*entities.AdvisoryDatabase, error)
+	SaveAdvisories(db *entities.AdvisoryDatabase) error
+}
+
+// advisoryRepository implements AdvisoryRepository.
+type advisoryRepository struct {
+	npmDirPath string
+	fs         filesystem.FileSystem
+}
+
+// NewAdvisoryRepository creates a repository storing the advisories in the local repository baseDir.
+func NewAdvisoryRepository(baseDir string, fs filesystem.FileSystem) AdvisoryRepository {
+	return &advisoryRepository{npmDirPath: baseDir, fs: fs}
+}
+
+// osvAdvisory is an advisory in the OSV format, used by the GitHub Advisory Database and by
+// the dumps of osv.dev.
+type osvAdvisory struct {
+	ID        string    `json:"id"`
+	Modified  time.Time `json:"modified"`
+	Withdrawn string    `json:"withdrawn"`
+	Aliases   []string  `json:"aliases"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details"`
+	Affected  []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string     `json:"type"`
+			Events []osvEvent `json:"events"`
+		} `json:"ranges"`
+		Versions []string `json:"versions"`
+	} `json:"affected"`
+	References []struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"references"`
+	DatabaseSpecific struct {
+		Severity string   `json:"severity"`
+		CWEIDs   []string `json:"cwe_ids"`
+	} `json:"database_specific"`
+}
+
+// osvEvent is an event of an OSV range: each event sets one of its fields.
+type osvEvent struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+}
+
+// ReadOSV reads the npm advisories of an OSV database: a JSON file holding an advisory or an
+// array of advisories, a zip archive of JSON files such as the npm dump of osv.dev, or a
+// directory of JSON files such as a clone of the GitHub Advisory Database.
+func (r *advisoryRepository) ReadOSV(path string) (OSVImport, error) {
+	var result OSVImport
+	add := func(name string, data []byte) error {
+		advisories, err := decodeOSV(data)
+		if err != nil {
+			return fmt.Errorf("invalid advisory %s: %v", name, err)
+		}
+		for _, advisory := range advisories {
+			if advisory.Withdrawn != "" {
+				result.Withdrawn = append(result.Withdrawn, advisory.ID)
+				continue
+			}
+			result.Advisories = append(result.Advisories, advisory.toAdvisories()...)
+		}
+		return nil
+	}
+
+	if strings.HasSuffix(path, ".zip") {
+		data, err := r.fs.ReadFile(path)
+		if err != nil {
+			return result, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return result, fmt.Errorf("invalid zip archive %s: %v", path, err)
+		}
+		for _, file := range archive.File {
+			if !strings.HasSuffix(file.Name, ".json") {
+				continue
+			}
+			content, err := readZipFile(file)
+			if err != nil {
+				return result, fmt.Errorf("failed to read %s in %s: %v", file.Name, path, err)
+			}
+			if err := add(file.Name, content); err != nil {
+				return result, err
+			}
+		}
+		return result, nil
+	}
+
+	return result, r.walkJSON(path, add)
+}
+
+// walkJSON calls add with the content of a JSON file, or of each JSON file of a directory tree.
+func (r *advisoryRepository) walkJSON(path string, add func(name string, data []byte) error) error {
+	entries, err := r.fs.ReadDir(path)
+	if err != nil {
+		// Not a directory: the path is a file.
+		data, readErr := r.fs.ReadFile(path)
+		if readErr != nil {
+			return fmt.Errorf("failed to read file %s: %v", path, readErr)
+		}
+		return add(path, data)
+	}
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		if entry.IsDir() {
+			if err := r.walkJSON(child, add); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := r.fs.ReadFile(child)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %v", child, err)
+		}
+		if err := add(child, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// decodeOSV decodes an advisory or an array of advisories.
+func decodeOSV(data []byte) ([]osvAdvisory, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var advisories []osvAdvisory
+		err := json.Unmarshal(data, &advisories)
+		return advisories, err
+	}
+	var advisory osvAdvisory
+	if err := json.Unmarshal(data, &advisory); err != nil {
+		return nil, err
+	}
+	return []osvAdvisory{advisory}, nil
+}
+
+// toAdvisories returns an advisory for each affected npm package. Without a severity in the
+// database specific fields, the advisory is moderate.
+func (a osvAdvisory) toAdvisories() []entities.Advisory {
+	severity := strings.ToLower(a.DatabaseSpecific.Severity)
+	if entities.SeverityLevel(severity) < 0 {
+		severity = "moderate"
+	}
+	summary := a.Summary
+	if summary == "" {
+		summary, _, _ = strings.Cut(a.Details, "\n")
+	}
+
+	var advisories []entities.Advisory
+	for _, affected := range a.Affected {
+		if !strings.EqualFold(affected.Package.Ecosystem, "npm") {
+			continue
+		}
+		var ranges []string
+		for _, osvRange := range affected.Ranges {
+			if osvRange.Type == "SEMVER" || osvRange.Type == "ECOSYSTEM" {
+				ranges = append(ranges, rangeFromEvents(osvRange.Events)...)
+			}
+		}
+		advisories = append(advisories, entities.Advisory{
+			ID:         a.ID,
+			Aliases:    a.Aliases,
+			Package:    affected.Package.Name,
+			Summary:    summary,
+			Severity:   severity,
+			URL:        a.url(),
+			CWEs:       a.DatabaseSpecific.CWEIDs,
+			Vulnerable: strings.Join(ranges, " || "),
+			Versions:   affected.Versions,
+			Modified:   a.Modified,
+		})
+	}
+	return advisories
+}
+
+// url returns the advisory reference, else the page of the advisory on GitHub or osv.dev.
+func (a osvAdvisory) url() string {
+	for _, reference := range a.References {
+		if reference.Type == "ADVISORY" && strings.Contains(reference.URL, a.ID) {
+			return reference.URL
+		}
+	}
+	if strings.HasPrefix(a.ID, "GHSA-") {
+		return "https://github.com/advisories/" + a.ID
+	}
+	return "https://osv.dev/vulnerability/" + a.ID
+}
+
+// rangeFromEvents converts the events of an OSV range to npm ranges: each introduced version
+// opens an interval which a fixed or last affected version closes.
+func rangeFromEvents(events []osvEvent) []string {
+	var ranges []string
+	lower, open := "", false
+	interval := func(upper string) string {
+		var bounds []string
+		if lower != "" && lower != "0" {
+			bounds = append(bounds, ">="+lower)
+		}
+		if upper != "" {
+			bounds = append(bounds, upper)
+		}
+		if len(bounds) == 0 {
+			return "*"
+		}
+		return strings.Join(bounds, " ")
+	}
+	for _, event := range events {
+		switch {
+		case event.Introduced != "":
+			lower, open = event.Introduced, true
+		case event.Fixed != "" && open:
+			ranges = append(ranges, interval("<"+event.Fixed))
+			open = false
+		case event.LastAffected != "" && open:
+			ranges = append(ranges, interval("<="+event.LastAffected))
+			open = false
+		}
+	}
+	if open {
+		ranges = append(ranges, interval(""))
+	}
+	return ranges
+}
+
+// LoadAdvisories loads the imported advisories. The database is empty if none were imported.
+func (r *advisoryRepository) LoadAdvisories() (*entities.AdvisoryDatabase, error) {
+	filePath := filepath.Join(r.npmDirPath, advisoriesFileName)
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entities.NewAdvisoryDatabase(nil), nil
+		}
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	var advisories []entities.Advisory
+	if err := json.Unmarshal(data, &advisories); err != nil {
+		return nil, fmt.Errorf("invalid advisories in %s: %v", filePath, err)
+	}
+	return entities.NewAdvisoryDatabase(advisories), nil
+}
+
+// SaveAdvisories stores the advisories in the local repository.
+func (r *advisoryRepository) SaveAdvisories(db *entities.AdvisoryDatabase) error {
+	if err := r.fs.MkdirAll(r.npmDirPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", r.npmDirPath, err)
+	}
+	data, err := json.Marshal(db.Advisories())
+	if err != nil {
+		return fmt.Errorf("failed to encode advisories: %v", err)
+	}
+	filePath := filepath.Join(r.npmDirPath, advisoriesFileName)
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lodashOSV = `{
+	"id": "GHSA-jf85-cpcp-j695",
+	"modified": "2024-01-02T03:04:05Z",
+	"aliases": ["CVE-2019-10744"],
+	"summary": "Prototype Pollution in lodash",
+	"affected": [
+		{
+			"package": {"ecosystem": "npm", "name": "lodash"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "4.17.12"}]}]
+		},
+		{
+			"package": {"ecosystem": "PyPI", "name": "lodash"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+		}
+	],
+	"references": [{"type": "WEB", "url": "https://example.com"}],
+	"database_specific": {"severity": "CRITICAL", "cwe_ids": ["CWE-1321"]}
+}`
+
+func TestReadOSV(t *testing.T) {
+	t.Run("Directory of advisories", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, filepath.Join(dir, "2019", "07"), map[string]string{"GHSA-jf85-cpcp-j695.json": lodashOSV})
+		writeFiles(t, filepath.Join(dir, "2020"), map[string]string{
+			"withdrawn.json": `{"id": "GHSA-xxxx-xxxx-xxxx", "withdrawn": "2020-01-01T00:00:00Z"}`,
+			"README.md":      "not an advisory",
+		})
+		repo := NewAdvisoryRepository(t.TempDir(), filesystem.NewOsFileSystem())
+
+		imported, err := repo.ReadOSV(dir)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GHSA-xxxx-xxxx-xxxx"}, imported.Withdrawn)
+		require.Len(t, imported.Advisories, 1)
+		advisory := imported.Advisories[0]
+		assert.Equal(t, "lodash", advisory.Package)
+		assert.Equal(t, "<4.17.12", advisory.Vulnerable)
+		assert.Equal(t, "critical", advisory.Severity)
+		assert.Equal(t, []string{"CWE-1321"}, advisory.CWEs)
+		assert.Equal(t, []string{"CVE-2019-10744"}, advisory.Aliases)
+		assert.Equal(t, "https://github.com/advisories/GHSA-jf85-cpcp-j695", advisory.URL)
+	})
+
+	t.Run("Zip archive", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "npm.zip")
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		archive := zip.NewWriter(file)
+		writer, err := archive.Create("GHSA-jf85-cpcp-j695.json")
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(lodashOSV))
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		require.NoError(t, file.Close())
+		repo := NewAdvisoryRepository(t.TempDir(), filesystem.NewOsFileSystem())
+
+		imported, err := repo.ReadOSV(path)
+
+		require.NoError(t, err)
+		require.Len(t, imported.Advisories, 1)
+		assert.Equal(t, "GHSA-jf85-cpcp-j695", imported.Advisories[0].ID)
+	})
+
+	t.Run("Array of advisories with several ranges", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"all.json": `[{
+			"id": "OSV-1",
+			"details": "First line\nSecond line",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "pkg"},
+				"ranges": [{"type": "SEMVER", "events": [
+					{"introduced": "1.0.0"}, {"fixed": "1.2.0"},
+					{"introduced": "2.0.0"}, {"last_affected": "2.1.0"},
+					{"introduced": "3.0.0"}
+				]}],
+				"versions": ["0.9.0"]
+			}]
+		}]`})
+		repo := NewAdvisoryRepository(t.TempDir(), filesystem.NewOsFileSystem())
+
+		imported, err := repo.ReadOSV(filepath.Join(dir, "all.json"))
+
+		require.NoError(t, err)
+		require.Len(t, imported.Advisories, 1)
+		advisory := imported.Advisories[0]
+		assert.Equal(t, ">=1.0.0 <1.2.0 || >=2.0.0 <=2.1.0 || >=3.0.0", advisory.Vulnerable)
+		assert.Equal(t, []string{"0.9.0"}, advisory.Versions)
+		assert.Equal(t, "moderate", advisory.Severity)
+		assert.Equal(t, "First line", advisory.Summary)
+		assert.Equal(t, "https://osv.dev/vulnerability/OSV-1", advisory.URL)
+	})
+
+	t.Run("Invalid advisory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"bad.json": "{"})
+		repo := NewAdvisoryRepository(t.TempDir(), filesystem.NewOsFileSystem())
+
+		_, err := repo.ReadOSV(dir)
+
+		assert.ErrorContains(t, err, "invalid advisory")
+	})
+}
+
+func TestSaveAndLoadAdvisories(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewAdvisoryRepository(baseDir, filesystem.NewOsFileSystem())
+
+	db, err := repo.LoadAdvisories()
+	require.NoError(t, err)
+	assert.Empty(t, db.Advisories())
+
+	db.Merge([]entities.Advisory{{ID: "GHSA-1", Package: "lodash", Severity: "high", Vulnerable: "<4.17.12"}}, nil)
+	require.NoError(t, repo.SaveAdvisories(db))
+
+	loaded, err := repo.LoadAdvisories()
+	require.NoError(t, err)
+	version, _ := entities.NewSemVer("4.17.11")
+	assert.Len(t, loaded.Affecting("lodash", version), 1)
+}
@@ -1,12 +1,20 @@
 package repositories
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,11 +27,41 @@ import (
 // and for managing the downloaded packages state.
 type LocalNpmRepository interface {
 	WriteTarball(packageName, version, integrity string, reader io.ReadCloser) error
+	WriteRemoteTarball(tarballURL string, reader io.ReadCloser) (entities.RemoteTarball, error)
 	WritePackageJSON(packageName string, reader io.ReadCloser) (io.ReadCloser, error)
 	LoadDownloadedPackagesState() ([]entities.RetrievePackage, time.Time, error)
 	SaveDownloadedPackagesState(packages []entities.RetrievePackage, lastSync time.Time) error
+	LoadCacheValidators(packageName string) (entities.CacheValidators, error)
+	SaveCacheValidators(packageName string, validators entities.CacheValidators) error
+	SaveDistTags(packageName string, tags entities.DistTags) error
+	UpdateDistTags(packageName string) (entities.DistTags, error)
+	ListPackages() ([]string, error)
+	LoadLocalVersions(packageName string) ([]entities.NpmPackage, error)
+	LoadTarballVersions(packageName string) ([]entities.SemVer, error)
+	PackageUsage(packageName string) (entities.PackageSummary, error)
+	InspectPackage(packageName string) (entities.PackageInfo, error)
+	LoadDependencyGraph() (entities.DependencyGraph, error)
+	SaveDependencyGraph(graph entities.DependencyGraph) error
+	PrunePackage(packageName string, versions []string, dryRun bool) (entities.PrunedPackage, error)
+	LoadTombstones() ([]entities.Tombstone, error)
+	SaveTombstones(tombstones []entities.Tombstone) error
 }
 
+const (
+	// cacheValidatorsFileName is the file storing the HTTP validators of the package.json.
+	cacheValidatorsFileName = ".cache.json"
+	// distTagsFileName is the file storing the dist-tags of the registry, since the dist-tags
+	// of the stored package.json point to local versions.
+	distTagsFileName = ".dist-tags.json"
+	// remoteTarballsDir is the directory of the tarballs downloaded from an URL.
+	// Its name is not a valid package name, so it cannot collide with a package directory.
+	remoteTarballsDir = "_remote"
+	// dependencyGraphFileName is the file storing the dependencies discovered by the runs.
+	dependencyGraphFileName = "_graph.json"
+	// tombstonesFileName is the file storing the packages removed from the local repository.
+	tombstonesFileName = "_tombstones.json"
+)
+
 // localNpmRepo implements LocalNpmRepository.
 type localNpmRepo struct {
 	npmDirPath       string
@@ -44,15 +82,21 @@ func NewLocalNpmRepository(baseDir string, fs filesystem.FileSystem, stateFilePa
 	}
 }
 
+// ErrIntegrityMismatch is returned by WriteTarball when the tarball does not match its integrity.
+var ErrIntegrityMismatch = errors.New("integrity hash does not match")
+
 // WriteTarball writes the tarball data to the appropriate directory.
 func (r *localNpmRepo) WriteTarball(packageName, version, integrity string, reader io.ReadCloser) error {
-	destDir := r.getPackageDirectory(packageName)
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
 	if err := r.fs.MkdirAll(destDir, os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create directory %s: %v", destDir, err)
 	}
 
-	// Use path.Base to handle scoped packages correctly.
-	filePath := filepath.Join(destDir, fmt.Sprintf("%s-%s.tgz", path.Base(packageName), version))
+	filePath := filepath.Join(destDir, name.TarballFileName(version))
 	file, err := r.fs.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %v", filePath, err)
@@ -69,12 +113,52 @@ func (r *localNpmRepo) WriteTarball(packageName, version, integrity string, read
 
 	// Calculate the integrity hash
 	if integrity != r.integrityChecker.GetSha512(hasher) {
-		return fmt.Errorf("integrity hash does not match")
+		return ErrIntegrityMismatch
 	}
 
 	return nil
 }
 
+// WriteRemoteTarball writes a tarball downloaded from an URL and computes its integrity.
+// The tarball is stored in the _remote directory, named after the SHA-256 of its URL, next to
+// a JSON file describing it.
+func (r *localNpmRepo) WriteRemoteTarball(tarballURL string, reader io.ReadCloser) (entities.RemoteTarball, error) {
+	destDir := filepath.Join(r.npmDirPath, remoteTarballsDir)
+	if err := r.fs.MkdirAll(destDir, os.ModePerm); err != nil {
+		return entities.RemoteTarball{}, fmt.Errorf("failed to create directory %s: %v", destDir, err)
+	}
+
+	sum := sha256.Sum256([]byte(tarballURL))
+	baseName := hex.EncodeToString(sum[:])
+	filePath := filepath.Join(destDir, baseName+".tgz")
+	file, err := r.fs.Create(filePath)
+	if err != nil {
+		return entities.RemoteTarball{}, fmt.Errorf("failed to create file %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	hasher := r.integrityChecker.NewHash()
+	mw := r.fs.MultiWriter(file, hasher)
+	if _, err := r.fs.Copy(mw, reader); err != nil {
+		return entities.RemoteTarball{}, fmt.Errorf("failed to write tarball data: %v", err)
+	}
+
+	tarball := entities.RemoteTarball{
+		URL:       tarballURL,
+		Integrity: r.integrityChecker.GetSha512(hasher),
+		Path:      path.Join(remoteTarballsDir, baseName+".tgz"),
+	}
+	data, err := json.Marshal(tarball)
+	if err != nil {
+		return entities.RemoteTarball{}, fmt.Errorf("failed to encode remote tarball: %v", err)
+	}
+	infoPath := filepath.Join(destDir, baseName+".json")
+	if err := r.fs.WriteFile(infoPath, data, 0644); err != nil {
+		return entities.RemoteTarball{}, fmt.Errorf("failed to write file %s: %v", infoPath, err)
+	}
+	return tarball, nil
+}
+
 // teeReadCloser wraps a TeeReader and a WriteCloser to implement io.ReadCloser.
 type teeReadCloser struct {
 	tee io.Reader
@@ -92,7 +176,10 @@ func (t *teeReadCloser) Close() error {
 // WritePackageJSON writes the package.json file to the package directory.
 // It returns an io.ReadCloser that provides the read data while writing it to disk.
 func (r *localNpmRepo) WritePackageJSON(packageName string, reader io.ReadCloser) (io.ReadCloser, error) {
-	destDir := r.getPackageDirectory(packageName)
+	destDir, err := r.getPackageDirectory(packageName)
+	if err != nil {
+		return nil, err
+	}
 	if err := r.fs.MkdirAll(destDir, os.ModePerm); err != nil {
 		return nil, fmt.Errorf("failed to create directory %s: %v", destDir, err)
 	}
@@ -183,8 +270,651 @@ func (r *localNpmRepo) SaveDownloadedPackagesState(packages []entities.RetrieveP
 	return nil
 }
 
+// LoadCacheValidators loads the HTTP validators of the stored package.json.
+// Empty validators are returned if none were saved.
+func (r *localNpmRepo) LoadCacheValidators(packageName string) (entities.CacheValidators, error) {
+	destDir, err := r.getPackageDirectory(packageName)
+	if err != nil {
+		return entities.CacheValidators{}, err
+	}
+	filePath := filepath.Join(destDir, cacheValidatorsFileName)
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entities.CacheValidators{}, nil
+		}
+		return entities.CacheValidators{}, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	var validators entities.CacheValidators
+	if err := json.Unmarshal(data, &validators); err != nil {
+		return entities.CacheValidators{}, fmt.Errorf("invalid cache validators in %s: %v", filePath, err)
+	}
+	return validators, nil
+}
+
+// SaveCacheValidators saves the HTTP validators of the stored package.json.
+// Saving empty validators invalidates the cache of the package.
+func (r *localNpmRepo) SaveCacheValidators(packageName string, validators entities.CacheValidators) error {
+	destDir, err := r.getPackageDirectory(packageName)
+	if err != nil {
+		return err
+	}
+	if err := r.fs.MkdirAll(destDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", destDir, err)
+	}
+
+	data, err := json.Marshal(validators)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache validators: %v", err)
+	}
+
+	filePath := filepath.Join(destDir, cacheValidatorsFileName)
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// LoadDependencyGraph loads the dependencies discovered by the previous runs. An empty graph
+// is returned if none was saved.
+func (r *localNpmRepo) LoadDependencyGraph() (entities.DependencyGraph, error) {
+	filePath := filepath.Join(r.npmDirPath, dependencyGraphFileName)
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entities.DependencyGraph{}, nil
+		}
+		return entities.DependencyGraph{}, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	var graph entities.DependencyGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return entities.DependencyGraph{}, fmt.Errorf("invalid dependency graph in %s: %v", filePath, err)
+	}
+	return graph, nil
+}
+
+// SaveDependencyGraph saves the dependencies discovered by the runs.
+func (r *localNpmRepo) SaveDependencyGraph(graph entities.DependencyGraph) error {
+	if err := r.fs.MkdirAll(r.npmDirPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", r.npmDirPath, err)
+	}
+
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return fmt.Errorf("failed to encode dependency graph: %v", err)
+	}
+
+	filePath := filepath.Join(r.npmDirPath, dependencyGraphFileName)
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// LoadTombstones loads the packages and versions removed from the local repository, compiled.
+// No tombstone is returned if none was saved.
+func (r *localNpmRepo) LoadTombstones() ([]entities.Tombstone, error) {
+	filePath := filepath.Join(r.npmDirPath, tombstonesFileName)
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+
+	var tombstones []entities.Tombstone
+	if err := json.Unmarshal(data, &tombstones); err != nil {
+		return nil, fmt.Errorf("invalid tombstones in %s: %v", filePath, err)
+	}
+	for i := range tombstones {
+		if err := tombstones[i].Compile(); err != nil {
+			return nil, fmt.Errorf("invalid tombstone in %s: %v", filePath, err)
+		}
+	}
+	return tombstones, nil
+}
+
+// SaveTombstones saves the packages and versions removed from the local repository.
+func (r *localNpmRepo) SaveTombstones(tombstones []entities.Tombstone) error {
+	if err := r.fs.MkdirAll(r.npmDirPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", r.npmDirPath, err)
+	}
+
+	data, err := json.MarshalIndent(tombstones, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tombstones: %v", err)
+	}
+
+	filePath := filepath.Join(r.npmDirPath, tombstonesFileName)
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// SaveDistTags saves the dist-tags of the registry, to rewrite the stored package.json later.
+func (r *localNpmRepo) SaveDistTags(packageName string, tags entities.DistTags) error {
+	destDir, err := r.getPackageDirectory(packageName)
+	if err != nil {
+		return err
+	}
+	if err := r.fs.MkdirAll(destDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", destDir, err)
+	}
+
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode dist-tags: %v", err)
+	}
+
+	filePath := filepath.Join(destDir, distTagsFileName)
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+// UpdateDistTags points the dist-tags of the stored package.json to the tarballs available
+// locally, so that a registry serving it never resolves a tag to a missing version.
+// The saved dist-tags of the registry are used, else those of the package.json.
+// It returns the dist-tags written, or nil when the package has no package.json or no tarball.
+func (r *localNpmRepo) UpdateDistTags(packageName string) (entities.DistTags, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return nil, err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
+
+	filePath := filepath.Join(destDir, "package.json")
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	var packument map[string]json.RawMessage
+	if err := json.Unmarshal(data, &packument); err != nil {
+		return nil, fmt.Errorf("invalid package.json %s: %v", filePath, err)
+	}
+
+	upstream, err := r.upstreamDistTags(destDir, packument)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := r.localVersions(name, destDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(local) == 0 {
+		return nil, nil
+	}
+
+	tags := upstream.ForLocalVersions(local)
+	if packument["dist-tags"], err = marshalPackument(tags); err != nil {
+		return nil, fmt.Errorf("failed to encode dist-tags: %v", err)
+	}
+	if data, err = marshalPackument(packument); err != nil {
+		return nil, fmt.Errorf("failed to encode package.json: %v", err)
+	}
+	if err := r.fs.WriteFile(filePath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file %s: %v", filePath, err)
+	}
+	return tags, nil
+}
+
+// marshalPackument encodes a value of a package.json. Unlike json.Marshal, the "<", ">" and "&"
+// of the readmes and descriptions are kept as is rather than escaped.
+func marshalPackument(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// upstreamDistTags returns the saved dist-tags of the registry, else those of the package.json.
+func (r *localNpmRepo) upstreamDistTags(destDir string, packument map[string]json.RawMessage) (entities.DistTags, error) {
+	var upstream entities.DistTags
+	tagsPath := filepath.Join(destDir, distTagsFileName)
+	tagsData, err := r.fs.ReadFile(tagsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(tagsData, &upstream); err != nil {
+			return nil, fmt.Errorf("invalid dist-tags in %s: %v", tagsPath, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if raw, ok := packument["dist-tags"]; ok {
+			if err := json.Unmarshal(raw, &upstream); err != nil {
+				return nil, fmt.Errorf("invalid dist-tags in %s: %v", filepath.Join(destDir, "package.json"), err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("failed to read file %s: %v", tagsPath, err)
+	}
+	return upstream, nil
+}
+
+// PrunePackage removes versions of a package: their tarballs are deleted, and they are removed
+// from the "versions" and "time" of the stored package.json, whose dist-tags are pointed to the
+// remaining tarballs. When no tarball is left, the package directory is deleted. With dryRun,
+// nothing is written and the returned result tells what would be done.
+func (r *localNpmRepo) PrunePackage(packageName string, versions []string, dryRun bool) (entities.PrunedPackage, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return entities.PrunedPackage{}, err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
+	result := entities.PrunedPackage{Name: packageName, Removed: []string{}}
+
+	entries, err := r.fs.ReadDir(destDir)
+	if err != nil {
+		return entities.PrunedPackage{}, fmt.Errorf("failed to read directory %s: %v", destDir, err)
+	}
+	files := map[string]os.DirEntry{}
+	for _, entry := range entries {
+		files[entry.Name()] = entry
+	}
+	removed := map[string]bool{}
+	var tarballs []string
+	for _, version := range versions {
+		removed[version] = true
+		result.Removed = append(result.Removed, version)
+		entry, ok := files[name.TarballFileName(version)]
+		if !ok {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return entities.PrunedPackage{}, fmt.Errorf("failed to stat file %s: %v", filepath.Join(destDir, entry.Name()), err)
+		}
+		result.Freed += stat.Size()
+		tarballs = append(tarballs, filepath.Join(destDir, entry.Name()))
+	}
+
+	local, err := r.localVersions(name, destDir)
+	if err != nil {
+		return entities.PrunedPackage{}, err
+	}
+	var kept []entities.SemVer
+	for _, version := range local {
+		if !removed[version.String()] {
+			kept = append(kept, version)
+		}
+	}
+	result.Kept = len(kept)
+
+	if len(kept) == 0 {
+		size, err := r.directorySize(destDir)
+		if err != nil {
+			return entities.PrunedPackage{}, err
+		}
+		result.Freed, result.Deleted = size, true
+		if !dryRun {
+			if err := r.fs.RemoveAll(destDir); err != nil {
+				return entities.PrunedPackage{}, fmt.Errorf("failed to remove directory %s: %v", destDir, err)
+			}
+		}
+		return result, nil
+	}
+
+	filePath := filepath.Join(destDir, "package.json")
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return entities.PrunedPackage{}, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	var updated []byte
+	if err == nil {
+		if updated, err = r.removeVersionsFromPackument(destDir, data, removed, kept); err != nil {
+			return entities.PrunedPackage{}, err
+		}
+		result.Freed += int64(len(data) - len(updated))
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	for _, tarball := range tarballs {
+		if err := r.fs.Remove(tarball); err != nil {
+			return entities.PrunedPackage{}, fmt.Errorf("failed to remove file %s: %v", tarball, err)
+		}
+	}
+	if updated != nil {
+		if err := r.fs.WriteFile(filePath, updated, 0644); err != nil {
+			return entities.PrunedPackage{}, fmt.Errorf("failed to write file %s: %v", filePath, err)
+		}
+	}
+	return result, nil
+}
+
+// removeVersionsFromPackument removes versions from the "versions" and "time" of a package.json
+// and points its dist-tags to the kept versions.
+func (r *localNpmRepo) removeVersionsFromPackument(destDir string, data []byte, removed map[string]bool, kept []entities.SemVer) ([]byte, error) {
+	filePath := filepath.Join(destDir, "package.json")
+	var packument map[string]json.RawMessage
+	if err := json.Unmarshal(data, &packument); err != nil {
+		return nil, fmt.Errorf("invalid package.json %s: %v", filePath, err)
+	}
+	for _, field := range []string{"versions", "time"} {
+		raw, ok := packument[field]
+		if !ok {
+			continue
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %v", field, filePath, err)
+		}
+		for version := range removed {
+			delete(values, version)
+		}
+		var err error
+		if packument[field], err = marshalPackument(values); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %v", field, err)
+		}
+	}
+
+	upstream, err := r.upstreamDistTags(destDir, packument)
+	if err != nil {
+		return nil, err
+	}
+	if packument["dist-tags"], err = marshalPackument(upstream.ForLocalVersions(kept)); err != nil {
+		return nil, fmt.Errorf("failed to encode dist-tags: %v", err)
+	}
+	updated, err := marshalPackument(packument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode package.json: %v", err)
+	}
+	return updated, nil
+}
+
+// ListPackages returns the names of the packages stored in the local repository, sorted.
+// Scoped packages are found in the directory of their scope.
+func (r *localNpmRepo) ListPackages() ([]string, error) {
+	entries, err := r.fs.ReadDir(r.npmDirPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read directory %s: %v", r.npmDirPath, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if !strings.HasPrefix(entry.Name(), "@") {
+			names = append(names, entry.Name())
+			continue
+		}
+		scopeDir := filepath.Join(r.npmDirPath, entry.Name())
+		scoped, err := r.fs.ReadDir(scopeDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %v", scopeDir, err)
+		}
+		for _, child := range scoped {
+			if child.IsDir() {
+				names = append(names, entry.Name()+"/"+child.Name())
+			}
+		}
+	}
+
+	// Other directories, such as _remote, are not package directories.
+	var packages []string
+	for _, name := range names {
+		if _, err := entities.NewPackageName(name); err == nil {
+			packages = append(packages, name)
+		}
+	}
+	sort.Strings(packages)
+	return packages, nil
+}
+
+// LoadLocalVersions returns the versions of a package whose tarball is stored locally, as
+// described by the stored package.json. It returns nil when the package has no package.json.
+func (r *localNpmRepo) LoadLocalVersions(packageName string) ([]entities.NpmPackage, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return nil, err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
+
+	filePath := filepath.Join(destDir, "package.json")
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	metadata, err := decodeNpmResponse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid package.json %s: %v", filePath, err)
+	}
+	packages := metadata.toNpmPackages()
+
+	local, err := r.localVersions(name, destDir)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[entities.SemVer]bool{}
+	for _, version := range local {
+		stored[version] = true
+	}
+	var versions []entities.NpmPackage
+	for _, pkg := range packages {
+		if stored[pkg.Version] {
+			versions = append(versions, pkg)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version.Compare(versions[j].Version) < 0 })
+	return versions, nil
+}
+
+// LoadTarballVersions returns the versions of a package whose tarball is stored locally, sorted,
+// without reading the package.json. It returns nil when the package has no directory.
+func (r *localNpmRepo) LoadTarballVersions(packageName string) ([]entities.SemVer, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := r.localVersions(name, filepath.Join(r.npmDirPath, name.DirPath()))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+	return versions, nil
+}
+
+// PackageUsage returns the number of stored tarballs of a package and the disk usage of its directory.
+func (r *localNpmRepo) PackageUsage(packageName string) (entities.PackageSummary, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return entities.PackageSummary{}, err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
+	versions, err := r.localVersions(name, destDir)
+	if err != nil {
+		return entities.PackageSummary{}, err
+	}
+	size, err := r.directorySize(destDir)
+	if err != nil {
+		return entities.PackageSummary{}, err
+	}
+	return entities.PackageSummary{Name: packageName, Versions: len(versions), Size: size}, nil
+}
+
+// directorySize returns the size of the files of a directory tree.
+func (r *localNpmRepo) directorySize(dir string) (int64, error) {
+	entries, err := r.fs.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read directory %s: %v", dir, err)
+	}
+	var size int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			child, err := r.directorySize(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return 0, err
+			}
+			size += child
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat file %s: %v", filepath.Join(dir, entry.Name()), err)
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// InspectPackage describes a package of the local repository: the versions whose tarball is
+// stored, verified against the hashes of the stored package.json, the versions of the
+// package.json without a tarball, and the dist-tags. The download state is not read.
+func (r *localNpmRepo) InspectPackage(packageName string) (entities.PackageInfo, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return entities.PackageInfo{}, err
+	}
+	destDir := filepath.Join(r.npmDirPath, name.DirPath())
+
+	filePath := filepath.Join(destDir, "package.json")
+	data, err := r.fs.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entities.PackageInfo{}, fmt.Errorf("package %s is not in the local repository: %w", packageName, err)
+		}
+		return entities.PackageInfo{}, fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	metadata, err := decodeNpmResponse(bytes.NewReader(data))
+	if err != nil {
+		return entities.PackageInfo{}, fmt.Errorf("invalid package.json %s: %v", filePath, err)
+	}
+	packages := metadata.toNpmPackages()
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Version.Compare(packages[j].Version) < 0 })
+
+	entries, err := r.fs.ReadDir(destDir)
+	if err != nil {
+		return entities.PackageInfo{}, fmt.Errorf("failed to read directory %s: %v", destDir, err)
+	}
+	files := map[string]os.DirEntry{}
+	for _, entry := range entries {
+		files[entry.Name()] = entry
+	}
+
+	info := entities.PackageInfo{Name: packageName, DistTags: metadata.DistTags, Missing: []string{}}
+	for _, pkg := range packages {
+		version := pkg.Version.String()
+		entry, ok := files[name.TarballFileName(version)]
+		if !ok {
+			info.Missing = append(info.Missing, version)
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return entities.PackageInfo{}, fmt.Errorf("failed to stat file %s: %v", filepath.Join(destDir, entry.Name()), err)
+		}
+		status, err := r.verifyTarball(filepath.Join(destDir, entry.Name()), pkg.Integrity, pkg.Shasum)
+		if err != nil {
+			return entities.PackageInfo{}, err
+		}
+		info.Versions = append(info.Versions, entities.LocalVersionInfo{
+			Version:      version,
+			Size:         stat.Size(),
+			Integrity:    pkg.Integrity,
+			Status:       status,
+			DownloadedAt: stat.ModTime().UTC(),
+			Deprecated:   pkg.Deprecated,
+		})
+	}
+
+	tagsPath := filepath.Join(destDir, distTagsFileName)
+	tagsData, err := r.fs.ReadFile(tagsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(tagsData, &info.RegistryDistTags); err != nil {
+			return entities.PackageInfo{}, fmt.Errorf("invalid dist-tags in %s: %v", tagsPath, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return entities.PackageInfo{}, fmt.Errorf("failed to read file %s: %v", tagsPath, err)
+	}
+	return info, nil
+}
+
+// tarballHashes create the hashes of the algorithms found in the integrity of the packuments.
+var tarballHashes = map[string]func() hash.Hash{
+	"SHA-1":   sha1.New,
+	"SHA-256": sha256.New,
+	"SHA-384": sha512.New384,
+	"SHA-512": sha512.New,
+}
+
+// verifyTarball checks a stored tarball against the hashes of its integrity and shasum.
+func (r *localNpmRepo) verifyTarball(filePath string, integrity string, shasum string) (entities.IntegrityStatus, error) {
+	expected := entities.IntegrityHashes(integrity, shasum)
+	if len(expected) == 0 {
+		return entities.IntegrityUnknown, nil
+	}
+	file, err := r.fs.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	hashers := make([]hash.Hash, len(expected))
+	writers := make([]io.Writer, len(expected))
+	for i, h := range expected {
+		hashers[i] = tarballHashes[h.Algorithm]()
+		writers[i] = hashers[i]
+	}
+	if _, err := r.fs.Copy(r.fs.MultiWriter(writers...), file); err != nil {
+		return "", fmt.Errorf("failed to read file %s: %v", filePath, err)
+	}
+	for i, h := range expected {
+		if hex.EncodeToString(hashers[i].Sum(nil)) != h.Value {
+			return entities.IntegrityMismatch, nil
+		}
+	}
+	return entities.IntegrityOK, nil
+}
+
+// localVersions returns the versions whose tarball is stored in the package directory.
+func (r *localNpmRepo) localVersions(name entities.PackageName, dir string) ([]entities.SemVer, error) {
+	entries, err := r.fs.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	prefix, suffix := name.Name+"-", ".tgz"
+	var versions []entities.SemVer
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(fileName, prefix) || !strings.HasSuffix(fileName, suffix) {
+			continue
+		}
+		version, err := entities.NewSemVer(strings.TrimSuffix(strings.TrimPrefix(fileName, prefix), suffix))
+		if err != nil {
+			continue // Not a tarball of this package, e.g. "left-pad-extra-1.0.0.tgz" for "left".
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
 // getPackageDirectory returns the directory path for the given package.
-// It replaces slashes in package names to create nested directories.
-func (r *localNpmRepo) getPackageDirectory(packageName string) string {
-	return filepath.Join(r.npmDirPath, filepath.FromSlash(packageName))
+// Scoped packages are stored in a nested directory named after their scope.
+func (r *localNpmRepo) getPackageDirectory(packageName string) (string, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(r.npmDirPath, name.DirPath()), nil
 }
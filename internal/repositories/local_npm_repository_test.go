@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -89,7 +91,7 @@ func TestWriteTarball(t *testing.T) {
 	destDir := filepath.Join("base", filepath.FromSlash(packageName))
 	filePath := filepath.Join(destDir, fmt.Sprintf("%s-%s.tgz", path.Base(packageName), version))
 
-	mockFile := os.NewFile(0, "test-file")
+	mockFile := newTestFile(t)
 	mockFS := filesystem.NewMockFileSystem(t)
 
 	t.Run("MkdirAll fails", func(t *testing.T) {
@@ -172,6 +174,47 @@ func TestWriteTarball(t *testing.T) {
 	})
 }
 
+func TestWriteRemoteTarball(t *testing.T) {
+	tarballURL := "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz"
+	readerContent := "tarball data"
+	destDir := filepath.Join("base", "_remote")
+	sum := sha256.Sum256([]byte(tarballURL))
+	baseName := hex.EncodeToString(sum[:])
+
+	mockFS := filesystem.NewMockFileSystem(t)
+
+	t.Run("Create fails", func(t *testing.T) {
+		repo := NewLocalNpmRepository("base", mockFS, "state.txt")
+		reader := io.NopCloser(strings.NewReader(readerContent))
+
+		mockFS.On("MkdirAll", destDir, os.ModePerm).Return(nil).Once()
+		mockFS.On("Create", filepath.Join(destDir, baseName+".tgz")).Return(nil, fmt.Errorf("create error")).Once()
+
+		_, err := repo.WriteRemoteTarball(tarballURL, reader)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create file")
+		mockFS.AssertExpectations(t)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		baseDir := t.TempDir()
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+		sha := sha512.Sum512([]byte(readerContent))
+		expectedIntegrity := "sha512-" + base64.StdEncoding.EncodeToString(sha[:])
+
+		tarball, err := repo.WriteRemoteTarball(tarballURL, io.NopCloser(strings.NewReader(readerContent)))
+
+		require.NoError(t, err)
+		assert.Equal(t, entities.RemoteTarball{URL: tarballURL, Integrity: expectedIntegrity, Path: "_remote/" + baseName + ".tgz"}, tarball)
+		content, err := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(tarball.Path)))
+		require.NoError(t, err)
+		assert.Equal(t, readerContent, string(content))
+		info, err := os.ReadFile(filepath.Join(baseDir, "_remote", baseName+".json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(info), expectedIntegrity)
+	})
+}
+
 func TestWritePackageJSON(t *testing.T) {
 	packageName := "react"
 	jsonContent := `{"name": "react"}`
@@ -180,7 +223,7 @@ func TestWritePackageJSON(t *testing.T) {
 	destDir := filepath.Join("base", filepath.FromSlash(packageName))
 	filePath := filepath.Join(destDir, "package.json")
 
-	mockFile := os.NewFile(0, "test-file")
+	mockFile := newTestFile(t)
 	mockFS := filesystem.NewMockFileSystem(t)
 
 	t.Run("MkdirAll fails", func(t *testing.T) {
@@ -226,7 +269,7 @@ func TestWritePackageJSON(t *testing.T) {
 func TestLoadDownloadedPackagesState(t *testing.T) {
 	mockScanner := func(content string, err bool) (*fakeScanner, io.ReadCloser) {
 		lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
-		mockFile := os.NewFile(0, "test-file")
+		mockFile := newTestFile(t)
 		scan := &fakeScanner{lines: lines}
 		if err {
 			scan.err = fmt.Errorf("scanner error")
@@ -352,7 +395,7 @@ func TestSaveDownloadedPackagesState(t *testing.T) {
 		entities.NewRetrievePackage("pkg2|toto"),
 	}
 
-	mockFile := os.NewFile(0, "test-file")
+	mockFile := newTestFile(t)
 	mockFS := filesystem.NewMockFileSystem(t)
 
 	t.Run("Create fails", func(t *testing.T) {
@@ -423,3 +466,403 @@ func TestSaveDownloadedPackagesState(t *testing.T) {
 		mockFS.AssertExpectations(t)
 	})
 }
+
+func TestCacheValidators(t *testing.T) {
+	packageName := "@babel/core"
+	destDir := filepath.Join("base", filepath.FromSlash(packageName))
+	filePath := filepath.Join(destDir, cacheValidatorsFileName)
+	mockFS := filesystem.NewMockFileSystem(t)
+	repo := NewLocalNpmRepository("base", mockFS, "state.txt")
+
+	t.Run("Load returns empty validators when none were saved", func(t *testing.T) {
+		mockFS.On("ReadFile", filePath).Return(nil, os.ErrNotExist).Once()
+
+		validators, err := repo.LoadCacheValidators(packageName)
+		require.NoError(t, err)
+		assert.True(t, validators.IsEmpty())
+	})
+
+	t.Run("Load fails on invalid content", func(t *testing.T) {
+		mockFS.On("ReadFile", filePath).Return([]byte("not json"), nil).Once()
+
+		_, err := repo.LoadCacheValidators(packageName)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid cache validators")
+	})
+
+	t.Run("Save then load", func(t *testing.T) {
+		validators := entities.CacheValidators{ETag: `W/"123"`, LastModified: "Mon, 03 Mar 2025 23:20:12 GMT"}
+		var saved []byte
+		mockFS.On("MkdirAll", destDir, os.ModePerm).Return(nil).Once()
+		mockFS.On("WriteFile", filePath, mock.Anything, os.FileMode(0644)).
+			Run(func(args mock.Arguments) { saved = args.Get(1).([]byte) }).
+			Return(nil).Once()
+
+		require.NoError(t, repo.SaveCacheValidators(packageName, validators))
+
+		mockFS.On("ReadFile", filePath).Return(saved, nil).Once()
+		loaded, err := repo.LoadCacheValidators(packageName)
+		require.NoError(t, err)
+		assert.Equal(t, validators, loaded)
+	})
+}
+
+// writeFiles creates the files of a package directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestUpdateDistTags(t *testing.T) {
+	t.Run("Saved dist-tags are pointed to the local tarballs", func(t *testing.T) {
+		baseDir := t.TempDir()
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+		dir := filepath.Join(baseDir, "@scope", "pkg")
+		writeFiles(t, dir, map[string]string{
+			"package.json":     `{"name": "@scope/pkg", "dist-tags": {"latest": "1.0.0"}, "versions": {}}`,
+			"pkg-1.0.0.tgz":    "",
+			"pkg-1.1.0.tgz":    "",
+			"pkg-2.0.0-rc.tgz": "",
+			"pkg-ext-1.0.tgz":  "",
+		})
+		require.NoError(t, repo.SaveDistTags("@scope/pkg", entities.DistTags{"latest": "1.2.0", "next": "2.0.0-rc.2"}))
+
+		tags, err := repo.UpdateDistTags("@scope/pkg")
+
+		require.NoError(t, err)
+		assert.Equal(t, entities.DistTags{"latest": "1.1.0", "next": "2.0.0-rc"}, tags)
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "@scope/pkg", "dist-tags": {"latest": "1.1.0", "next": "2.0.0-rc"}, "versions": {}}`, string(data))
+	})
+
+	t.Run("Dist-tags of the package.json are used when none were saved", func(t *testing.T) {
+		baseDir := t.TempDir()
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+		writeFiles(t, filepath.Join(baseDir, "left-pad"), map[string]string{
+			"package.json":       `{"name": "left-pad", "dist-tags": {"latest": "1.3.0"}}`,
+			"left-pad-1.3.0.tgz": "",
+		})
+
+		tags, err := repo.UpdateDistTags("left-pad")
+
+		require.NoError(t, err)
+		assert.Equal(t, entities.DistTags{"latest": "1.3.0"}, tags)
+	})
+
+	t.Run("HTML characters of the package.json are kept as is", func(t *testing.T) {
+		baseDir := t.TempDir()
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+		dir := filepath.Join(baseDir, "left-pad")
+		writeFiles(t, dir, map[string]string{
+			"package.json":       `{"name": "left-pad", "readme": "<b>a</b> & b", "dist-tags": {"latest": "1.3.0"}}`,
+			"left-pad-1.3.0.tgz": "",
+		})
+
+		_, err := repo.UpdateDistTags("left-pad")
+
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"dist-tags":{"latest":"1.3.0"},"name":"left-pad","readme":"<b>a</b> & b"}`, string(data))
+	})
+
+	t.Run("Package without package.json or tarball", func(t *testing.T) {
+		baseDir := t.TempDir()
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+		writeFiles(t, filepath.Join(baseDir, "empty"), map[string]string{"package.json": `{"name": "empty"}`})
+
+		tags, err := repo.UpdateDistTags("missing")
+		require.NoError(t, err)
+		assert.Nil(t, tags)
+
+		tags, err = repo.UpdateDistTags("empty")
+		require.NoError(t, err)
+		assert.Nil(t, tags)
+	})
+}
+
+func TestListPackages(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+	writeFiles(t, filepath.Join(baseDir, "left-pad"), map[string]string{"package.json": "{}"})
+	writeFiles(t, filepath.Join(baseDir, "@scope", "pkg"), map[string]string{"package.json": "{}"})
+	writeFiles(t, filepath.Join(baseDir, "_remote"), map[string]string{"x.tgz": ""})
+	writeFiles(t, baseDir, map[string]string{"download_state": ""})
+
+	names, err := repo.ListPackages()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@scope/pkg", "left-pad"}, names)
+
+	names, err = NewLocalNpmRepository(filepath.Join(baseDir, "missing"), filesystem.NewOsFileSystem(), "state.txt").ListPackages()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestLoadTarballVersions(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+	writeFiles(t, filepath.Join(baseDir, "@scope", "pkg"), map[string]string{
+		"pkg-1.1.0.tgz":   "",
+		"pkg-1.0.0.tgz":   "",
+		"pkg-ext-1.0.tgz": "",
+		".dist-tags.json": "{}",
+	})
+
+	versions, err := repo.LoadTarballVersions("@scope/pkg")
+	require.NoError(t, err)
+	assert.Equal(t, []entities.SemVer{{Major: 1}, {Major: 1, Minor: 1}}, versions)
+
+	versions, err = repo.LoadTarballVersions("missing")
+	require.NoError(t, err)
+	assert.Nil(t, versions)
+}
+
+func TestLoadLocalVersions(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+	writeFiles(t, filepath.Join(baseDir, "@scope", "pkg"), map[string]string{
+		"package.json": `{
+			"name": "@scope/pkg",
+			"dist-tags": {"latest": "1.1.0"},
+			"versions": {
+				"1.0.0": {"name": "@scope/pkg", "version": "1.0.0", "license": "Apache 2.0"},
+				"1.1.0": {"name": "@scope/pkg", "version": "1.1.0", "license": "MIT"},
+				"2.0.0": {"name": "@scope/pkg", "version": "2.0.0", "license": "MIT"}
+			}
+		}`,
+		"pkg-1.1.0.tgz": "",
+		"pkg-1.0.0.tgz": "",
+	})
+
+	t.Run("Only the versions whose tarball is stored are returned", func(t *testing.T) {
+		versions, err := repo.LoadLocalVersions("@scope/pkg")
+
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "1.0.0", versions[0].Version.String())
+		assert.Equal(t, "Apache-2.0", versions[0].License)
+		assert.Equal(t, "1.1.0", versions[1].Version.String())
+		assert.Equal(t, []string{"latest"}, versions[1].DistTags)
+	})
+
+	t.Run("Package without package.json", func(t *testing.T) {
+		versions, err := repo.LoadLocalVersions("missing")
+
+		require.NoError(t, err)
+		assert.Nil(t, versions)
+	})
+
+	t.Run("Invalid package.json", func(t *testing.T) {
+		writeFiles(t, filepath.Join(baseDir, "broken"), map[string]string{"package.json": "{"})
+
+		_, err := repo.LoadLocalVersions("broken")
+
+		assert.ErrorContains(t, err, "invalid package.json")
+	})
+}
+
+func TestDependencyGraph(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+
+	graph, err := repo.LoadDependencyGraph()
+	require.NoError(t, err)
+	assert.Empty(t, graph.Edges)
+
+	graph = entities.DependencyGraph{
+		Roots: []string{"express"},
+		Edges: []entities.DependencyEdge{{From: "express@4.18.2", To: "qs", Range: "6.11.0", Kind: entities.DependencyProd}},
+	}
+	require.NoError(t, repo.SaveDependencyGraph(graph))
+
+	loaded, err := repo.LoadDependencyGraph()
+	require.NoError(t, err)
+	assert.Equal(t, graph, loaded)
+
+	names, err := repo.ListPackages()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestInspectPackage(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+	content := "tarball"
+	sum := sha512.Sum512([]byte(content))
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	writeFiles(t, filepath.Join(baseDir, "pkg"), map[string]string{
+		"package.json": `{
+			"name": "pkg",
+			"dist-tags": {"latest": "1.1.0"},
+			"versions": {
+				"1.0.0": {"name": "pkg", "version": "1.0.0", "dist": {"integrity": "` + integrity + `"}},
+				"1.1.0": {"name": "pkg", "version": "1.1.0", "dist": {"integrity": "` + integrity + `"}},
+				"1.2.0": {"name": "pkg", "version": "1.2.0", "dist": {}},
+				"2.0.0": {"name": "pkg", "version": "2.0.0", "dist": {"integrity": "` + integrity + `"}}
+			}
+		}`,
+		".dist-tags.json": `{"latest": "2.0.0"}`,
+		"pkg-1.0.0.tgz":   content,
+		"pkg-1.1.0.tgz":   "corrupted",
+		"pkg-1.2.0.tgz":   content,
+	})
+
+	info, err := repo.InspectPackage("pkg")
+
+	require.NoError(t, err)
+	require.Len(t, info.Versions, 3)
+	assert.Equal(t, entities.IntegrityOK, info.Versions[0].Status)
+	assert.Equal(t, int64(len(content)), info.Versions[0].Size)
+	assert.False(t, info.Versions[0].DownloadedAt.IsZero())
+	assert.Equal(t, entities.IntegrityMismatch, info.Versions[1].Status)
+	assert.Equal(t, entities.IntegrityUnknown, info.Versions[2].Status)
+	assert.Equal(t, []string{"2.0.0"}, info.Missing)
+	assert.Equal(t, []string{"1.1.0"}, info.Corrupted())
+	assert.Equal(t, entities.DistTags{"latest": "1.1.0"}, info.DistTags)
+	assert.Equal(t, entities.DistTags{"latest": "2.0.0"}, info.RegistryDistTags)
+
+	usage, err := repo.PackageUsage("pkg")
+	require.NoError(t, err)
+	assert.Equal(t, 3, usage.Versions)
+	assert.Greater(t, usage.Size, int64(3*len(content)))
+
+	_, err = repo.InspectPackage("missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPrunePackage(t *testing.T) {
+	packageJSON := `{
+		"name": "pkg",
+		"dist-tags": {"latest": "2.0.0", "next": "3.0.0-rc.1"},
+		"versions": {
+			"1.0.0": {"name": "pkg", "version": "1.0.0"},
+			"2.0.0": {"name": "pkg", "version": "2.0.0"},
+			"3.0.0-rc.1": {"name": "pkg", "version": "3.0.0-rc.1"}
+		},
+		"time": {"modified": "2024-01-01T00:00:00Z", "1.0.0": "2020-01-01T00:00:00Z", "2.0.0": "2021-01-01T00:00:00Z", "3.0.0-rc.1": "2022-01-01T00:00:00Z"}
+	}`
+	setup := func(t *testing.T) (*localNpmRepo, string) {
+		baseDir := t.TempDir()
+		writeFiles(t, filepath.Join(baseDir, "pkg"), map[string]string{
+			"package.json":       packageJSON,
+			"pkg-1.0.0.tgz":      "first",
+			"pkg-2.0.0.tgz":      "second",
+			"pkg-3.0.0-rc.1.tgz": "third",
+		})
+		return NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt"), filepath.Join(baseDir, "pkg")
+	}
+
+	t.Run("Tarballs and versions of the package.json are removed", func(t *testing.T) {
+		repo, dir := setup(t)
+
+		result, err := repo.PrunePackage("pkg", []string{"2.0.0", "3.0.0-rc.1"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2.0.0", "3.0.0-rc.1"}, result.Removed)
+		assert.Equal(t, 1, result.Kept)
+		assert.False(t, result.Deleted)
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "pkg",
+			"dist-tags": {"latest": "1.0.0"},
+			"versions": {"1.0.0": {"name": "pkg", "version": "1.0.0"}},
+			"time": {"modified": "2024-01-01T00:00:00Z", "1.0.0": "2020-01-01T00:00:00Z"}
+		}`, string(data))
+		assert.Equal(t, int64(len("second")+len("third")+len(packageJSON)-len(data)), result.Freed)
+		assert.NoFileExists(t, filepath.Join(dir, "pkg-2.0.0.tgz"))
+		assert.FileExists(t, filepath.Join(dir, "pkg-1.0.0.tgz"))
+	})
+
+	t.Run("Freed bytes only count the removed versions", func(t *testing.T) {
+		baseDir := t.TempDir()
+		dir := filepath.Join(baseDir, "pkg")
+		writeFiles(t, dir, map[string]string{
+			"package.json": `{"dist-tags":{"latest":"2.0.0"},"name":"pkg","readme":"<b>a</b> & b",` +
+				`"time":{"1.0.0":"2020","2.0.0":"2021"},"versions":{"1.0.0":{"version":"1.0.0"},"2.0.0":{"version":"2.0.0"}}}`,
+			"pkg-1.0.0.tgz": "first",
+			"pkg-2.0.0.tgz": "second",
+		})
+		repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+
+		result, err := repo.PrunePackage("pkg", []string{"2.0.0"}, false)
+
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"dist-tags":{"latest":"1.0.0"},"name":"pkg","readme":"<b>a</b> & b",`+
+			`"time":{"1.0.0":"2020"},"versions":{"1.0.0":{"version":"1.0.0"}}}`, string(data))
+		assert.Equal(t, int64(len("second")+len(`,"2.0.0":"2021"`)+len(`,"2.0.0":{"version":"2.0.0"}`)), result.Freed)
+	})
+
+	t.Run("Dry run writes nothing and estimates the same savings", func(t *testing.T) {
+		repo, dir := setup(t)
+		expected, err := func() (entities.PrunedPackage, error) {
+			other, _ := setup(t)
+			return other.PrunePackage("pkg", []string{"1.0.0"}, false)
+		}()
+		require.NoError(t, err)
+
+		result, err := repo.PrunePackage("pkg", []string{"1.0.0"}, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, result)
+		assert.FileExists(t, filepath.Join(dir, "pkg-1.0.0.tgz"))
+		data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+		require.NoError(t, err)
+		assert.Equal(t, packageJSON, string(data))
+	})
+
+	t.Run("Package directory is removed when no tarball is left", func(t *testing.T) {
+		repo, dir := setup(t)
+		size, err := repo.directorySize(dir)
+		require.NoError(t, err)
+
+		result, err := repo.PrunePackage("pkg", []string{"1.0.0", "2.0.0", "3.0.0-rc.1"}, false)
+
+		require.NoError(t, err)
+		assert.True(t, result.Deleted)
+		assert.Equal(t, size, result.Freed)
+		assert.NoDirExists(t, dir)
+	})
+}
+
+func TestTombstones(t *testing.T) {
+	baseDir := t.TempDir()
+	repo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), "state.txt")
+
+	tombstones, err := repo.LoadTombstones()
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+
+	tombstone, err := entities.NewTombstone("event-stream", "3.3.6", "compromised", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveTombstones([]entities.Tombstone{tombstone}))
+
+	loaded, err := repo.LoadTombstones()
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.True(t, loaded[0].Matches(entities.NpmPackage{Name: "event-stream", Version: entities.SemVer{Major: 3, Minor: 3, Patch: 6}}))
+	assert.Equal(t, "compromised", loaded[0].Reason)
+
+	names, err := repo.ListPackages()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	writeFiles(t, baseDir, map[string]string{"_tombstones.json": `[{"name": "a", "range": "not a range"}]`})
+	_, err = repo.LoadTombstones()
+	assert.ErrorContains(t, err, "invalid tombstone")
+}
+
+// newTestFile creates an empty file returned by the file system mocks. A real file is used rather
+// than a wrapper of an arbitrary descriptor, which the repository would close under other tests.
+func newTestFile(t *testing.T) *os.File {
+	file, err := os.CreateTemp(t.TempDir(), "test-file")
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	return file
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// decodeNpmResponse stream-decodes a packument into an NpmResponse.
+// Unlike json.Decoder.Decode, which buffers the whole document before decoding it,
+// the versions are decoded one at a time and every unused field (readme, users, ...)
+// is skipped token by token, so the raw document and its readme are never held in
+// memory. Each version is converted to an NpmPackage as soon as it is decoded and its
+// raw metadata dropped, so only the converted versions grow with the size of the package.
+func decodeNpmResponse(reader io.Reader) (*NpmResponse, error) {
+	dec := json.NewDecoder(reader)
+	metadata := &NpmResponse{
+		Time: map[string]time.Time{},
+	}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "_id":
+			err = dec.Decode(&metadata.ID)
+		case "_rev":
+			err = dec.Decode(&metadata.Rev)
+		case "name":
+			err = dec.Decode(&metadata.Name)
+		case "modified":
+			err = dec.Decode(&metadata.Modified)
+		case "dist-tags":
+			err = dec.Decode(&metadata.DistTags)
+		case "versions":
+			err = decodeVersions(dec, metadata)
+		case "time":
+			err = decodeTime(dec, metadata)
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %v", key, err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// decodeVersions decodes the "versions" object, one version at a time. The release date and
+// the dist-tags of the versions are set by toNpmPackages, as they may come later in the document.
+func decodeVersions(dec *json.Decoder, response *NpmResponse) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		version, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+		var metadata NpmPackageMetadata
+		if err := dec.Decode(&metadata); err != nil {
+			return fmt.Errorf("version %s: %v", version, err)
+		}
+		pkg, err := metadata.ToNpmPackage(time.Time{})
+		if err != nil {
+			return fmt.Errorf("version %s: failed to convert metadata: %v", version, err)
+		}
+		response.Versions = append(response.Versions, pkg)
+		response.versionKeys = append(response.versionKeys, metadata.Version)
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeTime decodes the "time" object. The "unpublished" entry of unpublished packages is kept,
+// the other entries which are not dates are ignored.
+func decodeTime(dec *json.Decoder, metadata *NpmResponse) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+		if key == "unpublished" {
+			if err := decodeUnpublished(dec, metadata); err != nil {
+				return fmt.Errorf("invalid unpublished entry: %v", err)
+			}
+			continue
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch value := token.(type) {
+		case string:
+			date, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid date for %s: %v", key, err)
+			}
+			metadata.Time[key] = date
+		case json.Delim:
+			if err := skipContainer(dec); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// decodeUnpublished decodes the "unpublished" entry of the time object, which is an object
+// for unpublished packages. Any other value is ignored.
+func decodeUnpublished(dec *json.Decoder, metadata *NpmResponse) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil
+	}
+	var unpublished UnpublishedInfo
+	if err := json.Unmarshal(raw, &unpublished); err != nil {
+		return err
+	}
+	metadata.Unpublished = &unpublished
+	return nil
+}
+
+// skipValue reads and discards the next JSON value.
+func skipValue(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := token.(json.Delim); ok {
+		return skipContainer(dec)
+	}
+	return nil
+}
+
+// skipContainer discards the tokens until the end of the container just opened.
+func skipContainer(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// readKey reads an object key.
+func readKey(dec *json.Decoder) (string, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", token)
+	}
+	return key, nil
+}
+
+// expectDelim reads the next token and checks it is the expected delimiter.
+func expectDelim(dec *json.Decoder, expected json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %q, got %v", expected, token)
+	}
+	return nil
+}
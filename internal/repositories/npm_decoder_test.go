@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeNpmResponse(t *testing.T) {
+	t.Run("Converts the versions and skips the unused fields", func(t *testing.T) {
+		body := `{
+			"_id": "pkg",
+			"_rev": "12-abc",
+			"name": "pkg",
+			"dist-tags": {"latest": "1.0.0"},
+			"readme": "# A very long readme",
+			"users": {"someone": true},
+			"versions": {
+				"1.0.0": {
+					"name": "pkg",
+					"version": "1.0.0",
+					"readme": "nested readme",
+					"scripts": {"test": "jest"},
+					"dependencies": {"dep1": "^1.0.0"},
+					"peerDependencies": {"peer1": "*"},
+					"dist": {"tarball": "https://r/pkg-1.0.0.tgz", "integrity": "sha512-a", "shasum": "abc"}
+				}
+			},
+			"time": {"created": "2019-01-01T00:00:00.000Z", "1.0.0": "2020-01-01T00:00:00.123Z"},
+			"maintainers": [{"name": "someone", "email": "a@b.c"}]
+		}`
+
+		metadata, err := decodeNpmResponse(strings.NewReader(body))
+
+		require.NoError(t, err)
+		assert.Equal(t, "pkg", metadata.ID)
+		assert.Equal(t, "12-abc", metadata.Rev)
+		assert.Equal(t, "pkg", metadata.Name)
+		require.Len(t, metadata.Versions, 1)
+		version := metadata.Versions[0]
+		assert.Equal(t, entities.SemVer{Major: 1}, version.Version)
+		assert.Equal(t, []string{"dep1"}, version.Dependencies)
+		assert.Equal(t, []string{"peer1"}, version.PeerDeps)
+		assert.Equal(t, "https://r/pkg-1.0.0.tgz", version.Url)
+		assert.Equal(t, "sha512-a", version.Integrity)
+		assert.Equal(t, "abc", version.Shasum)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 123_000_000, time.UTC), metadata.Time["1.0.0"])
+
+		packages := metadata.toNpmPackages()
+		require.Len(t, packages, 1)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 123_000_000, time.UTC), packages[0].ReleaseDate)
+		assert.Equal(t, []string{"latest"}, packages[0].DistTags)
+	})
+
+	t.Run("Versions may come after time", func(t *testing.T) {
+		body := `{"time": {"1.0.0": "2020-01-01T00:00:00Z"}, "versions": {"1.0.0": {"name": "pkg", "version": "1.0.0"}}}`
+
+		metadata, err := decodeNpmResponse(strings.NewReader(body))
+
+		require.NoError(t, err)
+		assert.Len(t, metadata.Versions, 1)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), metadata.releaseDate("1.0.0"))
+	})
+
+	t.Run("Non-date time entries are ignored", func(t *testing.T) {
+		body := `{"name": "pkg", "time": {"unpublished": {"time": "2020-01-01T00:00:00Z", "versions": ["1.0.0"]}}}`
+
+		metadata, err := decodeNpmResponse(strings.NewReader(body))
+
+		require.NoError(t, err)
+		assert.Empty(t, metadata.Time)
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		_, err := decodeNpmResponse(strings.NewReader(`{"name": "pkg", "versions": {"next": {"name": "pkg", "version": "next"}}}`))
+
+		assert.ErrorContains(t, err, "version next: failed to convert metadata")
+	})
+
+	t.Run("Truncated document", func(t *testing.T) {
+		_, err := decodeNpmResponse(strings.NewReader(`{"name": "pkg", "versions": {"1.0.0": {"name"`))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Document is not an object", func(t *testing.T) {
+		_, err := decodeNpmResponse(strings.NewReader(`["pkg"]`))
+
+		assert.Error(t, err)
+	})
+}
+
+// largePackument generates a packument with the given number of versions, each with a readme,
+// similar in shape to the ones of @types/node or aws-sdk.
+func largePackument(versions int, readmeSize int) []byte {
+	readme := strings.Repeat("x", readmeSize)
+	var buf bytes.Buffer
+	buf.WriteString(`{"_id":"big","name":"big","readme":`)
+	json.NewEncoder(&buf).Encode(readme)
+	buf.WriteString(`,"versions":{`)
+	for i := 0; i < versions; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"1.%d.0":{"name":"big","version":"1.%d.0","readme":%q,"dependencies":{"dep":"^1.0.0"},"dist":{"tarball":"https://r/big-1.%d.0.tgz","integrity":"sha512-x"}}`, i, i, readme, i)
+	}
+	buf.WriteString(`},"time":{`)
+	for i := 0; i < versions; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `"1.%d.0":"2020-01-01T00:00:00Z"`, i)
+	}
+	buf.WriteString(`}}`)
+	return buf.Bytes()
+}
+
+// measurePeakHeap runs fn while sampling the heap, and returns the peak heap growth in bytes.
+func measurePeakHeap(fn func()) uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapAlloc
+
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var s runtime.MemStats
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			runtime.ReadMemStats(&s)
+			if s.HeapAlloc > base && s.HeapAlloc-base > peak.Load() {
+				peak.Store(s.HeapAlloc - base)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	fn()
+	close(done)
+	wg.Wait()
+	return peak.Load()
+}
+
+// liveHeap collects the garbage and returns the size of the live heap.
+func liveHeap() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// liveHeapReader measures the live heap every few reads of the decoder and keeps its peak, so that
+// the measure does not depend on when the collector runs.
+type liveHeapReader struct {
+	reader io.Reader
+	reads  int
+	peak   uint64
+}
+
+func (r *liveHeapReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads%16 == 0 {
+		r.peak = max(r.peak, liveHeap())
+	}
+	return r.reader.Read(p)
+}
+
+// measureStreamingDecoder decodes the document and returns the peak of the live heap while
+// decoding and converting the versions, and the heap still held by the returned versions.
+func measureStreamingDecoder(b *testing.B, document []byte) (peak uint64, retained uint64) {
+	base := liveHeap()
+	reader := &liveHeapReader{reader: bytes.NewReader(document)}
+	metadata, err := decodeNpmResponse(reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	decoded := liveHeap()
+	packages := metadata.toNpmPackages()
+	converted := liveHeap()
+	runtime.KeepAlive(metadata)
+	retained = liveHeap() - base
+	runtime.KeepAlive(packages)
+	return max(reader.peak, decoded, converted) - base, retained
+}
+
+// BenchmarkDecodeNpmPackages compares the streaming decoder with decoding the whole document at once.
+// The peak-heap-MB metric shows the memory held by one metadata worker while decoding. The streaming
+// decoder converts each version as soon as it is decoded: it fails when its peak heap grows faster
+// with the number of versions than the versions it returns, i.e. when the raw metadata of the versions
+// is kept. Only the release dates are kept besides the versions.
+func BenchmarkDecodeNpmPackages(b *testing.B) {
+	document := largePackument(2000, 8*1024)
+
+	b.Run("Streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(document)))
+		small := largePackument(500, 8*1024)
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			smallPeak, smallRetained := measureStreamingDecoder(b, small)
+			p, retained := measureStreamingDecoder(b, document)
+			// The overhead of the decoder may grow by a quarter of the returned versions, for the release dates.
+			overhead, smallOverhead := float64(p)-float64(retained), float64(smallPeak)-float64(smallRetained)
+			if overhead-smallOverhead > float64(retained-smallRetained)/4 {
+				b.Fatalf("peak heap grows with the number of versions: %.2f MB for 500 versions, %.2f MB for 2000 versions, %.2f MB held by the 2000 versions",
+					float64(smallPeak)/(1<<20), float64(p)/(1<<20), float64(retained)/(1<<20))
+			}
+			peak = max(peak, p)
+		}
+		b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+	})
+
+	b.Run("WholeDocument", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(document)))
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			p := measurePeakHeap(func() {
+				var metadata struct {
+					Versions map[string]NpmPackageMetadata `json:"versions"`
+					Time     map[string]time.Time          `json:"time"`
+				}
+				if err := json.NewDecoder(bytes.NewReader(document)).Decode(&metadata); err != nil {
+					b.Fatal(err)
+				}
+			})
+			peak = max(peak, p)
+		}
+		b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+	})
+}
@@ -3,10 +3,13 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/npmoffline/internal/entities"
@@ -19,59 +22,375 @@ type Dist struct {
 	Shasum    string `json:"shasum"`
 	Tarball   string `json:"tarball"`
 	Integrity string `json:"integrity"`
+	// UnpackedSize is the size of the files of the tarball, set by recent registries.
+	UnpackedSize int64 `json:"unpackedSize"`
 }
 
 // NpmPackageMetadata represents the metadata for an NPM package version.
 type NpmPackageMetadata struct {
-	Name         string            `json:"name"`
-	Version      string            `json:"version"`
-	Dependencies map[string]string `json:"dependencies,omitempty"`
-	PeerDeps     map[string]string `json:"peerDependencies,omitempty"`
-	Dist         Dist              `json:"dist"`
+	Name             string                        `json:"name"`
+	Version          string                        `json:"version"`
+	Dependencies     map[string]string             `json:"dependencies,omitempty"`
+	OptionalDeps     map[string]string             `json:"optionalDependencies,omitempty"`
+	PeerDeps         map[string]string             `json:"peerDependencies,omitempty"`
+	PeerDepsMeta     map[string]PeerDependencyMeta `json:"peerDependenciesMeta,omitempty"`
+	BundleDeps       BundleDependencies            `json:"bundleDependencies,omitempty"`
+	LegacyBundleDeps BundleDependencies            `json:"bundledDependencies,omitempty"`
+	OS               StringList                    `json:"os,omitempty"`
+	CPU              StringList                    `json:"cpu,omitempty"`
+	Libc             StringList                    `json:"libc,omitempty"`
+	Deprecated       Deprecation                   `json:"deprecated,omitempty"`
+	License          License                       `json:"license,omitempty"`
+	LegacyLicenses   []License                     `json:"licenses,omitempty"`
+	Maintainers      []Person                      `json:"maintainers,omitempty"`
+	Scripts          Scripts                       `json:"scripts,omitempty"`
+	Dist             Dist                          `json:"dist"`
+}
+
+// Deprecation is the deprecation message of a version. Some old packuments use a boolean.
+type Deprecation string
+
+// UnmarshalJSON accepts a message or a boolean.
+func (d *Deprecation) UnmarshalJSON(data []byte) error {
+	var deprecated bool
+	if err := json.Unmarshal(data, &deprecated); err == nil {
+		*d = ""
+		if deprecated {
+			*d = "deprecated"
+		}
+		return nil
+	}
+	var message string
+	if err := json.Unmarshal(data, &message); err != nil {
+		return fmt.Errorf("deprecated must be a message or a boolean: %v", err)
+	}
+	*d = Deprecation(message)
+	return nil
+}
+
+// StringList is a list of strings which may be written as a single string in the packument,
+// as the os, cpu and libc fields sometimes are.
+type StringList []string
+
+// UnmarshalJSON accepts a string or an array of strings.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*l = StringList{value}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("expected a string or an array of strings: %v", err)
+	}
+	*l = values
+	return nil
+}
+
+// License is the license of a version: an SPDX expression, or the {"type": ..., "url": ...}
+// object of old packuments.
+type License string
+
+// UnmarshalJSON accepts an expression or a license object. Other values are ignored: a
+// malformed license must not fail the decoding of the whole packument.
+func (l *License) UnmarshalJSON(data []byte) error {
+	var expression string
+	if err := json.Unmarshal(data, &expression); err == nil {
+		*l = License(strings.TrimSpace(expression))
+		return nil
+	}
+	var object struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &object); err == nil {
+		*l = License(strings.TrimSpace(object.Type))
+		return nil
+	}
+	*l = ""
+	return nil
+}
+
+// expression returns the license expression of the version. The legacy licenses array is
+// read as a choice between its licenses.
+func (m *NpmPackageMetadata) expression() string {
+	if m.License != "" || len(m.LegacyLicenses) == 0 {
+		return string(m.License)
+	}
+	var licenses []string
+	for _, license := range m.LegacyLicenses {
+		if license == "" {
+			continue
+		}
+		if strings.Contains(string(license), " ") {
+			license = "(" + license + ")"
+		}
+		licenses = append(licenses, string(license))
+	}
+	return strings.Join(licenses, " OR ")
+}
+
+// Person is a maintainer of a package, written as an object or as "name <email> (url)".
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// UnmarshalJSON accepts an object or a string. Other values give an empty person.
+func (p *Person) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		name, rest, _ := strings.Cut(value, "<")
+		email, _, _ := strings.Cut(rest, ">")
+		*p = Person{Name: strings.TrimSpace(name), Email: strings.TrimSpace(email)}
+		return nil
+	}
+	var object struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(data, &object); err == nil {
+		*p = Person(object)
+		return nil
+	}
+	*p = Person{}
+	return nil
+}
+
+// Scripts holds the names of the scripts of a version. Their commands are not needed.
+type Scripts []string
+
+// UnmarshalJSON reads the keys of the scripts object. Other values give no script.
+func (s *Scripts) UnmarshalJSON(data []byte) error {
+	var scripts map[string]json.RawMessage
+	if err := json.Unmarshal(data, &scripts); err != nil {
+		*s = nil
+		return nil
+	}
+	names := make(Scripts, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	*s = names
+	return nil
+}
+
+// PeerDependencyMeta represents an entry of peerDependenciesMeta.
+type PeerDependencyMeta struct {
+	Optional bool `json:"optional"`
+}
+
+// BundleDependencies represents the bundled dependencies of a version: either the list of
+// the bundled packages, or true when all the dependencies are bundled.
+type BundleDependencies struct {
+	All   bool
+	Names []string
+}
+
+// UnmarshalJSON accepts a boolean or an array of package names.
+func (b *BundleDependencies) UnmarshalJSON(data []byte) error {
+	var all bool
+	if err := json.Unmarshal(data, &all); err == nil {
+		*b = BundleDependencies{All: all}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("bundled dependencies must be a boolean or an array of names: %v", err)
+	}
+	*b = BundleDependencies{Names: names}
+	return nil
+}
+
+// isBundled returns true if the given dependency is shipped inside the tarball of the version.
+func (m *NpmPackageMetadata) isBundled(dep string) bool {
+	for _, bundle := range []BundleDependencies{m.BundleDeps, m.LegacyBundleDeps} {
+		if bundle.All && (m.Dependencies[dep] != "" || m.OptionalDeps[dep] != "") {
+			return true
+		}
+		for _, name := range bundle.Names {
+			if name == dep {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // ToNpmPackage converts the metadata to an NpmPackage entity.
 // The date parameter is used to set the release date of the package.
+// Optional dependencies are removed from the regular dependencies, where npm also lists them,
+// optional peer dependencies are separated from the required ones and bundled dependencies
+// are excluded from every list since they do not need a separate download.
+// Aliases are replaced by the registry package they point to, while tarball, git and file
+// dependencies are moved to ExternalDeps.
 func (m *NpmPackageMetadata) ToNpmPackage(date time.Time) (entities.NpmPackage, error) {
 	version, err := entities.NewSemVer(m.Version)
 	if err != nil {
 		return entities.NpmPackage{}, fmt.Errorf("failed to convert version: %v", err)
 	}
 
-	// Convert dependencies and peer dependencies to a slice of strings
-	var deps []string
+	var external []entities.DependencySpec
+	ranges := map[string]string{}
+
+	// resolve returns the registry packages of the given dependencies: aliases are replaced
+	// by the package they point to, and the other sources are kept in external.
+	resolve := func(deps map[string]string, kind entities.DependencyKind, keep func(dep string) bool) []string {
+		var packages []string
+		for dep, value := range deps {
+			if !keep(dep) || m.isBundled(dep) {
+				continue
+			}
+			spec := entities.ParseDependencySpec(dep, value)
+			spec.Kind = kind
+			if spec.IsRegistry() {
+				packages = append(packages, spec.Package)
+				if previous, ok := ranges[spec.Package]; ok && previous != spec.Range {
+					// The same package is also required under another name.
+					ranges[spec.Package] = previous + " || " + spec.Range
+				} else {
+					ranges[spec.Package] = spec.Range
+				}
+			} else {
+				external = append(external, spec)
+			}
+		}
+		return packages
+	}
+	all := func(string) bool { return true }
+
+	deps := resolve(m.Dependencies, entities.DependencyProd, func(dep string) bool {
+		_, optional := m.OptionalDeps[dep]
+		return !optional
+	})
+	optionalDeps := resolve(m.OptionalDeps, entities.DependencyOptional, all)
+	peerDeps := resolve(m.PeerDeps, entities.DependencyPeer, func(dep string) bool { return !m.PeerDepsMeta[dep].Optional })
+	optionalPeerDeps := resolve(m.PeerDeps, entities.DependencyOptionalPeer, func(dep string) bool { return m.PeerDepsMeta[dep].Optional })
+
+	var bundledDeps []string
 	for dep := range m.Dependencies {
-		deps = append(deps, dep)
+		if m.isBundled(dep) {
+			bundledDeps = append(bundledDeps, dep)
+		}
+	}
+	for dep := range m.OptionalDeps {
+		if _, regular := m.Dependencies[dep]; !regular && m.isBundled(dep) {
+			bundledDeps = append(bundledDeps, dep)
+		}
 	}
 
-	var peerDeps []string
-	for dep := range m.PeerDeps {
-		peerDeps = append(peerDeps, dep)
+	var license string
+	if declared := m.expression(); declared != "" {
+		license, _ = entities.NormalizeLicense(declared)
+	}
+
+	var maintainers []string
+	for _, maintainer := range m.Maintainers {
+		if maintainer.Name != "" {
+			maintainers = append(maintainers, maintainer.Name)
+		}
+	}
+	var installScripts []string
+	for _, script := range m.Scripts {
+		if slices.Contains(entities.InstallScripts, script) {
+			installScripts = append(installScripts, script)
+		}
 	}
 
 	return entities.NpmPackage{
-		Name:         m.Name,
-		Version:      version,
-		ReleaseDate:  date,
-		Dependencies: deps,
-		PeerDeps:     peerDeps,
+		Name:             m.Name,
+		Version:          version,
+		ReleaseDate:      date,
+		Dependencies:     deps,
+		OptionalDeps:     optionalDeps,
+		PeerDeps:         peerDeps,
+		OptionalPeerDeps: optionalPeerDeps,
+		BundledDeps:      bundledDeps,
+		ExternalDeps:     external,
+		DependencyRanges: ranges,
+		Deprecated:       string(m.Deprecated),
+		License:          license,
+		Maintainers:      maintainers,
+		InstallScripts:   installScripts,
+		Platform: entities.PlatformConstraints{
+			OS:   m.OS,
+			CPU:  m.CPU,
+			Libc: m.Libc,
+		},
 		Integrity:    m.Dist.Integrity,
+		Shasum:       m.Dist.Shasum,
+		UnpackedSize: m.Dist.UnpackedSize,
 		Url:          m.Dist.Tarball,
 	}, nil
 }
 
-// NpmResponse represents the full response for an NPM package.
+// NpmResponse represents the response for an NPM package, either a full packument
+// or an abbreviated one. Abbreviated documents have no per-version release date,
+// only the modification date of the whole document.
 type NpmResponse struct {
-	ID       string                        `json:"_id"`
-	Rev      string                        `json:"_rev"`
-	Name     string                        `json:"name"`
-	Versions map[string]NpmPackageMetadata `json:"versions"`
-	Time     map[string]time.Time          `json:"time"`
+	ID   string `json:"_id"`
+	Rev  string `json:"_rev"`
+	Name string `json:"name"`
+	// Versions are converted as soon as they are decoded by decodeNpmResponse. Their release
+	// date and dist-tags are set by toNpmPackages.
+	Versions []entities.NpmPackage `json:"-"`
+	Time     map[string]time.Time  `json:"time"`
+	Modified time.Time             `json:"modified"`
+	DistTags map[string]string     `json:"dist-tags"`
+	// Unpublished is set when the package was unpublished: its versions are gone.
+	Unpublished *UnpublishedInfo `json:"-"`
+	// versionKeys are the version strings the time and dist-tags objects refer to the versions by.
+	versionKeys []string
+}
+
+// UnpublishedInfo is the "unpublished" entry of the time object of an unpublished package.
+type UnpublishedInfo struct {
+	Time     time.Time `json:"time"`
+	Versions []string  `json:"versions"`
+}
+
+// UnpublishedError is returned by DecodeNpmPackages for a package which was unpublished.
+type UnpublishedError struct {
+	Name     string
+	Time     time.Time
+	Versions []string
 }
 
+func (e *UnpublishedError) Error() string {
+	return fmt.Sprintf("package %s was unpublished on %s", e.Name, e.Time.Format(time.RFC3339))
+}
+
+// releaseDate returns the release date of the given version.
+// The document modification date is used for abbreviated documents.
+func (m *NpmResponse) releaseDate(version string) time.Time {
+	if date, ok := m.Time[version]; ok {
+		return date
+	}
+	return m.Modified
+}
+
+// MetadataFormat is the format of the packument requested to the registry.
+type MetadataFormat int
+
+const (
+	// FullMetadata is the complete packument, as stored in the local repository.
+	FullMetadata MetadataFormat = iota
+	// AbbreviatedMetadata is the smaller document used by npm to resolve dependencies.
+	AbbreviatedMetadata
+)
+
+const (
+	fullMetadataAccept        = "application/json"
+	abbreviatedMetadataAccept = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8, */*"
+)
+
+// ErrNotModified is returned by FetchMetadata when the registry answers 304 Not Modified.
+var ErrNotModified = errors.New("metadata not modified")
+
 type NpmRepository interface {
-	FetchMetadata(ctx context.Context, packageName string) (io.ReadCloser, error)
+	FetchMetadata(ctx context.Context, packageName string, format MetadataFormat, validators entities.CacheValidators) (io.ReadCloser, entities.CacheValidators, error)
 	DownloadTarballStream(ctx context.Context, tarballURL string) (io.ReadCloser, error)
+	TarballSize(ctx context.Context, tarballURL string) (int64, error)
 	DecodeNpmPackages(r io.Reader) ([]entities.NpmPackage, error)
 }
 
@@ -91,55 +410,111 @@ func NewNpmRepository(baseURL string, client httpclient.Client, log logger.Logge
 	}
 }
 
-// FetchMetadata retrieves the metadata of a package from the NPM registry.
-func (r *npmRepository) FetchMetadata(ctx context.Context, packageName string) (io.ReadCloser, error) {
-	// Encode the package name to make it safe for URL usage
-	encodedPackageName := url.QueryEscape(packageName)
-	url := fmt.Sprintf("%s/%s", r.baseURL, encodedPackageName)
+// FetchMetadata retrieves the metadata of a package from the NPM registry, in the given format.
+// The given validators are sent as a conditional request: ErrNotModified is returned
+// if the packument did not change. The validators of the new packument are returned.
+func (r *npmRepository) FetchMetadata(ctx context.Context, packageName string, format MetadataFormat, validators entities.CacheValidators) (io.ReadCloser, entities.CacheValidators, error) {
+	name, err := entities.NewPackageName(packageName)
+	if err != nil {
+		return nil, entities.CacheValidators{}, err
+	}
+	url := fmt.Sprintf("%s/%s", strings.TrimSuffix(r.baseURL, "/"), name.RegistryPath())
 
-	resp, err := r.client.Do(ctx, "GET", url, nil, nil)
+	headers := map[string]string{"Accept": fullMetadataAccept}
+	if format == AbbreviatedMetadata {
+		headers["Accept"] = abbreviatedMetadataAccept
+	}
+	if validators.ETag != "" {
+		headers["If-None-Match"] = validators.ETag
+	}
+	if validators.LastModified != "" {
+		headers["If-Modified-Since"] = validators.LastModified
+	}
+
+	resp, err := r.client.Do(ctx, "GET", url, nil, headers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch metadata: %v", err)
+		return nil, entities.CacheValidators{}, fmt.Errorf("failed to fetch metadata: %w", err)
 	}
 
-	if resp.StatusCode != 200 {
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, validators, ErrNotModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close() // Important to close the body in case of errors
-		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		return nil, entities.CacheValidators{}, httpclient.NewStatusError(resp)
 	}
 
-	return resp.Body, nil
+	return resp.Body, entities.CacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
 }
 
 // DownloadTarballStream downloads a tarball file and returns a stream (io.ReadCloser).
 func (r *npmRepository) DownloadTarballStream(ctx context.Context, tarballURL string) (io.ReadCloser, error) {
 	resp, err := r.client.Do(ctx, "GET", tarballURL, nil, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download tarball: %v", err)
+		return nil, fmt.Errorf("failed to download tarball: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close() // Important to close the body in case of errors
-		return nil, fmt.Errorf("unexpected status code %d while downloading tarball", resp.StatusCode)
+		return nil, fmt.Errorf("failed to download tarball: %w", httpclient.NewStatusError(resp))
 	}
 
 	// Return the response body as a ReadCloser
 	return resp.Body, nil
 }
 
+// TarballSize returns the size of a tarball from the Content-Length of a HEAD request, or -1
+// when the registry does not send it.
+func (r *npmRepository) TarballSize(ctx context.Context, tarballURL string) (int64, error) {
+	resp, err := r.client.Do(ctx, "HEAD", tarballURL, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to request tarball: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to request tarball: %w", httpclient.NewStatusError(resp))
+	}
+	return resp.ContentLength, nil
+}
+
 // DecodeNpmPackages decodes the NPM packages from a reader.
+// Both full and abbreviated documents are supported. The document is stream-decoded
+// so that very large packuments do not have to be loaded in memory.
 func (r *npmRepository) DecodeNpmPackages(reader io.Reader) ([]entities.NpmPackage, error) {
-	var metadata NpmResponse
-	if err := json.NewDecoder(reader).Decode(&metadata); err != nil {
+	metadata, err := decodeNpmResponse(reader)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
+	// Consume the trailing bytes so that a tee-ed copy of the document is complete.
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
 
-	var packages []entities.NpmPackage
-	for _, releasedPackage := range metadata.Versions {
-		pkg, err := releasedPackage.ToNpmPackage(metadata.Time[releasedPackage.Version])
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert metadata: %v", err)
-		}
-		packages = append(packages, pkg)
+	if metadata.Unpublished != nil && len(metadata.Versions) == 0 {
+		return nil, &UnpublishedError{Name: metadata.Name, Time: metadata.Unpublished.Time, Versions: metadata.Unpublished.Versions}
+	}
+
+	return metadata.toNpmPackages(), nil
+}
+
+// toNpmPackages sets the release date and the dist-tags of the versions of the document,
+// and returns them.
+func (m *NpmResponse) toNpmPackages() []entities.NpmPackage {
+	tags := map[string][]string{}
+	for tag, version := range m.DistTags {
+		tags[version] = append(tags[version], tag)
+	}
+
+	for i, version := range m.versionKeys {
+		m.Versions[i].ReleaseDate = m.releaseDate(version)
+		m.Versions[i].DistTags = tags[version]
+		sort.Strings(m.Versions[i].DistTags)
 	}
-	return packages, nil
+	return m.Versions
 }
@@ -2,16 +2,19 @@ package repositories
 
 import (
 	context "context"
+	"errors"
 	"fmt"
 	io "io"
 	"net/http"
-	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/npmoffline/internal/entities"
 	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/stretchr/testify/assert"
 	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFetchMetadata(t *testing.T) {
@@ -44,27 +47,74 @@ func TestFetchMetadata(t *testing.T) {
 
 	t.Run("EncodesPackageName", func(t *testing.T) {
 		packageName := "@mui/icons-material"
-		encodedPackageName := url.QueryEscape(packageName)
-		expectedURL := "https://registry.npmjs.org/" + encodedPackageName
+		expectedURL := "https://registry.npmjs.org/@mui%2ficons-material"
 
 		mockClient.On("Do", mock.Anything, "GET", expectedURL, nil, mock.Anything).Return(mockResponse(http.StatusOK, `{"versions": {}}`), nil).Once()
 
-		_, err := repo.FetchMetadata(context.Background(), packageName)
+		_, _, err := repo.FetchMetadata(context.Background(), packageName, FullMetadata, entities.CacheValidators{})
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("RejectsInvalidPackageName", func(t *testing.T) {
+		_, _, err := repo.FetchMetadata(context.Background(), "../etc/passwd", FullMetadata, entities.CacheValidators{})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid package name")
+	})
+
 	t.Run("UnexpectedStatusCode", func(t *testing.T) {
 		mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, mock.Anything).Return(mockResponse(http.StatusNotFound, ``), nil).Once()
 
-		_, err := repo.FetchMetadata(context.Background(), "test-package")
+		_, _, err := repo.FetchMetadata(context.Background(), "test-package", FullMetadata, entities.CacheValidators{})
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unexpected status code")
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("SendsCacheValidators", func(t *testing.T) {
+		validators := entities.CacheValidators{ETag: `"abc"`, LastModified: "Mon, 03 Mar 2025 23:20:12 GMT"}
+		expectedHeaders := map[string]string{
+			"Accept":            fullMetadataAccept,
+			"If-None-Match":     `"abc"`,
+			"If-Modified-Since": "Mon, 03 Mar 2025 23:20:12 GMT",
+		}
+		resp := mockResponse(http.StatusOK, `{"versions": {}}`)
+		resp.Header = http.Header{"Etag": []string{`"def"`}}
+		mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, expectedHeaders).Return(resp, nil).Once()
+
+		body, newValidators, err := repo.FetchMetadata(context.Background(), "test-package", FullMetadata, validators)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, body)
+		assert.Equal(t, entities.CacheValidators{ETag: `"def"`}, newValidators)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("RequestsAbbreviatedFormat", func(t *testing.T) {
+		expectedHeaders := map[string]string{"Accept": abbreviatedMetadataAccept}
+		mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, expectedHeaders).Return(mockResponse(http.StatusOK, `{"versions": {}}`), nil).Once()
+
+		_, _, err := repo.FetchMetadata(context.Background(), "test-package", AbbreviatedMetadata, entities.CacheValidators{})
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("NotModified", func(t *testing.T) {
+		validators := entities.CacheValidators{ETag: `"abc"`}
+		mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, mock.Anything).Return(mockResponse(http.StatusNotModified, ``), nil).Once()
+
+		body, newValidators, err := repo.FetchMetadata(context.Background(), "test-package", FullMetadata, validators)
+
+		assert.ErrorIs(t, err, ErrNotModified)
+		assert.Nil(t, body)
+		assert.Equal(t, validators, newValidators)
+		mockClient.AssertExpectations(t)
+	})
+
 	// t.Run("MalformedJSON", func(t *testing.T) {
 	// 	mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, mock.Anything).Return(mockResponse(http.StatusOK, "invalid-json"), nil).Once()
 
@@ -129,9 +179,315 @@ func TestDownloadTarballStream(t *testing.T) {
 
 }
 
+func TestTarballSize(t *testing.T) {
+	mockClient := httpclient.NewMockClient(t)
+	repo := &npmRepository{
+		client: mockClient,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		resp := mockResponse(http.StatusOK, "")
+		resp.ContentLength = 1234
+		mockClient.On("Do", mock.Anything, "HEAD", "https://example.com/tarball.tgz", nil, mock.Anything).Return(resp, nil).Once()
+
+		size, err := repo.TarballSize(context.Background(), "https://example.com/tarball.tgz")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1234), size)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Unexpected status code", func(t *testing.T) {
+		mockClient.On("Do", mock.Anything, "HEAD", "https://example.com/tarball.tgz", nil, mock.Anything).Return(mockResponse(http.StatusNotFound, ""), nil).Once()
+
+		_, err := repo.TarballSize(context.Background(), "https://example.com/tarball.tgz")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected status code")
+		mockClient.AssertExpectations(t)
+	})
+}
+
 func mockResponse(status int, body string) *http.Response {
 	return &http.Response{
 		StatusCode: status,
 		Body:       io.NopCloser(strings.NewReader(body)),
 	}
 }
+
+func TestStatusErrors(t *testing.T) {
+	mockClient := httpclient.NewMockClient(t)
+	repo := &npmRepository{client: mockClient, baseURL: "https://registry.npmjs.org"}
+
+	t.Run("Throttled metadata request exposes Retry-After", func(t *testing.T) {
+		resp := mockResponse(http.StatusTooManyRequests, ``)
+		resp.Header = http.Header{"Retry-After": []string{"7"}}
+		mockClient.On("Do", mock.Anything, "GET", mock.Anything, nil, mock.Anything).Return(resp, nil).Once()
+
+		_, _, err := repo.FetchMetadata(context.Background(), "test-package", FullMetadata, entities.CacheValidators{})
+
+		var statusErr *httpclient.StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Equal(t, 7*time.Second, statusErr.RetryAfter)
+		assert.True(t, httpclient.IsRetryable(err))
+	})
+
+	t.Run("Missing tarball is not retryable", func(t *testing.T) {
+		mockClient.On("Do", mock.Anything, "GET", "https://example.com/tarball.tgz", nil, mock.Anything).Return(mockResponse(http.StatusNotFound, ""), nil).Once()
+
+		_, err := repo.DownloadTarballStream(context.Background(), "https://example.com/tarball.tgz")
+
+		var statusErr *httpclient.StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+		assert.False(t, httpclient.IsRetryable(err))
+	})
+}
+
+func TestDecodeNpmPackages(t *testing.T) {
+	repo := &npmRepository{}
+
+	t.Run("Full packument uses per-version release dates", func(t *testing.T) {
+		body := `{
+			"name": "left-pad",
+			"versions": {
+				"1.0.0": {"name": "left-pad", "version": "1.0.0", "dependencies": {"dep1": "^1.0.0"}, "dist": {"tarball": "https://r/left-pad-1.0.0.tgz", "integrity": "sha512-a"}},
+				"1.1.0": {"name": "left-pad", "version": "1.1.0", "dist": {"tarball": "https://r/left-pad-1.1.0.tgz", "integrity": "sha512-b"}}
+			},
+			"time": {"1.0.0": "2020-01-01T00:00:00Z", "1.1.0": "2021-01-01T00:00:00Z"}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		assert.NoError(t, err)
+		assert.Len(t, packages, 2)
+		for _, pkg := range packages {
+			switch pkg.Version.String() {
+			case "1.0.0":
+				assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), pkg.ReleaseDate)
+				assert.Equal(t, []string{"dep1"}, pkg.Dependencies)
+				assert.Equal(t, "sha512-a", pkg.Integrity)
+			case "1.1.0":
+				assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), pkg.ReleaseDate)
+			}
+		}
+	})
+
+	t.Run("Optional, optional peer and bundled dependencies", func(t *testing.T) {
+		body := `{
+			"name": "esbuild",
+			"versions": {
+				"1.0.0": {
+					"name": "esbuild", "version": "1.0.0",
+					"dependencies": {"dep1": "^1.0.0", "@esbuild/linux-x64": "1.0.0", "bundled1": "^1.0.0"},
+					"optionalDependencies": {"@esbuild/linux-x64": "1.0.0", "@esbuild/darwin-arm64": "1.0.0"},
+					"peerDependencies": {"peer1": "*", "peer2": "*"},
+					"peerDependenciesMeta": {"peer2": {"optional": true}},
+					"bundleDependencies": ["bundled1"],
+					"dist": {"tarball": "https://r/esbuild-1.0.0.tgz"}
+				}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 1)
+		assert.Equal(t, []string{"dep1"}, packages[0].Dependencies)
+		assert.ElementsMatch(t, []string{"@esbuild/linux-x64", "@esbuild/darwin-arm64"}, packages[0].OptionalDeps)
+		assert.Equal(t, []string{"peer1"}, packages[0].PeerDeps)
+		assert.Equal(t, []string{"peer2"}, packages[0].OptionalPeerDeps)
+		assert.Equal(t, []string{"bundled1"}, packages[0].BundledDeps)
+	})
+
+	t.Run("All dependencies bundled with the legacy field", func(t *testing.T) {
+		body := `{
+			"name": "npm",
+			"versions": {
+				"1.0.0": {"name": "npm", "version": "1.0.0", "dependencies": {"dep1": "^1.0.0", "dep2": "^1.0.0"}, "bundledDependencies": true}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 1)
+		assert.Empty(t, packages[0].Dependencies)
+		assert.ElementsMatch(t, []string{"dep1", "dep2"}, packages[0].BundledDeps)
+	})
+
+	t.Run("Invalid bundled dependencies", func(t *testing.T) {
+		body := `{"name": "pkg", "versions": {"1.0.0": {"name": "pkg", "version": "1.0.0", "bundleDependencies": "dep1"}}}`
+
+		_, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Platform constraints", func(t *testing.T) {
+		body := `{
+			"name": "@esbuild/linux-x64",
+			"versions": {
+				"1.0.0": {"name": "@esbuild/linux-x64", "version": "1.0.0", "os": ["linux"], "cpu": "x64", "libc": ["glibc"]}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 1)
+		assert.Equal(t, entities.PlatformConstraints{OS: []string{"linux"}, CPU: []string{"x64"}, Libc: []string{"glibc"}}, packages[0].Platform)
+	})
+
+	t.Run("Aliases are resolved and other sources are external", func(t *testing.T) {
+		body := `{
+			"name": "pkg",
+			"versions": {
+				"1.0.0": {
+					"name": "pkg", "version": "1.0.0",
+					"dependencies": {
+						"string-width-cjs": "npm:string-width@^4.2.0",
+						"xlsx": "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz",
+						"lib": "github:user/repo#abc123"
+					},
+					"optionalDependencies": {"local": "file:../local"}
+				}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 1)
+		assert.Equal(t, []string{"string-width"}, packages[0].Dependencies)
+		assert.Empty(t, packages[0].OptionalDeps)
+		assert.ElementsMatch(t, []entities.DependencySpec{
+			{Name: "xlsx", Spec: "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz", Type: entities.TarballDependency, Kind: entities.DependencyProd},
+			{Name: "lib", Spec: "github:user/repo#abc123", Type: entities.GitDependency, Kind: entities.DependencyProd},
+			{Name: "local", Spec: "file:../local", Type: entities.FileDependency, Kind: entities.DependencyOptional},
+		}, packages[0].ExternalDeps)
+	})
+
+	t.Run("Deprecated versions and dependency ranges", func(t *testing.T) {
+		body := `{
+			"name": "request",
+			"versions": {
+				"2.88.2": {"name": "request", "version": "2.88.2", "deprecated": "request has been deprecated", "dependencies": {"uuid": "^3.3.2", "uuid-cjs": "npm:uuid@^8"}},
+				"2.88.1": {"name": "request", "version": "2.88.1", "deprecated": false}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 2)
+		for _, pkg := range packages {
+			if pkg.Version.String() == "2.88.2" {
+				assert.Equal(t, "request has been deprecated", pkg.Deprecated)
+				assert.Contains(t, []string{"^3.3.2 || ^8", "^8 || ^3.3.2"}, pkg.DependencyRanges["uuid"])
+			} else {
+				assert.Empty(t, pkg.Deprecated)
+			}
+		}
+	})
+
+	t.Run("Dist-tags are set on their versions", func(t *testing.T) {
+		body := `{
+			"name": "react",
+			"dist-tags": {"latest": "18.3.1", "next": "19.0.0-rc.1", "stable": "18.3.1"},
+			"versions": {
+				"18.3.1": {"name": "react", "version": "18.3.1"},
+				"19.0.0-rc.1": {"name": "react", "version": "19.0.0-rc.1"},
+				"18.2.0": {"name": "react", "version": "18.2.0"}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 3)
+		tags := map[string][]string{}
+		for _, pkg := range packages {
+			tags[pkg.Version.String()] = pkg.DistTags
+		}
+		assert.Equal(t, map[string][]string{"18.3.1": {"latest", "stable"}, "19.0.0-rc.1": {"next"}, "18.2.0": nil}, tags)
+	})
+
+	t.Run("Licenses, maintainers and install scripts", func(t *testing.T) {
+		body := `{
+			"name": "native",
+			"versions": {
+				"1.0.0": {
+					"name": "native", "version": "1.0.0",
+					"licenses": [{"type": "MIT"}, {"type": "Apache-2.0 AND BSD-3-Clause"}],
+					"maintainers": ["Jane Doe <jane@example.com>"]
+				},
+				"2.0.0": {
+					"name": "native", "version": "2.0.0",
+					"license": {"type": "ISC", "url": "https://opensource.org/licenses/ISC"},
+					"maintainers": [{"name": "jane", "email": "jane@example.com"}, {"email": "nobody@example.com"}],
+					"scripts": {"test": "jest", "postinstall": "node-gyp rebuild", "install": "node install.js"}
+				},
+				"3.0.0": {"name": "native", "version": "3.0.0", "license": "MIT", "scripts": "invalid"}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		require.NoError(t, err)
+		require.Len(t, packages, 3)
+		byVersion := map[string]entities.NpmPackage{}
+		for _, pkg := range packages {
+			byVersion[pkg.Version.String()] = pkg
+		}
+		assert.Equal(t, "MIT OR (Apache-2.0 AND BSD-3-Clause)", byVersion["1.0.0"].License)
+		assert.Equal(t, []string{"Jane Doe"}, byVersion["1.0.0"].Maintainers)
+		assert.Empty(t, byVersion["1.0.0"].InstallScripts)
+		assert.Equal(t, "ISC", byVersion["2.0.0"].License)
+		assert.Equal(t, []string{"jane"}, byVersion["2.0.0"].Maintainers)
+		assert.Equal(t, []string{"install", "postinstall"}, byVersion["2.0.0"].InstallScripts)
+		assert.Equal(t, "MIT", byVersion["3.0.0"].License)
+		assert.Empty(t, byVersion["3.0.0"].InstallScripts)
+	})
+
+	t.Run("Unpublished package", func(t *testing.T) {
+		body := `{
+			"_id": "gone",
+			"name": "gone",
+			"time": {
+				"created": "2019-01-01T00:00:00.000Z",
+				"unpublished": {"time": "2021-03-04T05:06:07.000Z", "versions": ["1.0.0", "1.0.1"]}
+			}
+		}`
+
+		_, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		var unpublished *UnpublishedError
+		require.ErrorAs(t, err, &unpublished)
+		assert.Equal(t, "gone", unpublished.Name)
+		assert.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), unpublished.Time)
+		assert.Equal(t, []string{"1.0.0", "1.0.1"}, unpublished.Versions)
+	})
+
+	t.Run("Abbreviated document uses the modification date", func(t *testing.T) {
+		body := `{
+			"name": "left-pad",
+			"modified": "2022-06-01T10:00:00Z",
+			"versions": {
+				"1.0.0": {"name": "left-pad", "version": "1.0.0", "dist": {"tarball": "https://r/left-pad-1.0.0.tgz"}}
+			}
+		}`
+
+		packages, err := repo.DecodeNpmPackages(strings.NewReader(body))
+
+		assert.NoError(t, err)
+		assert.Len(t, packages, 1)
+		assert.Equal(t, time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC), packages[0].ReleaseDate)
+	})
+
+	t.Run("Malformed JSON", func(t *testing.T) {
+		_, err := repo.DecodeNpmPackages(strings.NewReader("invalid-json"))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode response")
+	})
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/filesystem"
+	"github.com/npmoffline/internal/pkg/httpclient"
+	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStrictRegistry starts a local registry serving one version of each given package.
+// Like Verdaccio or Nexus, it only serves the packument of a scoped package when the
+// slash of its name is encoded as "%2f", so it matches the raw request URI.
+func newStrictRegistry(t *testing.T, packages ...string) *httptest.Server {
+	packuments := map[string]string{}
+	tarballs := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tarball, ok := tarballs[r.URL.Path]; ok {
+			io.WriteString(w, tarball)
+			return
+		}
+		if packument, ok := packuments[r.RequestURI]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, packument)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	for _, pkg := range packages {
+		name, err := entities.NewPackageName(pkg)
+		require.NoError(t, err)
+
+		tarballPath := fmt.Sprintf("/%s/-/%s", name, name.TarballFileName("1.0.0"))
+		tarballs[tarballPath] = "tarball of " + pkg
+		sum := sha512.Sum512([]byte(tarballs[tarballPath]))
+		integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+		packuments["/"+name.RegistryPath()] = fmt.Sprintf(
+			`{"name": %q, "versions": {"1.0.0": {"name": %q, "version": "1.0.0", "dist": {"tarball": %q, "integrity": %q}}}, "time": {"1.0.0": "2024-01-01T00:00:00Z"}}`,
+			pkg, pkg, server.URL+tarballPath, integrity)
+	}
+	return server
+}
+
+func TestScopedPackages(t *testing.T) {
+	packages := []string{"@babel/core", "@types/node", "left-pad"}
+	server := newStrictRegistry(t, packages...)
+
+	remoteRepo := NewNpmRepository(server.URL, httpclient.NewHttpClient(server.Client()), logger.NewMockLogger(t))
+	baseDir := t.TempDir()
+	localRepo := NewLocalNpmRepository(baseDir, filesystem.NewOsFileSystem(), filepath.Join(baseDir, "state.txt"))
+
+	for _, pkg := range packages {
+		t.Run(pkg, func(t *testing.T) {
+			name, err := entities.NewPackageName(pkg)
+			require.NoError(t, err)
+
+			// Fetch the packument and store it while decoding it.
+			body, _, err := remoteRepo.FetchMetadata(context.Background(), pkg, FullMetadata, entities.CacheValidators{})
+			require.NoError(t, err)
+			tee, err := localRepo.WritePackageJSON(pkg, body)
+			require.NoError(t, err)
+			npmPackages, err := remoteRepo.DecodeNpmPackages(tee)
+			require.NoError(t, err)
+			require.NoError(t, tee.Close())
+			require.Len(t, npmPackages, 1)
+			assert.Equal(t, pkg, npmPackages[0].Name)
+			assert.FileExists(t, filepath.Join(baseDir, name.DirPath(), "package.json"))
+
+			// Download the tarball, it is stored in the directory of the scope.
+			tarball, err := remoteRepo.DownloadTarballStream(context.Background(), npmPackages[0].Url)
+			require.NoError(t, err)
+			require.NoError(t, localRepo.WriteTarball(pkg, "1.0.0", npmPackages[0].Integrity, tarball))
+
+			content, err := os.ReadFile(filepath.Join(baseDir, name.DirPath(), name.TarballFileName("1.0.0")))
+			require.NoError(t, err)
+			assert.Equal(t, "tarball of "+pkg, string(content))
+		})
+	}
+
+	t.Run("Query-escaped names are not found on strict registries", func(t *testing.T) {
+		resp, err := server.Client().Get(server.URL + "/%40babel%2Fcore")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Invalid names are never written outside the repository", func(t *testing.T) {
+		err := localRepo.WriteTarball("@babel/../../escape", "1.0.0", "", io.NopCloser(strings.NewReader("")))
+		assert.Error(t, err)
+
+		_, err = localRepo.WritePackageJSON("../escape", io.NopCloser(strings.NewReader("")))
+		assert.Error(t, err)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(baseDir), "escape"))
+	})
+}
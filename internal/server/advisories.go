@@ -0,0 +1,119 @@
+// Package server serves the registry endpoints computed from the local repository, for the
+// reverse proxy in front of the offline registry to route to.
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/npmoffline/internal/entities"
+)
+
+// BulkAdvisoriesPath is the endpoint queried by npm audit.
+const BulkAdvisoriesPath = "/-/npm/v1/security/advisories/bulk"
+
+// bulkAdvisory is an advisory in the response of the bulk endpoint.
+type bulkAdvisory struct {
+	ID                 uint32   `json:"id"`
+	URL                string   `json:"url"`
+	Title              string   `json:"title"`
+	Severity           string   `json:"severity"`
+	VulnerableVersions string   `json:"vulnerable_versions"`
+	CWE                []string `json:"cwe"`
+	CVSS               bulkCVSS `json:"cvss"`
+	GHSA               string   `json:"github_advisory_id,omitempty"`
+}
+
+// bulkCVSS is the CVSS score of an advisory, which the imported databases do not carry.
+type bulkCVSS struct {
+	Score        float64 `json:"score"`
+	VectorString *string `json:"vectorString"`
+}
+
+// BulkAdvisoriesHandler answers the bulk advisory requests of npm audit: the body maps package
+// names to their installed versions, possibly gzip encoded, and the response maps each package
+// to the advisories affecting at least one of its versions.
+func BulkAdvisoriesHandler(db *entities.AdvisoryDatabase) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body io.Reader = r.Body
+		if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer reader.Close()
+			body = reader
+		}
+		var request map[string][]string
+		if err := json.NewDecoder(body).Decode(&request); err != nil {
+			http.Error(w, "invalid body: expected an object of package versions", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string][]bulkAdvisory{}
+		for name, versions := range request {
+			if advisories := affecting(db, name, versions); len(advisories) > 0 {
+				response[name] = advisories
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+// affecting returns the advisories of a package affecting one of the versions. The versions
+// which are not valid semantic versions are ignored.
+func affecting(db *entities.AdvisoryDatabase, name string, versions []string) []bulkAdvisory {
+	var advisories []bulkAdvisory
+	for _, advisory := range db.ForPackage(name) {
+		for _, value := range versions {
+			version, err := entities.NewSemVer(value)
+			if err != nil || !advisory.Affects(version) {
+				continue
+			}
+			advisories = append(advisories, toBulkAdvisory(advisory))
+			break
+		}
+	}
+	return advisories
+}
+
+// toBulkAdvisory converts an advisory. npm expects a numeric identifier: it is derived from the
+// advisory identifier so that it is stable across imports.
+func toBulkAdvisory(advisory entities.Advisory) bulkAdvisory {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(advisory.ID))
+	vulnerable := advisory.Vulnerable
+	for _, version := range advisory.Versions {
+		if vulnerable == "" {
+			vulnerable = version
+		} else {
+			vulnerable += " || " + version
+		}
+	}
+	result := bulkAdvisory{
+		ID:                 hash.Sum32(),
+		URL:                advisory.URL,
+		Title:              advisory.Summary,
+		Severity:           advisory.Severity,
+		VulnerableVersions: vulnerable,
+		CWE:                advisory.CWEs,
+	}
+	if strings.HasPrefix(advisory.ID, "GHSA-") {
+		result.GHSA = advisory.ID
+	}
+	if result.CWE == nil {
+		result.CWE = []string{}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkAdvisoriesHandler(t *testing.T) {
+	handler := BulkAdvisoriesHandler(entities.NewAdvisoryDatabase([]entities.Advisory{
+		{ID: "GHSA-jf85-cpcp-j695", Package: "lodash", Summary: "Prototype Pollution", Severity: "critical",
+			Vulnerable: "<4.17.12", URL: "https://github.com/advisories/GHSA-jf85-cpcp-j695"},
+		{ID: "GHSA-2", Package: "lodash", Severity: "low", Vulnerable: ">=5.0.0"},
+	}))
+
+	t.Run("Gzip request", func(t *testing.T) {
+		var body bytes.Buffer
+		writer := gzip.NewWriter(&body)
+		_, _ = writer.Write([]byte(`{"lodash": ["4.17.11", "4.17.21"], "left-pad": ["1.3.0"]}`))
+		require.NoError(t, writer.Close())
+		request := httptest.NewRequest(http.MethodPost, BulkAdvisoriesPath, &body)
+		request.Header.Set("Content-Encoding", "gzip")
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var response map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response, 1)
+		require.Len(t, response["lodash"], 1)
+		advisory := response["lodash"][0]
+		assert.Equal(t, "Prototype Pollution", advisory["title"])
+		assert.Equal(t, "critical", advisory["severity"])
+		assert.Equal(t, "<4.17.12", advisory["vulnerable_versions"])
+		assert.Equal(t, "GHSA-jf85-cpcp-j695", advisory["github_advisory_id"])
+		assert.NotZero(t, advisory["id"])
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, BulkAdvisoriesPath, strings.NewReader("[")))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Only POST is allowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, BulkAdvisoriesPath, nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/npmoffline/internal/pkg/metrics"
+)
+
+// MetricsPath is the endpoint scraped by Prometheus.
+const MetricsPath = "/metrics"
+
+// Metrics records the requests served. The endpoints answer from data loaded in memory and have
+// no cache, so there is no cache hit counter on the server side.
+type Metrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+// NewMetrics registers the metrics of the served endpoints.
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: registry.NewCounter("npm_offline_server_requests_total",
+			"Requests served, by endpoint and status code.", "path", "code"),
+		duration: registry.NewHistogram("npm_offline_server_request_duration_seconds",
+			"Time to serve a request, by endpoint.", metrics.DefaultDurationBuckets, "path"),
+	}
+}
+
+// Instrument wraps the handler of an endpoint to count its requests and measure their duration.
+func (m *Metrics) Instrument(path string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		m.duration.Observe(time.Since(start).Seconds(), path)
+		m.requests.Inc(path, strconv.Itoa(recorder.status))
+	})
+}
+
+// statusRecorder keeps the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
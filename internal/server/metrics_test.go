@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	handler := NewMetrics(registry).Instrument(BulkAdvisoriesPath, BulkAdvisoriesHandler(entities.NewAdvisoryDatabase(nil)))
+
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		request := httptest.NewRequest(method, BulkAdvisoriesPath, strings.NewReader(`{"lodash": ["4.17.20"]}`))
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, metrics.ContentType, recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "# TYPE npm_offline_server_requests_total counter\n")
+	assert.Contains(t, body, `npm_offline_server_requests_total{path="`+BulkAdvisoriesPath+`",code="200"} 1`)
+	assert.Contains(t, body, `npm_offline_server_requests_total{path="`+BulkAdvisoriesPath+`",code="405"} 1`)
+	assert.Contains(t, body, `npm_offline_server_request_duration_seconds_bucket{path="`+BulkAdvisoriesPath+`",le="+Inf"} 2`)
+	assert.Contains(t, body, `npm_offline_server_request_duration_seconds_count{path="`+BulkAdvisoriesPath+`"} 2`)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// AuditLocalRepository matches the versions stored in the local repository against the
+// advisories, returning the affected versions sorted by package and version.
+func AuditLocalRepository(repo repositories.LocalNpmRepository, db *entities.AdvisoryDatabase) ([]entities.Vulnerability, error) {
+	names, err := repo.ListPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the packages: %w", err)
+	}
+	var vulnerabilities []entities.Vulnerability
+	for _, name := range names {
+		if len(db.ForPackage(name)) == 0 {
+			continue
+		}
+		versions, err := repo.LoadLocalVersions(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the versions of %s: %w", name, err)
+		}
+		for _, version := range versions {
+			if advisories := db.Affecting(name, version.Version); len(advisories) > 0 {
+				vulnerabilities = append(vulnerabilities, entities.Vulnerability{
+					Package:    name,
+					Version:    version.Version.String(),
+					Advisories: advisories,
+				})
+			}
+		}
+	}
+	return vulnerabilities, nil
+}
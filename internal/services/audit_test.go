@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLocalRepository(t *testing.T) {
+	db := entities.NewAdvisoryDatabase([]entities.Advisory{
+		{ID: "GHSA-1", Package: "lodash", Severity: "high", Vulnerable: "<4.17.12"},
+	})
+
+	t.Run("Affected versions are reported", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"left-pad", "lodash"}, nil).Once()
+		repo.On("LoadLocalVersions", "lodash").Return([]entities.NpmPackage{
+			{Name: "lodash", Version: entities.SemVer{Major: 4, Minor: 17, Patch: 11}},
+			{Name: "lodash", Version: entities.SemVer{Major: 4, Minor: 17, Patch: 21}},
+		}, nil).Once()
+
+		vulnerabilities, err := AuditLocalRepository(repo, db)
+
+		require.NoError(t, err)
+		require.Len(t, vulnerabilities, 1)
+		assert.Equal(t, "lodash", vulnerabilities[0].Package)
+		assert.Equal(t, "4.17.11", vulnerabilities[0].Version)
+		assert.Equal(t, "high", vulnerabilities[0].Severity())
+	})
+
+	t.Run("Failure to list the packages", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return(nil, errors.New("permission denied")).Once()
+
+		_, err := AuditLocalRepository(repo, db)
+
+		assert.EqualError(t, err, "failed to list the packages: permission denied")
+	})
+}
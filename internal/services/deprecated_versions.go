@@ -0,0 +1,29 @@
+package services
+
+import (
+	"fmt"
+)
+
+// DeprecatedPolicy tells what to do with the deprecated versions.
+type DeprecatedPolicy string
+
+const (
+	// IncludeDeprecated downloads the deprecated versions like the others.
+	IncludeDeprecated DeprecatedPolicy = "include"
+	// ExcludeDeprecated never downloads the deprecated versions.
+	ExcludeDeprecated DeprecatedPolicy = "exclude"
+	// DeprecatedIfRequired only downloads a deprecated version when a dependent needs it,
+	// i.e. when it is the version npm would install for one of the ranges required for the package.
+	DeprecatedIfRequired DeprecatedPolicy = "if-required"
+)
+
+// ParseDeprecatedPolicy parses a deprecated policy. An empty value is the include policy.
+func ParseDeprecatedPolicy(value string) (DeprecatedPolicy, error) {
+	switch policy := DeprecatedPolicy(value); policy {
+	case "":
+		return IncludeDeprecated, nil
+	case IncludeDeprecated, ExcludeDeprecated, DeprecatedIfRequired:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid deprecated policy %q: expected include, exclude or if-required", value)
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeprecatedPolicy(t *testing.T) {
+	for value, expected := range map[string]DeprecatedPolicy{
+		"":            IncludeDeprecated,
+		"include":     IncludeDeprecated,
+		"exclude":     ExcludeDeprecated,
+		"if-required": DeprecatedIfRequired,
+	} {
+		policy, err := ParseDeprecatedPolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := ParseDeprecatedPolicy("never")
+	assert.Error(t, err)
+}
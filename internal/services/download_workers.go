@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/npmoffline/internal/pkg/logger"
 	"github.com/npmoffline/internal/repositories"
 )
@@ -26,20 +28,29 @@ type tarballWorkerPool struct {
 	localNpmRepo       repositories.LocalNpmRepository
 	remoteNpmRepo      repositories.NpmRepository
 	localNpmState      entities.LocalNpmState
-	backoffFactor      time.Duration
+	backoff            httpclient.Backoff
 	maxDownloadRetries int
+	report             *entities.RunReport
+	progress           *entities.Progress
+	metrics            *Metrics
+	// dryRun only measures the tarballs, with HEAD requests, and records them in the report.
+	dryRun bool
 }
 
 // NewTarballdWorkerPool creates a new instance of DownloadWorkerFactory.
-func NewTarballdWorkerPool(logger logger.Logger, localNpmRepo repositories.LocalNpmRepository, remoteNpmRepo repositories.NpmRepository, localNpmState entities.LocalNpmState) TarballWorkerPool {
+func NewTarballdWorkerPool(logger logger.Logger, localNpmRepo repositories.LocalNpmRepository, remoteNpmRepo repositories.NpmRepository, localNpmState entities.LocalNpmState, report *entities.RunReport, progress *entities.Progress, metrics *Metrics, dryRun bool) TarballWorkerPool {
 	return &tarballWorkerPool{
 		logger:             logger,
 		wg:                 &sync.WaitGroup{},
 		localNpmRepo:       localNpmRepo,
 		remoteNpmRepo:      remoteNpmRepo,
 		localNpmState:      localNpmState,
-		backoffFactor:      time.Second,
+		backoff:            httpclient.NewBackoff(defaultBackoffBase, defaultBackoffMax),
 		maxDownloadRetries: maxTarballRetries,
+		report:             report,
+		progress:           progress,
+		metrics:            metrics,
+		dryRun:             dryRun,
 	}
 }
 
@@ -68,9 +79,12 @@ func (p *tarballWorkerPool) StartWorker(ctx context.Context, downloadChan <-chan
 				if pkg.Name == "" {
 					continue
 				}
-				if err := p.downloadTarball(ctx, pkg, id); err != nil {
+				p.progress.Start(entities.DownloadStage)
+				err := p.downloadTarball(ctx, pkg, id)
+				if err != nil {
 					p.logger.Error("[dl_#%d] Failed to download tarball for %s: %w", id, pkg.Name, err)
 				}
+				p.progress.Finish(entities.DownloadStage, err != nil)
 				// Reset the timer to avoid stopping the worker.
 				if !timer.Stop() {
 					<-timer.C
@@ -91,9 +105,18 @@ func (f *tarballWorkerPool) WaitAllWorkers() {
 
 // downloadTarball downloads the tarball for the given package.
 func (p *tarballWorkerPool) downloadTarball(ctx context.Context, pkg entities.NpmPackage, workerID int) error {
+	if p.dryRun {
+		p.measureTarball(ctx, pkg, workerID)
+		return nil
+	}
 	var lastErr error
+	attempt := 1
 
-	for attempt := 1; attempt <= p.maxDownloadRetries; attempt++ {
+	for ; attempt <= p.maxDownloadRetries; attempt++ {
+		if attempt > 1 {
+			p.progress.Retry(entities.DownloadStage)
+			p.metrics.retry(entities.DownloadStage, lastErr)
+		}
 		if p.logger.IsDebug() {
 			p.logger.Debug("[dl_#%d] Attempt %d: Downloading tarball for package %s:%s", workerID, attempt, pkg.Name, pkg.Version.String())
 		}
@@ -102,26 +125,80 @@ func (p *tarballWorkerPool) downloadTarball(ctx context.Context, pkg entities.Np
 		if err != nil {
 			p.logger.Error("[dl_#%d] Attempt %d: Failed to download tarball for %s:%s. Err:%v", workerID, attempt, pkg.Name, pkg.Version.String(), err)
 			lastErr = err
-			// Délai progressif avant la prochaine tentative
-			time.Sleep(time.Duration(attempt) * p.backoffFactor)
+			if !waitBeforeRetry(ctx, p.backoff, attempt, p.maxDownloadRetries, err) {
+				break
+			}
 			continue
 		}
 
-		err = p.localNpmRepo.WriteTarball(pkg.Name, pkg.Version.String(), pkg.Integrity, reader)
+		err = p.writeTarball(pkg, &countingReader{ReadCloser: reader, progress: p.progress, metrics: p.metrics})
 		reader.Close()
 		if err != nil {
+			p.metrics.integrityFailure(err)
 			p.logger.Error("[dl_#%d] Attempt %d: Failed to write tarball for %s:%s. Err:%v", workerID, attempt, pkg.Name, pkg.Version.String(), err)
 			lastErr = err
-			time.Sleep(time.Duration(attempt) * p.backoffFactor)
+			if !waitBeforeRetry(ctx, p.backoff, attempt, p.maxDownloadRetries, err) {
+				break
+			}
 			continue
 		}
 
 		p.localNpmState.IncrementDownloadedCount()
+		p.metrics.tarballDone(nil)
 		if p.logger.IsDebug() {
 			p.logger.Debug("[dl_#%d] Successfully downloaded tarball for package %s:%s", workerID, pkg.Name, pkg.Version.String())
 		}
 		return nil
 	}
 
-	return fmt.Errorf("failed to download tarball for package %s after %d attempts: %w", pkg.Name, p.maxDownloadRetries, lastErr)
+	if attempt > p.maxDownloadRetries {
+		attempt = p.maxDownloadRetries
+	}
+	p.metrics.tarballDone(lastErr)
+	return fmt.Errorf("failed to download tarball for package %s after %d attempts: %w", pkg.Name, attempt, lastErr)
+}
+
+// measureTarball records the tarball a dry run would download, with its Content-Length.
+// A failure to get the size is logged, and the tarball recorded with an unknown size.
+func (p *tarballWorkerPool) measureTarball(ctx context.Context, pkg entities.NpmPackage, workerID int) {
+	size, err := p.remoteNpmRepo.TarballSize(ctx, pkg.Url)
+	if err != nil {
+		p.logger.Warn("[dl_#%d] Failed to get the size of the tarball of %s:%s. Err:%v", workerID, pkg.Name, pkg.Version.String(), err)
+		size = -1
+	}
+	download := entities.PlannedDownload{Package: pkg.Name, URL: pkg.Url, Size: size, UnpackedSize: pkg.UnpackedSize}
+	if !pkg.IsRemoteTarball {
+		download.Version = pkg.Version.String()
+	}
+	p.report.AddPlannedDownload(download)
+	p.localNpmState.IncrementDownloadedCount()
+}
+
+// writeTarball stores a downloaded tarball. Registry tarballs are checked against their integrity,
+// while the integrity of remote tarballs is computed and recorded in the run report.
+func (p *tarballWorkerPool) writeTarball(pkg entities.NpmPackage, reader io.ReadCloser) error {
+	if !pkg.IsRemoteTarball {
+		return p.localNpmRepo.WriteTarball(pkg.Name, pkg.Version.String(), pkg.Integrity, reader)
+	}
+	tarball, err := p.localNpmRepo.WriteRemoteTarball(pkg.Url, reader)
+	if err != nil {
+		return err
+	}
+	p.report.AddRemoteTarball(tarball)
+	return nil
+}
+
+// countingReader records the bytes read from a tarball stream in the progress and the metrics
+// of the run.
+type countingReader struct {
+	io.ReadCloser
+	progress *entities.Progress
+	metrics  *Metrics
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.progress.AddBytes(int64(n))
+	r.metrics.addTarballBytes(n)
+	return n, err
 }
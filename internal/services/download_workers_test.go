@@ -5,16 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/npmoffline/internal/pkg/metrics"
 	"github.com/npmoffline/internal/repositories"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTarballWorkerPool_StartWorker(t *testing.T) {
@@ -30,7 +34,8 @@ func TestTarballWorkerPool_StartWorker(t *testing.T) {
 		remoteNpmRepo:      mockRemoteRepo,
 		localNpmState:      mockLocalState,
 		maxDownloadRetries: 1,
-		backoffFactor:      time.Millisecond * 10,
+		backoff:            httpclient.NewBackoff(10*time.Millisecond, 50*time.Millisecond),
+		progress:           entities.NewProgress(time.Now()),
 	}
 
 	t.Run("Worker terminates when context is cancelled", func(t *testing.T) {
@@ -145,8 +150,10 @@ func TestTarballWorkerPool_downloadTarball(t *testing.T) {
 		localNpmRepo:       mockLocalRepo,
 		remoteNpmRepo:      mockRemoteRepo,
 		localNpmState:      mockLocalState,
-		backoffFactor:      time.Millisecond * time.Duration(10),
+		backoff:            httpclient.NewBackoff(10*time.Millisecond, 50*time.Millisecond),
 		maxDownloadRetries: maxRetries,
+		report:             entities.NewRunReport(),
+		progress:           entities.NewProgress(time.Now()),
 	}
 
 	t.Run("Erreur dans DownloadTarballStream", func(t *testing.T) {
@@ -172,6 +179,7 @@ func TestTarballWorkerPool_downloadTarball(t *testing.T) {
 		// Le message d'erreur final doit mentionner "after 5 attempts" et l'erreur retournée doit être celle de téléchargement
 		assert.Contains(t, err.Error(), "after 5 attempts")
 		assert.True(t, errors.Is(err, downloadErr))
+		assert.Equal(t, int64(maxRetries-1), pool.progress.Snapshot(time.Now()).Download.Retries)
 	})
 
 	t.Run("Erreur dans WriteTarball", func(t *testing.T) {
@@ -230,4 +238,104 @@ func TestTarballWorkerPool_downloadTarball(t *testing.T) {
 		err := pool.downloadTarball(ctx, pkg, workerID)
 		assert.NoError(t, err)
 	})
+
+	t.Run("Remote tarball is stored with its computed integrity", func(t *testing.T) {
+		tarballURL := "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz"
+		remotePkg := entities.NewRemoteTarballPackage(entities.ParseDependencySpec("xlsx", tarballURL))
+		reader := io.NopCloser(strings.NewReader("tarball data"))
+		tarball := entities.RemoteTarball{URL: tarballURL, Integrity: "sha512-x", Path: "_remote/x.tgz"}
+
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockRemoteRepo.On("DownloadTarballStream", mock.Anything, tarballURL).Return(reader, nil).Once()
+		mockLocalRepo.On("WriteRemoteTarball", tarballURL, mock.Anything).Return(tarball, nil).Once()
+		mockLocalState.On("IncrementDownloadedCount").Once()
+
+		err := pool.downloadTarball(context.Background(), remotePkg, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []entities.RemoteTarball{tarball}, pool.report.RemoteTarballs())
+	})
+
+	t.Run("Dry run only measures the tarball", func(t *testing.T) {
+		dryPool := *pool
+		dryPool.dryRun = true
+		dryPool.report = entities.NewRunReport()
+		sizedPkg := pkg
+		sizedPkg.UnpackedSize = 4096
+
+		mockRemoteRepo.On("TarballSize", mock.Anything, dummyUrl).Return(int64(1024), nil).Once()
+		mockLocalState.On("IncrementDownloadedCount").Once()
+
+		err := dryPool.downloadTarball(context.Background(), sizedPkg, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []entities.PlannedDownload{{Package: packageName, Version: "1.0.0", URL: dummyUrl, Size: 1024, UnpackedSize: 4096}}, dryPool.report.PlannedDownloads())
+	})
+
+	t.Run("Dry run with an unknown tarball size", func(t *testing.T) {
+		dryPool := *pool
+		dryPool.dryRun = true
+		dryPool.report = entities.NewRunReport()
+		headErr := fmt.Errorf("head error")
+
+		mockRemoteRepo.On("TarballSize", mock.Anything, dummyUrl).Return(int64(0), headErr).Once()
+		mockLogger.On("Warn", "[dl_#%d] Failed to get the size of the tarball of %s:%s. Err:%v", workerID, packageName, dummyVersion.String(), headErr).Once()
+		mockLocalState.On("IncrementDownloadedCount").Once()
+
+		err := dryPool.downloadTarball(context.Background(), pkg, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []entities.PlannedDownload{{Package: packageName, Version: "1.0.0", URL: dummyUrl, Size: -1}}, dryPool.report.PlannedDownloads())
+	})
+}
+
+func TestTarballWorkerPool_Metrics(t *testing.T) {
+	packageName := "testpkg"
+	dummyUrl := "http://example.com/tarball"
+	version := entities.SemVer{Major: 1}
+	pkg := entities.NpmPackage{Name: packageName, Url: dummyUrl, Version: version, Integrity: "sha512-x"}
+
+	mockLogger := logger.NewMockLogger(t)
+	mockLocalRepo := repositories.NewMockLocalNpmRepository(t)
+	mockRemoteRepo := repositories.NewMockNpmRepository(t)
+	mockLocalState := entities.NewMockLocalNpmState(t)
+	registry := metrics.NewRegistry()
+
+	pool := &tarballWorkerPool{
+		logger:             mockLogger,
+		wg:                 &sync.WaitGroup{},
+		localNpmRepo:       mockLocalRepo,
+		remoteNpmRepo:      mockRemoteRepo,
+		localNpmState:      mockLocalState,
+		backoff:            httpclient.NewBackoff(time.Millisecond, time.Millisecond),
+		maxDownloadRetries: 3,
+		report:             entities.NewRunReport(),
+		progress:           entities.NewProgress(time.Now()),
+		metrics:            NewMetrics(registry),
+	}
+
+	mockLogger.On("IsDebug").Return(false)
+	mockLogger.On("Error", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	// A throttled attempt, an attempt failing the integrity check, then a successful one.
+	mockRemoteRepo.On("DownloadTarballStream", mock.Anything, dummyUrl).Return(nil, &httpclient.StatusError{StatusCode: http.StatusTooManyRequests}).Once()
+	mockRemoteRepo.On("DownloadTarballStream", mock.Anything, dummyUrl).Return(io.NopCloser(strings.NewReader("corrupted")), nil).Once()
+	mockRemoteRepo.On("DownloadTarballStream", mock.Anything, dummyUrl).Return(io.NopCloser(strings.NewReader("tarball")), nil).Once()
+	mockLocalRepo.On("WriteTarball", packageName, "1.0.0", "sha512-x", mock.Anything).
+		Run(func(args mock.Arguments) { _, _ = io.ReadAll(args.Get(3).(io.Reader)) }).
+		Return(repositories.ErrIntegrityMismatch).Once()
+	mockLocalRepo.On("WriteTarball", packageName, "1.0.0", "sha512-x", mock.Anything).
+		Run(func(args mock.Arguments) { _, _ = io.ReadAll(args.Get(3).(io.Reader)) }).
+		Return(nil).Once()
+	mockLocalState.On("IncrementDownloadedCount").Once()
+
+	err := pool.downloadTarball(context.Background(), pkg, 1)
+
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, registry.Write(&out))
+	assert.Contains(t, out.String(), `npm_offline_retries_total{stage="download",code="429"} 1`)
+	assert.Contains(t, out.String(), `npm_offline_retries_total{stage="download",code="error"} 1`)
+	assert.Contains(t, out.String(), "npm_offline_integrity_failures_total 1\n")
+	assert.Contains(t, out.String(), "npm_offline_tarball_bytes_total 16\n")
+	assert.Contains(t, out.String(), `npm_offline_tarballs_total{result="downloaded"} 1`)
 }
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// BuildLicenseReport lists the licenses of the versions stored in the local repository,
+// flagging the unknown licenses and the licenses violating the policy.
+func BuildLicenseReport(repo repositories.LocalNpmRepository, policy entities.PackagePolicy) (entities.LicenseReport, error) {
+	var report entities.LicenseReport
+	names, err := repo.ListPackages()
+	if err != nil {
+		return report, fmt.Errorf("failed to list the packages: %w", err)
+	}
+	for _, name := range names {
+		versions, err := repo.LoadLocalVersions(name)
+		if err != nil {
+			return report, fmt.Errorf("failed to load the versions of %s: %w", name, err)
+		}
+		for _, version := range versions {
+			report.Entries = append(report.Entries, entities.NewLicenseEntry(version, policy))
+		}
+	}
+	report.Sort()
+	return report, nil
+}
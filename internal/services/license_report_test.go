@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildLicenseReport(t *testing.T) {
+	policy := entities.PackagePolicy{Rules: []entities.PolicyRule{{Action: entities.PolicyDeny, Licenses: []string{"GPL-*"}, Reason: "copyleft"}}}
+	require.NoError(t, policy.Compile())
+
+	t.Run("Licenses are normalised and flagged", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a", "b"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return([]entities.NpmPackage{
+			{Name: "a", Version: entities.SemVer{Major: 1}, License: "The MIT License"},
+			{Name: "a", Version: entities.SemVer{Major: 2}, License: "GPL-2.0+"},
+		}, nil).Once()
+		repo.On("LoadLocalVersions", "b").Return([]entities.NpmPackage{
+			{Name: "b", Version: entities.SemVer{Major: 1}},
+		}, nil).Once()
+
+		report, err := BuildLicenseReport(repo, policy)
+
+		require.NoError(t, err)
+		assert.Equal(t, []entities.LicenseEntry{
+			{Package: "a", Version: "1.0.0", License: "MIT", Status: entities.LicenseAllowed},
+			{Package: "a", Version: "2.0.0", License: "GPL-2.0-or-later", Status: entities.LicenseDenied, Reason: "copyleft"},
+			{Package: "b", Version: "1.0.0", License: entities.NoAssertion, Status: entities.LicenseUnknown},
+		}, report.Entries)
+		assert.Len(t, report.Flagged(), 2)
+	})
+
+	t.Run("Failure to load a package", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(nil, errors.New("invalid package.json")).Once()
+
+		_, err := BuildLicenseReport(repo, policy)
+
+		assert.EqualError(t, err, "failed to load the versions of a: invalid package.json")
+	})
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// ListLocalPackages returns the version count and disk usage of the packages of the local
+// repository whose name matches the glob filter, or of all the packages without a filter.
+func ListLocalPackages(repo repositories.LocalNpmRepository, filter string) ([]entities.PackageSummary, error) {
+	if _, err := path.Match(filter, ""); err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", filter, err)
+	}
+	names, err := repo.ListPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the packages: %w", err)
+	}
+	summaries := []entities.PackageSummary{}
+	for _, name := range names {
+		if filter != "" {
+			if matched, _ := path.Match(filter, name); !matched {
+				continue
+			}
+		}
+		summary, err := repo.PackageUsage(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the package %s: %w", name, err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// InspectLocalPackage describes a package of the local repository, and tells whether the
+// download state tracks it.
+func InspectLocalPackage(repo repositories.LocalNpmRepository, name string) (entities.PackageInfo, error) {
+	info, err := repo.InspectPackage(name)
+	if err != nil {
+		return info, err
+	}
+	tracked, lastSync, err := repo.LoadDownloadedPackagesState()
+	if err != nil {
+		return info, fmt.Errorf("failed to load the download state: %w", err)
+	}
+	info.LastSync = lastSync
+	for _, pkg := range tracked {
+		if pkg.Name == name {
+			info.Tracked = true
+			break
+		}
+	}
+	return info, nil
+}
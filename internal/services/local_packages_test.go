@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLocalPackages(t *testing.T) {
+	t.Run("Packages are filtered by glob", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"@babel/core", "@babel/parser", "react"}, nil).Once()
+		repo.On("PackageUsage", "@babel/core").Return(entities.PackageSummary{Name: "@babel/core", Versions: 2, Size: 100}, nil).Once()
+		repo.On("PackageUsage", "@babel/parser").Return(entities.PackageSummary{Name: "@babel/parser", Versions: 1, Size: 50}, nil).Once()
+
+		packages, err := ListLocalPackages(repo, "@babel/*")
+
+		require.NoError(t, err)
+		assert.Equal(t, []entities.PackageSummary{
+			{Name: "@babel/core", Versions: 2, Size: 100},
+			{Name: "@babel/parser", Versions: 1, Size: 50},
+		}, packages)
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		_, err := ListLocalPackages(repositories.NewMockLocalNpmRepository(t), "[")
+
+		assert.ErrorContains(t, err, `invalid filter "["`)
+	})
+}
+
+func TestInspectLocalPackage(t *testing.T) {
+	lastSync := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := repositories.NewMockLocalNpmRepository(t)
+	repo.On("InspectPackage", "react").Return(entities.PackageInfo{Name: "react"}, nil).Once()
+	repo.On("LoadDownloadedPackagesState").Return([]entities.RetrievePackage{entities.NewRetrievePackage("react@next")}, lastSync, nil).Once()
+
+	info, err := InspectLocalPackage(repo, "react")
+
+	require.NoError(t, err)
+	assert.True(t, info.Tracked)
+	assert.Equal(t, lastSync, info.LastSync)
+}
@@ -2,21 +2,56 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/npmoffline/internal/pkg/logger"
 	"github.com/npmoffline/internal/repositories"
 )
 
 const maxMetadataRetries = 5
 
+// MetadataOptions contains the options of the metadata workers.
+type MetadataOptions struct {
+	// AbbreviatedMetadata resolves the dependencies from the abbreviated documents of the
+	// registry, and downloads the full packument only to store it, when the package changed
+	// since the last sync. The versions whose tarball is stored are not downloaded again.
+	AbbreviatedMetadata bool
+	// FollowOptionalDependencies also retrieves the optionalDependencies, such as the
+	// platform binaries of native modules.
+	FollowOptionalDependencies bool
+	// FollowOptionalPeerDependencies also retrieves the peer dependencies marked as optional
+	// in peerDependenciesMeta.
+	FollowOptionalPeerDependencies bool
+	// Platforms are the target platforms: versions whose os, cpu and libc fields exclude
+	// all of them are skipped. Every version is kept when empty.
+	Platforms []entities.Platform
+	// DeprecatedPolicy tells whether the deprecated versions are downloaded.
+	DeprecatedPolicy DeprecatedPolicy
+	// Selection tells which versions of each package are downloaded.
+	Selection entities.SelectionPolicies
+	// Policy blocks packages and versions: blocked versions are not downloaded and their
+	// dependencies are not followed.
+	Policy entities.PackagePolicy
+	// DryRun resolves the versions to download without writing anything to the local
+	// repository: the package.json, its cache validators and the dist-tags are not stored.
+	DryRun bool
+	// Metrics records the metrics of the run, when it is not nil.
+	Metrics *Metrics
+}
+
 // MetadataWorkerPool defines the interface for metadata retrieval workers.
 type MetadataWorkerPool interface {
 	StartWorker(ctx context.Context, analyzeChan chan entities.RetrievePackage, downloadChan chan entities.NpmPackage, workerID int, inactivityTime time.Duration)
 	WaitAllWorkers()
+	SyncedPackages() []string
 }
 
 // metadataWorkerPool is the concrete implementation of MetadataWorker.
@@ -26,20 +61,32 @@ type metadataWorkerPool struct {
 	localNpmRepo       repositories.LocalNpmRepository
 	remoteNpmRepo      repositories.NpmRepository
 	localNpmState      entities.LocalNpmState
-	backoffFactor      time.Duration
+	backoff            httpclient.Backoff
 	maxDownloadRetries int
+	options            MetadataOptions
+	report             *entities.RunReport
+	progress           *entities.Progress
+	required           *requiredVersions
+	// remoteTarballs holds the URLs of the tarball dependencies already enqueued.
+	remoteTarballs sync.Map
+	// synced holds the names of the packages whose package.json or tarballs were written.
+	synced sync.Map
 }
 
 // NewMetadataWorkerPool creates a new instance of MetadataWorker.
-func NewMetadataWorkerPool(logger logger.Logger, localNpmRepo repositories.LocalNpmRepository, remoteNpmRepo repositories.NpmRepository, localNpmState entities.LocalNpmState) MetadataWorkerPool {
+func NewMetadataWorkerPool(logger logger.Logger, localNpmRepo repositories.LocalNpmRepository, remoteNpmRepo repositories.NpmRepository, localNpmState entities.LocalNpmState, options MetadataOptions, report *entities.RunReport, progress *entities.Progress) MetadataWorkerPool {
 	return &metadataWorkerPool{
 		logger:             logger,
 		wg:                 &sync.WaitGroup{},
 		localNpmRepo:       localNpmRepo,
 		remoteNpmRepo:      remoteNpmRepo,
 		localNpmState:      localNpmState,
-		backoffFactor:      time.Second,
+		backoff:            httpclient.NewBackoff(defaultBackoffBase, defaultBackoffMax),
 		maxDownloadRetries: maxMetadataRetries,
+		options:            options,
+		report:             report,
+		progress:           progress,
+		required:           newRequiredVersions(),
 	}
 }
 
@@ -68,9 +115,12 @@ func (f *metadataWorkerPool) StartWorker(ctx context.Context, analyzeChan chan e
 				if pkg.Name == "" {
 					continue
 				}
-				if err := f.retrieveMetadata(ctx, pkg, analyzeChan, downloadChan, id); err != nil {
+				f.progress.Start(entities.MetadataStage)
+				err := f.retrieveMetadata(ctx, pkg, analyzeChan, downloadChan, id)
+				if err != nil {
 					f.logger.Error("[meta_#%d] Failed to retrieve metadata for %s: %w", id, pkg, err)
 				}
+				f.progress.Finish(entities.MetadataStage, err != nil)
 				// Reset the timer to avoid stopping the worker due to inactivity.
 				if !timer.Stop() {
 					<-timer.C
@@ -89,6 +139,18 @@ func (f *metadataWorkerPool) WaitAllWorkers() {
 	f.wg.Wait()
 }
 
+// SyncedPackages returns the names of the packages whose package.json was stored or whose
+// versions were enqueued for download.
+func (f *metadataWorkerPool) SyncedPackages() []string {
+	var names []string
+	f.synced.Range(func(name, _ any) bool {
+		names = append(names, name.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
 func (f *metadataWorkerPool) retrieveMetadata(ctx context.Context, pkg entities.RetrievePackage, analyzeChan chan entities.RetrievePackage, downloadChan chan entities.NpmPackage, workerID int) error {
 	// Avoid processing the package if already processed.
 	if !f.localNpmState.IsAnalysisNeeded(pkg) {
@@ -96,99 +158,444 @@ func (f *metadataWorkerPool) retrieveMetadata(ctx context.Context, pkg entities.
 		return nil
 	}
 
-	packages, err := f.fetchMetadata(ctx, pkg, workerID)
-	if err != nil {
+	if _, err := entities.NewPackageName(pkg.Name); err != nil {
+		return err
+	}
+
+	// A package blocked by its name is not even fetched.
+	if decision, decided := f.options.Policy.EvaluateName(pkg.Name); decided && !decision.Allowed() {
+		f.logger.Warn("[meta_#%d] Package %s is blocked by the policy: %s", workerID, pkg.Name, decision.Reason)
+		f.report.AddPolicyDecision(decision)
+		f.localNpmState.SetState(pkg, entities.AnalysedState)
+		f.localNpmState.IncrementAnalysedCount()
+		return nil
+	}
+
+	var packages []entities.NpmPackage
+	var err error
+	if f.options.AbbreviatedMetadata {
+		packages, err = f.resolveAbbreviatedMetadata(ctx, pkg, workerID)
+	} else {
+		packages, err = f.fetchMetadata(ctx, pkg, f.remoteNpmRepo.DecodeNpmPackages, workerID)
+	}
+	var unpublished *repositories.UnpublishedError
+	if errors.As(err, &unpublished) {
+		f.logger.Warn("[meta_#%d] %v", workerID, err)
+		f.report.AddUnpublished(entities.UnpublishedPackage{Name: pkg.Name, Time: unpublished.Time, Versions: unpublished.Versions})
+	} else if err != nil {
 		return err
 	}
 
+	if len(packages) > 0 {
+		f.synced.Store(pkg.Name, true)
+		f.saveDistTags(pkg, packages, workerID)
+	}
+
 	filteredPackages := f.filterPackages(packages, pkg)
+	if f.options.AbbreviatedMetadata && len(filteredPackages) > 0 && !f.localNpmState.GetLastSync(pkg).IsZero() {
+		filteredPackages = f.withoutStoredVersions(pkg, filteredPackages, workerID)
+	}
+	filteredPackages = f.selectVersions(pkg, packages, filteredPackages, workerID)
 
 	for _, pkg := range filteredPackages {
+		f.enqueueVersion(pkg, analyzeChan, downloadChan, workerID)
+	}
 
-		if f.logger.IsDebug() {
-			f.logger.Debug("[meta_#%d] Enqueueing package %s:%s for download", workerID, pkg.Name, pkg.Version.String())
-		}
+	f.logger.Debug("[meta_#%d] Processed package %s... %d versions to download", workerID, pkg.Name, len(filteredPackages))
+	f.localNpmState.SetState(pkg, entities.AnalysedState)
+	f.localNpmState.IncrementAnalysedCount()
+
+	return nil
+}
 
-		downloadChan <- pkg
+// enqueueVersion enqueues a version for download, and its dependencies for metadata retrieval.
+// Bundled dependencies are shipped inside the tarball and are never enqueued. A version blocked
+// by the policy is not enqueued, and neither are its dependencies.
+func (f *metadataWorkerPool) enqueueVersion(pkg entities.NpmPackage, analyzeChan chan entities.RetrievePackage, downloadChan chan entities.NpmPackage, workerID int) {
+	if !f.isAllowed(pkg, workerID) {
+		return
+	}
+	if f.logger.IsDebug() {
+		f.logger.Debug("[meta_#%d] Enqueueing package %s:%s for download", workerID, pkg.Name, pkg.Version.String())
+	}
+
+	f.progress.Enqueue(entities.DownloadStage)
+	downloadChan <- pkg
+	f.synced.Store(pkg.Name, true)
+
+	f.enqueueDependencies(pkg, pkg.Dependencies, entities.DependencyProd, analyzeChan, downloadChan, workerID)
+	f.enqueueDependencies(pkg, pkg.PeerDeps, entities.DependencyPeer, analyzeChan, downloadChan, workerID)
+	if f.options.FollowOptionalDependencies {
+		f.enqueueDependencies(pkg, pkg.OptionalDeps, entities.DependencyOptional, analyzeChan, downloadChan, workerID)
+	}
+	if f.options.FollowOptionalPeerDependencies {
+		f.enqueueDependencies(pkg, pkg.OptionalPeerDeps, entities.DependencyOptionalPeer, analyzeChan, downloadChan, workerID)
+	}
+	f.handleExternalDependencies(pkg, downloadChan, workerID)
+}
 
-		// Enqueue dependencies and peer dependencies for metadata retrieval.
-		for _, dep := range pkg.Dependencies {
-			depPkg := entities.NewRetrievePackage(dep)
-			if !f.localNpmState.IsAnalysisStarted(depPkg) {
-				f.localNpmState.SetState(depPkg, entities.AnalysingState)
-				analyzeChan <- depPkg
+// enqueueDependencies records the dependencies of a version in the dependency graph and
+// enqueues those whose analysis has not started yet.
+// When some versions are only downloaded if required, the ranges are recorded and the pending
+// versions of already analysed dependencies which become required are enqueued for download.
+func (f *metadataWorkerPool) enqueueDependencies(dependent entities.NpmPackage, deps []string, kind entities.DependencyKind, analyzeChan chan entities.RetrievePackage, downloadChan chan entities.NpmPackage, workerID int) {
+	ranges := dependent.DependencyRanges
+	for _, dep := range deps {
+		f.report.AddDependencyEdge(entities.DependencyEdge{
+			From:  dependent.Name + "@" + dependent.Version.String(),
+			To:    dep,
+			Range: ranges[dep],
+			Kind:  kind,
+		})
+		if f.tracksRequiredVersions() {
+			for _, version := range f.required.require(dep, ranges[dep]) {
+				f.logger.Debug("[meta_#%d] Version %s:%s is required by range %s", workerID, version.Name, version.Version.String(), ranges[dep])
+				if version.Deprecated != "" {
+					f.reportDeprecated(version, true)
+				}
+				f.enqueueVersion(version, analyzeChan, downloadChan, workerID)
 			}
 		}
-		for _, dep := range pkg.PeerDeps {
-			depPkg := entities.NewRetrievePackage(dep)
-			if !f.localNpmState.IsAnalysisStarted(depPkg) {
-				f.localNpmState.SetState(depPkg, entities.AnalysingState)
-				analyzeChan <- depPkg
-			}
+		depPkg := entities.NewRetrievePackage(dep)
+		if !f.localNpmState.IsAnalysisStarted(depPkg) {
+			f.localNpmState.SetState(depPkg, entities.AnalysingState)
+			f.progress.Enqueue(entities.MetadataStage)
+			analyzeChan <- depPkg
 		}
 	}
+}
 
-	f.logger.Debug("[meta_#%d] Processed package %s... %d versions to download", workerID, pkg.Name, len(filteredPackages))
-	f.localNpmState.SetState(pkg, entities.AnalysedState)
-	f.localNpmState.IncrementAnalysedCount()
+// isAllowed applies the package policy to a version. The blocked versions and the versions
+// decided by a rule are recorded in the run report.
+func (f *metadataWorkerPool) isAllowed(version entities.NpmPackage, workerID int) bool {
+	if f.options.Policy.IsEmpty() {
+		return true
+	}
+	decision := f.options.Policy.Evaluate(version)
+	if decision.Rule > 0 || !decision.Allowed() {
+		f.report.AddPolicyDecision(decision)
+	}
+	if !decision.Allowed() {
+		f.logger.Warn("[meta_#%d] Version %s:%s is blocked by the policy: %s", workerID, version.Name, version.Version.String(), decision.Reason)
+	}
+	return decision.Allowed()
+}
 
-	return nil
+// tracksRequiredVersions returns true if some versions are only downloaded when a dependent requires them.
+func (f *metadataWorkerPool) tracksRequiredVersions() bool {
+	return f.options.DeprecatedPolicy == DeprecatedIfRequired || f.options.Selection.HasRequiredOnly()
+}
+
+// selectVersions applies the selection policy of the package and the deprecated policy to the
+// candidate versions, and records the deprecated versions in the run report. All the versions of
+// the package are given: the selectors such as "latest N" and the ranges apply to all of them.
+// The versions which are only downloaded if required are kept by the tracker until a range needs them.
+func (f *metadataWorkerPool) selectVersions(pkg entities.RetrievePackage, all []entities.NpmPackage, candidates []entities.NpmPackage, workerID int) []entities.NpmPackage {
+	policy := f.options.Selection.For(pkg.Name)
+	excludeDeprecated := f.options.DeprecatedPolicy == ExcludeDeprecated
+
+	var eligible []entities.NpmPackage
+	for _, version := range all {
+		if f.isEligible(version, pkg) && !(excludeDeprecated && version.Deprecated != "") {
+			eligible = append(eligible, version)
+		}
+	}
+	selected := map[entities.SemVer]bool{}
+	var kept, pending, skipped []entities.NpmPackage
+	if pkg.Spec != "" {
+		// A package requested with a dist-tag or a range only needs the version it resolves to.
+		if version, ok := resolveRange(toResolvables(eligible), pkg.Spec); ok {
+			selected[version] = true
+		}
+		if f.tracksRequiredVersions() {
+			// The spec is a requirement for the versions of an earlier analysis of the package too.
+			kept = append(kept, f.required.require(pkg.Name, pkg.Spec)...)
+		}
+	} else {
+		for _, version := range policy.Select(eligible) {
+			selected[version.Version] = true
+		}
+	}
+
+	for _, candidate := range candidates {
+		switch {
+		case excludeDeprecated && candidate.Deprecated != "":
+			skipped = append(skipped, candidate)
+		case !selected[candidate.Version] && policy.RequiredOnly:
+			pending = append(pending, candidate)
+		case !selected[candidate.Version]:
+			f.logger.Debug("[meta_#%d] Version %s:%s is not selected", workerID, candidate.Name, candidate.Version.String())
+		case f.options.DeprecatedPolicy == DeprecatedIfRequired && candidate.Deprecated != "":
+			pending = append(pending, candidate)
+		default:
+			kept = append(kept, candidate)
+		}
+	}
+	if len(pending) > 0 {
+		var required []entities.NpmPackage
+		required, pending = f.required.filter(pkg.Name, all, pending)
+		kept = append(kept, required...)
+	}
+
+	for _, version := range kept {
+		if version.Deprecated != "" {
+			f.reportDeprecated(version, true)
+		}
+	}
+	for _, version := range skipped {
+		f.logger.Debug("[meta_#%d] Skipping deprecated version %s:%s", workerID, version.Name, version.Version.String())
+		f.reportDeprecated(version, false)
+	}
+	for _, version := range pending {
+		f.logger.Debug("[meta_#%d] Version %s:%s is only downloaded if required", workerID, version.Name, version.Version.String())
+		if version.Deprecated != "" {
+			f.reportDeprecated(version, false)
+		}
+	}
+	return kept
+}
+
+// reportDeprecated records a deprecated version in the run report.
+func (f *metadataWorkerPool) reportDeprecated(version entities.NpmPackage, downloaded bool) {
+	f.report.AddDeprecated(entities.DeprecatedVersion{
+		Package:    version.Name,
+		Version:    version.Version.String(),
+		Message:    version.Deprecated,
+		Downloaded: downloaded,
+	})
+}
+
+// handleExternalDependencies enqueues the tarball dependencies for download, and reports the
+// git and file dependencies which cannot be mirrored.
+func (f *metadataWorkerPool) handleExternalDependencies(pkg entities.NpmPackage, downloadChan chan entities.NpmPackage, workerID int) {
+	dependent := pkg.Name + "@" + pkg.Version.String()
+	for _, spec := range pkg.ExternalDeps {
+		if spec.Kind == entities.DependencyOptional && !f.options.FollowOptionalDependencies ||
+			spec.Kind == entities.DependencyOptionalPeer && !f.options.FollowOptionalPeerDependencies {
+			continue
+		}
+		if spec.Type != entities.TarballDependency {
+			f.logger.Warn("[meta_#%d] Dependency %s of %s cannot be mirrored: %s", workerID, spec.Name, dependent, spec.Spec)
+			f.report.AddUnmirrorable(dependent, spec)
+			continue
+		}
+		// Tarballs have no metadata: only the rules on the name apply to them.
+		if decision, decided := f.options.Policy.EvaluateName(spec.Name); decided && !decision.Allowed() {
+			f.logger.Warn("[meta_#%d] Tarball %s of %s is blocked by the policy: %s", workerID, spec.Spec, dependent, decision.Reason)
+			f.report.AddPolicyDecision(decision)
+			continue
+		}
+		if _, seen := f.remoteTarballs.LoadOrStore(spec.Spec, true); seen {
+			continue
+		}
+		f.logger.Debug("[meta_#%d] Enqueueing tarball %s of %s for download", workerID, spec.Spec, dependent)
+		f.progress.Enqueue(entities.DownloadStage)
+		downloadChan <- entities.NewRemoteTarballPackage(spec)
+	}
 }
 
-// fetchMetadata retrieves the metadata for the given package.
-func (f *metadataWorkerPool) fetchMetadata(ctx context.Context, pkg entities.RetrievePackage, workerID int) ([]entities.NpmPackage, error) {
+// fetchMetadata retrieves the full packument of the given package, stores it in the local
+// repository and returns the versions read from it by decode.
+func (f *metadataWorkerPool) fetchMetadata(ctx context.Context, pkg entities.RetrievePackage, decode func(io.Reader) ([]entities.NpmPackage, error), workerID int) ([]entities.NpmPackage, error) {
 	var lastErr error
+	validators := f.loadCacheValidators(pkg, workerID)
 
 	for attempt := 1; attempt <= f.maxDownloadRetries; attempt++ {
+		if attempt > 1 {
+			f.progress.Retry(entities.MetadataStage)
+			f.options.Metrics.retry(entities.MetadataStage, lastErr)
+		}
 		if f.logger.IsDebug() {
 			f.logger.Debug("[meta_#%d] Attempt %d: Fetching metadata for package %s", workerID, attempt, pkg.Name)
 		}
 
-		reader, err := f.remoteNpmRepo.FetchMetadata(ctx, pkg.Name)
+		start := time.Now()
+		reader, newValidators, err := f.remoteNpmRepo.FetchMetadata(ctx, pkg.Name, repositories.FullMetadata, validators)
+		f.options.Metrics.observeMetadataFetch(time.Since(start))
+		if errors.Is(err, repositories.ErrNotModified) {
+			f.options.Metrics.metadataCacheHit()
+			// Nothing changed since the last sync: there is no new version.
+			f.logger.Debug("[meta_#%d] Metadata of %s not modified since last sync", workerID, pkg.Name)
+			return nil, nil
+		}
 		if err != nil {
 			f.logger.Error("[meta_#%d] Attempt %d: Failed to fetch metadata for %s. Err: %v", workerID, attempt, pkg.Name, err)
 			lastErr = err
-			// Progressive delay before the next
-			time.Sleep(time.Duration(attempt) * f.backoffFactor)
+			if !waitBeforeRetry(ctx, f.backoff, attempt, f.maxDownloadRetries, err) {
+				break
+			}
 			continue
 		}
 
-		teeReader, err := f.localNpmRepo.WritePackageJSON(pkg.Name, reader)
-		if err != nil {
-			reader.Close()
-			f.logger.Error("[meta_#%d] Attempt %d: Failed to write package.json for %s. Err: %v", workerID, attempt, pkg.Name, err)
-			lastErr = err
-			time.Sleep(time.Duration(attempt) * f.backoffFactor)
-			continue
+		teeReader := reader
+		if !f.options.DryRun {
+			teeReader, err = f.localNpmRepo.WritePackageJSON(pkg.Name, reader)
+			if err != nil {
+				reader.Close()
+				f.logger.Error("[meta_#%d] Attempt %d: Failed to write package.json for %s. Err: %v", workerID, attempt, pkg.Name, err)
+				lastErr = err
+				if !waitBeforeRetry(ctx, f.backoff, attempt, f.maxDownloadRetries, err) {
+					break
+				}
+				continue
+			}
 		}
 
-		packages, err := f.remoteNpmRepo.DecodeNpmPackages(teeReader)
+		packages, err := decode(teeReader)
 		teeReader.Close()
 		reader.Close()
+		var unpublished *repositories.UnpublishedError
+		if errors.As(err, &unpublished) {
+			// Retrying will not bring the versions back.
+			f.saveCacheValidators(pkg, newValidators, workerID)
+			return nil, err
+		}
 		if err != nil {
 			f.logger.Error("[meta_#%d] Attempt %d: Failed to decode npm packages for %s. Err: %v", workerID, attempt, pkg.Name, err)
+			// The stored package.json is incomplete: its validators must not be sent again.
+			f.saveCacheValidators(pkg, entities.CacheValidators{}, workerID)
 			lastErr = err
-			time.Sleep(time.Duration(attempt) * f.backoffFactor)
+			if !waitBeforeRetry(ctx, f.backoff, attempt, f.maxDownloadRetries, err) {
+				break
+			}
 			continue
 		}
 
+		f.saveCacheValidators(pkg, newValidators, workerID)
 		return packages, nil
 	}
 
-	return nil, fmt.Errorf("failed to fetch metadata for %s. Err: %v", pkg.Name, lastErr)
+	return nil, fmt.Errorf("failed to fetch metadata for %s. Err: %w", pkg.Name, lastErr)
+}
+
+// resolveAbbreviatedMetadata returns the versions of a package read from its abbreviated document,
+// which holds everything the resolution of the dependencies needs. The full packument is only
+// downloaded to be stored in the local repository, when the package changed since the last sync.
+// An already synced package is requested with the date of its stored packument: when it did not
+// change, no version is returned, so neither its dist-tags nor the dependencies of its versions
+// are visited again. The full packument is decoded instead when the abbreviated document cannot
+// be read, and when the policy matches licenses, maintainers or install scripts, which only the
+// full packument holds.
+func (f *metadataWorkerPool) resolveAbbreviatedMetadata(ctx context.Context, pkg entities.RetrievePackage, workerID int) ([]entities.NpmPackage, error) {
+	// The stored ETag is the one of the full packument, and the registry ignores the date when an
+	// ETag is sent: only the date of the last change is sent.
+	validators := entities.CacheValidators{LastModified: f.loadCacheValidators(pkg, workerID).LastModified}
+	reader, _, err := f.remoteNpmRepo.FetchMetadata(ctx, pkg.Name, repositories.AbbreviatedMetadata, validators)
+	if errors.Is(err, repositories.ErrNotModified) {
+		f.options.Metrics.metadataCacheHit()
+		f.logger.Debug("[meta_#%d] No new version of %s since last sync", workerID, pkg.Name)
+		return nil, nil
+	}
+	var packages []entities.NpmPackage
+	if err == nil {
+		packages, err = f.remoteNpmRepo.DecodeNpmPackages(reader)
+		reader.Close()
+	}
+	var unpublished *repositories.UnpublishedError
+	if errors.As(err, &unpublished) {
+		return nil, err
+	}
+	if err != nil {
+		f.logger.Debug("[meta_#%d] Failed to read abbreviated metadata for %s, using the full packument. Err: %v", workerID, pkg.Name, err)
+		return f.fetchMetadata(ctx, pkg, f.remoteNpmRepo.DecodeNpmPackages, workerID)
+	}
+
+	// Abbreviated documents carry the modification date of the package as release date,
+	// so any change since the last sync keeps all the versions.
+	if len(f.filterPackages(packages, pkg)) == 0 {
+		f.logger.Debug("[meta_#%d] No new version of %s since last sync", workerID, pkg.Name)
+		return nil, nil
+	}
+	if f.options.Policy.NeedsFullMetadata() {
+		return f.fetchMetadata(ctx, pkg, f.remoteNpmRepo.DecodeNpmPackages, workerID)
+	}
+	if !f.options.DryRun {
+		if _, err := f.fetchMetadata(ctx, pkg, discardMetadata, workerID); err != nil {
+			return nil, err
+		}
+	}
+	return packages, nil
+}
+
+// discardMetadata reads the packument to the end without decoding it, so that it is stored whole.
+func discardMetadata(reader io.Reader) ([]entities.NpmPackage, error) {
+	_, err := io.Copy(io.Discard, reader)
+	return nil, err
+}
+
+// withoutStoredVersions removes the versions whose tarball is already stored. The abbreviated
+// documents have no release date per version, so the versions released before the last sync
+// cannot be told apart from the new ones by their date.
+func (f *metadataWorkerPool) withoutStoredVersions(pkg entities.RetrievePackage, packages []entities.NpmPackage, workerID int) []entities.NpmPackage {
+	stored, err := f.localNpmRepo.LoadTarballVersions(pkg.Name)
+	if err != nil {
+		f.logger.Warn("[meta_#%d] Failed to load the stored versions of %s. Err: %v", workerID, pkg.Name, err)
+		return packages
+	}
+	var filtered []entities.NpmPackage
+	for _, version := range packages {
+		if !slices.Contains(stored, version.Version) {
+			filtered = append(filtered, version)
+		}
+	}
+	return filtered
 }
 
-// filterPackages filtre pre-release versions and versions that are less than the last version.
+// saveDistTags saves the dist-tags of the registry, which are rewritten to point to local versions
+// at the end of the run.
+func (f *metadataWorkerPool) saveDistTags(pkg entities.RetrievePackage, packages []entities.NpmPackage, workerID int) {
+	tags := entities.NewDistTags(packages)
+	if len(tags) == 0 || f.options.DryRun {
+		return
+	}
+	if err := f.localNpmRepo.SaveDistTags(pkg.Name, tags); err != nil {
+		f.logger.Warn("[meta_#%d] Failed to save dist-tags for %s. Err: %v", workerID, pkg.Name, err)
+	}
+}
+
+// loadCacheValidators returns the validators of the stored package.json.
+// They are only used for packages already synced: a new package needs all its versions.
+func (f *metadataWorkerPool) loadCacheValidators(pkg entities.RetrievePackage, workerID int) entities.CacheValidators {
+	if f.localNpmState.GetLastSync(pkg).IsZero() {
+		return entities.CacheValidators{}
+	}
+	validators, err := f.localNpmRepo.LoadCacheValidators(pkg.Name)
+	if err != nil {
+		f.logger.Warn("[meta_#%d] Failed to load cache validators for %s. Err: %v", workerID, pkg.Name, err)
+		return entities.CacheValidators{}
+	}
+	return validators
+}
+
+// saveCacheValidators stores the validators of the package.json, logging failures.
+func (f *metadataWorkerPool) saveCacheValidators(pkg entities.RetrievePackage, validators entities.CacheValidators, workerID int) {
+	if f.options.DryRun {
+		return
+	}
+	if err := f.localNpmRepo.SaveCacheValidators(pkg.Name, validators); err != nil {
+		f.logger.Warn("[meta_#%d] Failed to save cache validators for %s. Err: %v", workerID, pkg.Name, err)
+	}
+}
+
+// filterPackages filtre pre-release versions, versions that are less than the last version
+// and versions which cannot be installed on the target platforms.
 func (f *metadataWorkerPool) filterPackages(npmPackages []entities.NpmPackage, retrievePkg entities.RetrievePackage) []entities.NpmPackage {
 	lastSyncDate := f.localNpmState.GetLastSync(retrievePkg)
 	var filtered []entities.NpmPackage
 	for _, npmPkg := range npmPackages {
-		if npmPkg.Version.IsPreRelease() && !retrievePkg.IsMatchingPreRelease(npmPkg.Version.PreRelease) {
-			continue // Exclude pre-release versions
-		}
-		if npmPkg.ReleaseDate.After(lastSyncDate) {
+		if f.isEligible(npmPkg, retrievePkg) && npmPkg.ReleaseDate.After(lastSyncDate) {
 			filtered = append(filtered, npmPkg)
 		}
 	}
 	return filtered
 }
+
+// isEligible excludes the pre-release versions and the binaries of other platforms.
+// The version pointed to by the requested dist-tag is eligible even if it is a pre-release.
+func (f *metadataWorkerPool) isEligible(npmPkg entities.NpmPackage, retrievePkg entities.RetrievePackage) bool {
+	isRequestedTag := retrievePkg.Spec != "" && slices.Contains(npmPkg.DistTags, retrievePkg.Spec)
+	if npmPkg.Version.IsPreRelease() && !retrievePkg.IsMatchingPreRelease(npmPkg.Version.PreRelease) && !isRequestedTag {
+		return false
+	}
+	return npmPkg.Platform.IsInstallableOn(f.options.Platforms)
+}
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
 	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/npmoffline/internal/pkg/metrics"
 	"github.com/npmoffline/internal/repositories"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,6 +34,7 @@ func TestMetadataWorkerPool_StartWorker(t *testing.T) {
 		localNpmRepo:  mockLocalRepo,
 		remoteNpmRepo: mockRemoteRepo,
 		localNpmState: mockLocalState,
+		progress:      entities.NewProgress(time.Now()),
 	}
 
 	t.Run("Worker quit when context is cancelled", func(t *testing.T) {
@@ -105,6 +108,7 @@ func TestMetadataWorkerPool_StartWorker(t *testing.T) {
 		mockLogger.On("Error", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Times(1)
 
 		mockLocalState.On("IsAnalysisNeeded", mock.Anything).Return(true).Once()
+		mockLocalState.On("GetLastSync", mock.Anything).Return(time.Time{}).Once()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 		defer cancel()
@@ -180,7 +184,9 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 		remoteNpmRepo:      mockRemoteRepo,
 		localNpmState:      mockLocalState,
 		maxDownloadRetries: 1,
-		backoffFactor:      time.Millisecond * 10,
+		backoff:            httpclient.NewBackoff(10*time.Millisecond, 50*time.Millisecond),
+		report:             entities.NewRunReport(),
+		progress:           entities.NewProgress(time.Now()),
 	}
 
 	t.Run("Skip processing if already processed", func(t *testing.T) {
@@ -208,7 +214,8 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 		mockLogger.On("Debug", "[meta_#%d] Attempt %d: Fetching metadata for package %s", workerID, 1, packageName).Once()
 
 		fetchErr := fmt.Errorf("fetch error")
-		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName).Return(nil, fetchErr).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(nil, entities.CacheValidators{}, fetchErr).Once()
 		mockLogger.On("Error", "[meta_#%d] Attempt %d: Failed to fetch metadata for %s. Err: %v", workerID, 1, packageName, fetchErr).Once()
 
 		ctx := context.Background()
@@ -232,7 +239,8 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 
 		dummyData := "dummy metadata"
 		reader := io.NopCloser(strings.NewReader(dummyData))
-		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName).Return(reader, nil).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
 
 		writeErr := fmt.Errorf("write error")
 		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(nil, writeErr).Once()
@@ -259,10 +267,12 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 
 		dummyData := "dummy metadata"
 		reader := io.NopCloser(strings.NewReader(dummyData))
-		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName).Return(reader, nil).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
 
 		teeReader := io.NopCloser(strings.NewReader(dummyData))
 		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
 
 		decodeErr := fmt.Errorf("decode error")
 		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return(nil, decodeErr).Once()
@@ -281,18 +291,361 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 		mockRemoteRepo.AssertCalled(t, "DecodeNpmPackages", teeReader)
 	})
 
+	t.Run("Dry run does not write the package.json", func(t *testing.T) {
+		pool.options.DryRun = true
+		defer func() { pool.options.DryRun = false }()
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLogger.On("IsDebug").Return(false).Once()
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+
+		decodeErr := fmt.Errorf("decode error")
+		mockRemoteRepo.On("DecodeNpmPackages", reader).Return(nil, decodeErr).Once()
+		mockLogger.On("Error", "[meta_#%d] Attempt %d: Failed to decode npm packages for %s. Err: %v", workerID, 1, packageName, decodeErr).Once()
+
+		ctx := context.Background()
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(ctx, testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.Error(t, err)
+		mockRemoteRepo.AssertCalled(t, "DecodeNpmPackages", reader)
+	})
+
+	t.Run("Unpublished package is reported without retry", func(t *testing.T) {
+		pool.maxDownloadRetries = 3
+		defer func() { pool.maxDownloadRetries = 1 }()
+		mockLogger.On("IsDebug").Return(false).Once()
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("stub"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("stub"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		unpublishedErr := &repositories.UnpublishedError{Name: packageName, Time: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), Versions: []string{"1.0.0"}}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return(nil, unpublishedErr).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		mockLogger.On("Warn", "[meta_#%d] %v", workerID, unpublishedErr).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 0).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		err := pool.retrieveMetadata(context.Background(), testpkg, make(chan entities.RetrievePackage, 1), make(chan entities.NpmPackage, 1), workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []entities.UnpublishedPackage{{Name: packageName, Time: unpublishedErr.Time, Versions: []string{"1.0.0"}}}, pool.report.Unpublished())
+	})
+
+	t.Run("Deprecated versions are excluded by policy", func(t *testing.T) {
+		pool.options = MetadataOptions{DeprecatedPolicy: ExcludeDeprecated}
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		current := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 5}, ReleaseDate: time.Now()}
+		deprecated := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 4}, ReleaseDate: time.Now(), Deprecated: "use 5.x"}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{current, deprecated}, nil).Once()
+
+		mockLogger.On("Debug", "[meta_#%d] Skipping deprecated version %s:%s", workerID, packageName, "4.0.0").Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 1).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		downloadChan := make(chan entities.NpmPackage, 10)
+		err := pool.retrieveMetadata(context.Background(), testpkg, make(chan entities.RetrievePackage, 10), downloadChan, workerID)
+
+		assert.NoError(t, err)
+		close(downloadChan)
+		var downloaded []entities.NpmPackage
+		for pkg := range downloadChan {
+			downloaded = append(downloaded, pkg)
+		}
+		assert.Equal(t, []entities.NpmPackage{current}, downloaded)
+		assert.Contains(t, pool.report.Deprecated(), entities.DeprecatedVersion{Package: packageName, Version: "4.0.0", Message: "use 5.x", Downloaded: false})
+	})
+
+	t.Run("Versions not selected by the policy of the package are skipped", func(t *testing.T) {
+		pool.options = MetadataOptions{Selection: entities.SelectionPolicies{
+			Default:  entities.SelectionPolicy{LatestPerMajor: true},
+			Packages: map[string]entities.SelectionPolicy{packageName: {Latest: 1}},
+		}}
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		latest := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 5, Minor: 1}, ReleaseDate: time.Now()}
+		older := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 5}, ReleaseDate: time.Now()}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{older, latest}, nil).Once()
+
+		mockLogger.On("Debug", "[meta_#%d] Version %s:%s is not selected", workerID, packageName, "5.0.0").Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 1).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		downloadChan := make(chan entities.NpmPackage, 10)
+		err := pool.retrieveMetadata(context.Background(), testpkg, make(chan entities.RetrievePackage, 10), downloadChan, workerID)
+
+		assert.NoError(t, err)
+		require.Len(t, downloadChan, 1)
+		assert.Equal(t, latest, <-downloadChan)
+	})
+
+	t.Run("Package requested with a dist-tag only downloads the tagged version", func(t *testing.T) {
+		taggedPkg := entities.NewRetrievePackage(packageName + "@next")
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockLocalState.On("IsAnalysisNeeded", taggedPkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", taggedPkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		latest := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 5}, ReleaseDate: time.Now(), DistTags: []string{"latest"}}
+		next := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 6, PreRelease: "rc.1"}, ReleaseDate: time.Now(), DistTags: []string{"next"}}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{latest, next}, nil).Once()
+		mockLocalRepo.On("SaveDistTags", packageName, entities.DistTags{"latest": "5.0.0", "next": "6.0.0-rc.1"}).Return(nil).Once()
+
+		mockLogger.On("Debug", "[meta_#%d] Version %s:%s is not selected", workerID, packageName, "5.0.0").Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 1).Once()
+		mockLocalState.On("SetState", taggedPkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		downloadChan := make(chan entities.NpmPackage, 10)
+		err := pool.retrieveMetadata(context.Background(), taggedPkg, make(chan entities.RetrievePackage, 10), downloadChan, workerID)
+
+		assert.NoError(t, err)
+		require.Len(t, downloadChan, 1)
+		assert.Equal(t, next, <-downloadChan)
+		assert.Contains(t, pool.SyncedPackages(), packageName)
+	})
+
+	t.Run("Package blocked by its name is not fetched", func(t *testing.T) {
+		policy := entities.PackagePolicy{Rules: []entities.PolicyRule{{Action: entities.PolicyDeny, Name: "test*", Reason: "malicious"}}}
+		require.NoError(t, policy.Compile())
+		pool.options = MetadataOptions{Policy: policy}
+		pool.report = entities.NewRunReport()
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLogger.On("Warn", "[meta_#%d] Package %s is blocked by the policy: %s", workerID, packageName, "malicious").Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		downloadChan := make(chan entities.NpmPackage, 10)
+		err := pool.retrieveMetadata(context.Background(), testpkg, make(chan entities.RetrievePackage, 10), downloadChan, workerID)
+
+		assert.NoError(t, err)
+		assert.Empty(t, downloadChan)
+		assert.Equal(t, []entities.PolicyDecision{{Package: packageName, Action: entities.PolicyDeny, Rule: 1, Reason: "malicious"}}, pool.report.PolicyDecisions())
+	})
+
+	t.Run("Versions blocked by the policy are not downloaded and their dependencies are not followed", func(t *testing.T) {
+		policy := entities.PackagePolicy{Rules: []entities.PolicyRule{{Action: entities.PolicyDeny, Licenses: []string{"GPL-*"}}}}
+		require.NoError(t, policy.Compile())
+		pool.options = MetadataOptions{Policy: policy}
+		pool.report = entities.NewRunReport()
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		allowed := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 1}, License: "MIT OR GPL-3.0-only", Dependencies: []string{"dep1"}, ReleaseDate: time.Now()}
+		blocked := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 2}, License: "GPL-3.0-only", Dependencies: []string{"dep2"}, ReleaseDate: time.Now()}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{allowed, blocked}, nil).Once()
+
+		mockLogger.On("Warn", "[meta_#%d] Version %s:%s is blocked by the policy: %s", workerID, packageName, "2.0.0", "licenses GPL-*").Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 2).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+		dep1 := entities.NewRetrievePackage("dep1")
+		mockLocalState.On("IsAnalysisStarted", dep1).Return(false).Once()
+		mockLocalState.On("SetState", dep1, entities.AnalysingState).Once()
+
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+		err := pool.retrieveMetadata(context.Background(), testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		require.Len(t, downloadChan, 1)
+		assert.Equal(t, allowed, <-downloadChan)
+		require.Len(t, analyzeChan, 1)
+		assert.Equal(t, dep1, <-analyzeChan)
+		assert.Equal(t, []entities.PolicyDecision{
+			{Package: packageName, Version: "2.0.0", Action: entities.PolicyDeny, Rule: 1, Reason: "licenses GPL-*"},
+		}, pool.report.PolicyDecisions())
+	})
+
+	t.Run("Metadata not modified since last sync", func(t *testing.T) {
+		pool.maxDownloadRetries = 1
+		registry := metrics.NewRegistry()
+		pool.options.Metrics = NewMetrics(registry)
+		defer func() { pool.options.Metrics = nil }()
+		lastSync := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+		validators := entities.CacheValidators{ETag: `"abc"`}
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(lastSync).Once()
+		mockLocalRepo.On("LoadCacheValidators", packageName).Return(validators, nil).Once()
+		mockLogger.On("IsDebug").Return(false).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, validators).Return(nil, validators, repositories.ErrNotModified).Once()
+		mockLogger.On("Debug", "[meta_#%d] Metadata of %s not modified since last sync", workerID, packageName).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(lastSync).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 0).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		ctx := context.Background()
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(ctx, testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(downloadChan))
+		assert.Equal(t, 0, len(analyzeChan))
+		var out strings.Builder
+		require.NoError(t, registry.Write(&out))
+		assert.Contains(t, out.String(), "npm_offline_metadata_cache_hits_total 1\n")
+		assert.Contains(t, out.String(), "npm_offline_metadata_fetch_duration_seconds_count 1\n")
+	})
+
+	t.Run("Abbreviated metadata without new version", func(t *testing.T) {
+		pool.maxDownloadRetries = 1
+		pool.options = MetadataOptions{AbbreviatedMetadata: true}
+		defer func() { pool.options = MetadataOptions{} }()
+		lastSync := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(lastSync).Times(3)
+		mockLocalRepo.On("LoadCacheValidators", packageName).Return(entities.CacheValidators{ETag: `"full"`, LastModified: "Mon, 03 Mar 2025 00:00:00 GMT"}, nil).Once()
+		reader := io.NopCloser(strings.NewReader("abbreviated metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.AbbreviatedMetadata, entities.CacheValidators{LastModified: "Mon, 03 Mar 2025 00:00:00 GMT"}).Return(reader, entities.CacheValidators{}, nil).Once()
+		oldPkg := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 1}, ReleaseDate: lastSync.Add(-time.Hour)}
+		mockRemoteRepo.On("DecodeNpmPackages", reader).Return([]entities.NpmPackage{oldPkg}, nil).Once()
+		mockLogger.On("Debug", "[meta_#%d] No new version of %s since last sync", workerID, packageName).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 0).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		ctx := context.Background()
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(ctx, testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(downloadChan))
+	})
+
+	t.Run("Abbreviated metadata not modified: the dist-tags and dependencies are not visited again", func(t *testing.T) {
+		pool.maxDownloadRetries = 1
+		pool.options = MetadataOptions{AbbreviatedMetadata: true}
+		defer func() { pool.options = MetadataOptions{} }()
+		lastSync := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(lastSync).Times(2)
+		mockLocalRepo.On("LoadCacheValidators", packageName).Return(entities.CacheValidators{LastModified: "Mon, 03 Mar 2025 00:00:00 GMT"}, nil).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.AbbreviatedMetadata, entities.CacheValidators{LastModified: "Mon, 03 Mar 2025 00:00:00 GMT"}).Return(nil, entities.CacheValidators{}, repositories.ErrNotModified).Once()
+		mockLogger.On("Debug", "[meta_#%d] No new version of %s since last sync", workerID, packageName).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 0).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		ctx := context.Background()
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(ctx, testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(downloadChan))
+		assert.Equal(t, 0, len(analyzeChan))
+	})
+
+	t.Run("Abbreviated metadata resolves the dependencies and the full packument is only stored", func(t *testing.T) {
+		pool.maxDownloadRetries = 1
+		pool.options = MetadataOptions{AbbreviatedMetadata: true}
+		defer func() { pool.options = MetadataOptions{} }()
+		lastSync := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+		validators := entities.CacheValidators{ETag: `"full"`, LastModified: "Mon, 03 Mar 2025 00:00:00 GMT"}
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(lastSync).Times(5)
+		mockLocalRepo.On("LoadCacheValidators", packageName).Return(validators, nil).Times(2)
+		abbreviated := io.NopCloser(strings.NewReader("abbreviated metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.AbbreviatedMetadata, entities.CacheValidators{LastModified: validators.LastModified}).Return(abbreviated, entities.CacheValidators{}, nil).Once()
+		stored := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 1}, ReleaseDate: lastSync.Add(time.Hour)}
+		released := entities.NpmPackage{Name: packageName, Version: entities.SemVer{Major: 2}, Dependencies: []string{"dep1"}, ReleaseDate: lastSync.Add(time.Hour)}
+		mockRemoteRepo.On("DecodeNpmPackages", abbreviated).Return([]entities.NpmPackage{stored, released}, nil).Once()
+
+		// The full packument is written to the local repository without being decoded.
+		full := io.NopCloser(strings.NewReader("full metadata"))
+		mockLogger.On("IsDebug").Return(false).Times(2)
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, validators).Return(full, entities.CacheValidators{ETag: `"new"`}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("full metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, full).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{ETag: `"new"`}).Return(nil).Once()
+
+		mockLocalRepo.On("LoadTarballVersions", packageName).Return([]entities.SemVer{stored.Version}, nil).Once()
+		dep1 := entities.NewRetrievePackage("dep1")
+		mockLocalState.On("IsAnalysisStarted", dep1).Return(false).Once()
+		mockLocalState.On("SetState", dep1, entities.AnalysingState).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 1).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(context.Background(), testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		require.Len(t, downloadChan, 1)
+		assert.Equal(t, released, <-downloadChan)
+		require.Len(t, analyzeChan, 1)
+		assert.Equal(t, dep1, <-analyzeChan)
+	})
+
 	t.Run("Successful processing without dependencies", func(t *testing.T) {
 		pool.maxDownloadRetries = 1
 		mockLogger.On("IsDebug").Return(true).Times(2)
 		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
-		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
 
 		dummyData := "dummy metadata"
 		reader := io.NopCloser(strings.NewReader(dummyData))
-		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName).Return(reader, nil).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
 
 		teeReader := io.NopCloser(strings.NewReader(dummyData))
 		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
 
 		pkg := entities.NpmPackage{
 			Name:         packageName,
@@ -336,21 +689,23 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 		mockLogger.On("IsDebug").Return(true).Times(2)
 
 		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
-		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
 
 		dummyData := "dummy metadata"
 		reader := io.NopCloser(strings.NewReader(dummyData))
-		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName).Return(reader, nil).Once()
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
 
 		teeReader := io.NopCloser(strings.NewReader(dummyData))
 		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
 
 		pkg := entities.NpmPackage{
-			Name:         packageName,
-			Version:      entities.SemVer{Major: 2, Minor: 0, Patch: 0},
-			Dependencies: []string{"dep1"},
-			PeerDeps:     []string{"peer1"},
-			ReleaseDate:  time.Now(),
+			Name:             packageName,
+			Version:          entities.SemVer{Major: 2, Minor: 0, Patch: 0},
+			Dependencies:     []string{"dep1"},
+			PeerDeps:         []string{"peer1"},
+			DependencyRanges: map[string]string{"dep1": "^1.0.0", "peer1": "*"},
+			ReleaseDate:      time.Now(),
 		}
 		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{pkg}, nil).Once()
 
@@ -393,7 +748,162 @@ func TestMetadataWorkerPool_RetrieveMetadata(t *testing.T) {
 		}
 		assert.Contains(t, deps, dep1)
 		assert.Contains(t, deps, peer1)
+		assert.Subset(t, pool.report.DependencyGraph().Edges, []entities.DependencyEdge{
+			{From: packageName + "@2.0.0", To: "dep1", Range: "^1.0.0", Kind: entities.DependencyProd},
+			{From: packageName + "@2.0.0", To: "peer1", Range: "*", Kind: entities.DependencyPeer},
+		})
 	})
+
+	t.Run("Optional dependencies are only followed when enabled", func(t *testing.T) {
+		pool.options = MetadataOptions{FollowOptionalDependencies: true}
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLogger.On("IsDebug").Return(true).Times(2)
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		pkg := entities.NpmPackage{
+			Name:             packageName,
+			Version:          entities.SemVer{Major: 3, Minor: 0, Patch: 0},
+			OptionalDeps:     []string{"optional1"},
+			OptionalPeerDeps: []string{"optionalPeer1"},
+			BundledDeps:      []string{"bundled1"},
+			ReleaseDate:      time.Now(),
+		}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{pkg}, nil).Once()
+
+		mockLogger.On("Debug", "[meta_#%d] Attempt %d: Fetching metadata for package %s", workerID, 1, packageName).Once()
+		mockLogger.On("Debug", "[meta_#%d] Enqueueing package %s:%s for download", workerID, pkg.Name, pkg.Version.String()).Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 1).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		optional1 := entities.NewRetrievePackage("optional1")
+		mockLocalState.On("IsAnalysisStarted", optional1).Return(false).Once()
+		mockLocalState.On("SetState", optional1, entities.AnalysingState).Once()
+
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(context.Background(), testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		close(analyzeChan)
+		var deps []entities.RetrievePackage
+		for dep := range analyzeChan {
+			deps = append(deps, dep)
+		}
+		// Optional peers are disabled and bundled dependencies are never enqueued.
+		assert.Equal(t, []entities.RetrievePackage{optional1}, deps)
+	})
+
+	t.Run("External dependencies are downloaded or reported", func(t *testing.T) {
+		mockLogger.On("IsDebug").Return(false).Times(3)
+
+		mockLocalState.On("IsAnalysisNeeded", testpkg).Return(true).Once()
+		mockLocalState.On("GetLastSync", testpkg).Return(time.Time{}).Times(2)
+
+		reader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockRemoteRepo.On("FetchMetadata", mock.Anything, packageName, repositories.FullMetadata, entities.CacheValidators{}).Return(reader, entities.CacheValidators{}, nil).Once()
+		teeReader := io.NopCloser(strings.NewReader("dummy metadata"))
+		mockLocalRepo.On("WritePackageJSON", packageName, reader).Return(teeReader, nil).Once()
+		mockLocalRepo.On("SaveCacheValidators", packageName, entities.CacheValidators{}).Return(nil).Once()
+
+		tarballURL := "https://cdn.sheetjs.com/xlsx-0.20.1/xlsx-0.20.1.tgz"
+		tarballSpec := entities.ParseDependencySpec("xlsx", tarballURL)
+		gitSpec := entities.ParseDependencySpec("lib", "github:user/repo")
+		newVersion := func(patch int) entities.NpmPackage {
+			return entities.NpmPackage{
+				Name:         packageName,
+				Version:      entities.SemVer{Major: 4, Minor: 0, Patch: patch},
+				ExternalDeps: []entities.DependencySpec{tarballSpec, gitSpec},
+				ReleaseDate:  time.Now(),
+			}
+		}
+		mockRemoteRepo.On("DecodeNpmPackages", teeReader).Return([]entities.NpmPackage{newVersion(0), newVersion(1)}, nil).Once()
+
+		mockLogger.On("Debug", "[meta_#%d] Enqueueing tarball %s of %s for download", workerID, tarballURL, packageName+"@4.0.0").Once()
+		mockLogger.On("Warn", "[meta_#%d] Dependency %s of %s cannot be mirrored: %s", workerID, "lib", packageName+"@4.0.0", "github:user/repo").Once()
+		mockLogger.On("Warn", "[meta_#%d] Dependency %s of %s cannot be mirrored: %s", workerID, "lib", packageName+"@4.0.1", "github:user/repo").Once()
+		mockLogger.On("Debug", "[meta_#%d] Processed package %s... %d versions to download", workerID, packageName, 2).Once()
+		mockLocalState.On("SetState", testpkg, entities.AnalysedState).Once()
+		mockLocalState.On("IncrementAnalysedCount").Once()
+
+		analyzeChan := make(chan entities.RetrievePackage, 10)
+		downloadChan := make(chan entities.NpmPackage, 10)
+
+		err := pool.retrieveMetadata(context.Background(), testpkg, analyzeChan, downloadChan, workerID)
+
+		assert.NoError(t, err)
+		close(downloadChan)
+		var remoteTarballs []entities.NpmPackage
+		for pkg := range downloadChan {
+			if pkg.IsRemoteTarball {
+				remoteTarballs = append(remoteTarballs, pkg)
+			}
+		}
+		// The tarball shared by both versions is only downloaded once.
+		assert.Equal(t, []entities.NpmPackage{entities.NewRemoteTarballPackage(tarballSpec)}, remoteTarballs)
+		unmirrorable := pool.report.Unmirrorable()
+		require.Len(t, unmirrorable, 2)
+		assert.Equal(t, gitSpec, unmirrorable[0].DependencySpec)
+	})
+}
+
+func TestMetadataWorkerPool_HandleExternalDependencies(t *testing.T) {
+	spec := func(name string, kind entities.DependencyKind) entities.DependencySpec {
+		dep := entities.ParseDependencySpec(name, "https://example.com/"+name+".tgz")
+		dep.Kind = kind
+		return dep
+	}
+	pkg := entities.NpmPackage{
+		Name:    "pkg",
+		Version: entities.SemVer{Major: 1},
+		ExternalDeps: []entities.DependencySpec{
+			spec("prod", entities.DependencyProd),
+			spec("peer", entities.DependencyPeer),
+			spec("optional", entities.DependencyOptional),
+			spec("optional-peer", entities.DependencyOptionalPeer),
+		},
+	}
+	tests := []struct {
+		name     string
+		options  MetadataOptions
+		expected []string
+	}{
+		{name: "No optional dependency", options: MetadataOptions{}, expected: []string{"prod", "peer"}},
+		{name: "Optional dependencies", options: MetadataOptions{FollowOptionalDependencies: true}, expected: []string{"prod", "peer", "optional"}},
+		{name: "Optional peer dependencies", options: MetadataOptions{FollowOptionalPeerDependencies: true}, expected: []string{"prod", "peer", "optional-peer"}},
+		{name: "All dependencies", options: MetadataOptions{FollowOptionalDependencies: true, FollowOptionalPeerDependencies: true}, expected: []string{"prod", "peer", "optional", "optional-peer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockLogger := logger.NewMockLogger(t)
+			mockLogger.On("Debug", "[meta_#%d] Enqueueing tarball %s of %s for download", 1, mock.Anything, "pkg@1.0.0").Times(len(tt.expected))
+			pool := &metadataWorkerPool{
+				logger:   mockLogger,
+				options:  tt.options,
+				report:   entities.NewRunReport(),
+				progress: entities.NewProgress(time.Now()),
+			}
+			downloadChan := make(chan entities.NpmPackage, 10)
+
+			pool.handleExternalDependencies(pkg, downloadChan, 1)
+
+			close(downloadChan)
+			var names []string
+			for tarball := range downloadChan {
+				names = append(names, tarball.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
 }
 
 func TestMetadataWorkerPool_FilterPackages(t *testing.T) {
@@ -514,4 +1024,22 @@ func TestMetadataWorkerPool_FilterPackages(t *testing.T) {
 		assert.Contains(t, versions, "1.1.0-beta")
 		mockLocalState.AssertExpectations(t)
 	})
+
+	t.Run("Excludes versions which cannot be installed on the target platforms", func(t *testing.T) {
+		pool.options = MetadataOptions{Platforms: []entities.Platform{{OS: "linux", CPU: "x64", Libc: "glibc"}}}
+		defer func() { pool.options = MetadataOptions{} }()
+		mockLocalState.On("GetLastSync", mock.Anything).Return(baseSync).Once()
+
+		linux := toPkg("@esbuild/linux-x64", "1.0.0", baseSync.Add(time.Hour))
+		linux.Platform = entities.PlatformConstraints{OS: []string{"linux"}, CPU: []string{"x64"}}
+		musl := toPkg("@esbuild/linux-x64", "1.0.1", baseSync.Add(time.Hour))
+		musl.Platform = entities.PlatformConstraints{OS: []string{"linux"}, CPU: []string{"x64"}, Libc: []string{"musl"}}
+		windows := toPkg("@esbuild/linux-x64", "1.0.2", baseSync.Add(time.Hour))
+		windows.Platform = entities.PlatformConstraints{OS: []string{"win32"}}
+		anywhere := toPkg("@esbuild/linux-x64", "1.0.3", baseSync.Add(time.Hour))
+
+		filtered := pool.filterPackages([]entities.NpmPackage{linux, musl, windows, anywhere}, entities.NewRetrievePackage("@esbuild/linux-x64"))
+		assert.Equal(t, []entities.NpmPackage{linux, anywhere}, filtered)
+		mockLocalState.AssertExpectations(t)
+	})
 }
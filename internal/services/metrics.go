@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/httpclient"
+	"github.com/npmoffline/internal/pkg/metrics"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// Metrics records the metrics of the download runs. A nil *Metrics records nothing.
+type Metrics struct {
+	metadataDuration  *metrics.Histogram
+	metadataCacheHits *metrics.Counter
+	tarballBytes      *metrics.Counter
+	tarballs          *metrics.Counter
+	retries           *metrics.Counter
+	integrityFailures *metrics.Counter
+	queueDepth        *metrics.Gauge
+	activeWorkers     *metrics.Gauge
+	workers           *metrics.Gauge
+}
+
+// NewMetrics registers the metrics of the download runs.
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	return &Metrics{
+		metadataDuration: registry.NewHistogram("npm_offline_metadata_fetch_duration_seconds",
+			"Time to fetch the metadata of a package from the registry.", metrics.DefaultDurationBuckets),
+		metadataCacheHits: registry.NewCounter("npm_offline_metadata_cache_hits_total",
+			"Metadata requests answered 304 Not Modified thanks to the stored cache validators."),
+		tarballBytes: registry.NewCounter("npm_offline_tarball_bytes_total",
+			"Bytes of tarballs received from the registry."),
+		tarballs: registry.NewCounter("npm_offline_tarballs_total",
+			"Tarballs processed by the download workers, by result.", "result"),
+		retries: registry.NewCounter("npm_offline_retries_total",
+			"Attempts made again after a failure, by stage and status code of the failure.", "stage", "code"),
+		integrityFailures: registry.NewCounter("npm_offline_integrity_failures_total",
+			"Downloaded tarballs not matching the integrity of their packument."),
+		queueDepth: registry.NewGauge("npm_offline_queue_depth",
+			"Items waiting in the queue of a stage.", "stage"),
+		activeWorkers: registry.NewGauge("npm_offline_workers_active",
+			"Workers of a stage busy with an item.", "stage"),
+		workers: registry.NewGauge("npm_offline_workers",
+			"Workers started for a stage.", "stage"),
+	}
+}
+
+func (m *Metrics) observeMetadataFetch(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.metadataDuration.Observe(duration.Seconds())
+}
+
+func (m *Metrics) metadataCacheHit() {
+	if m == nil {
+		return
+	}
+	m.metadataCacheHits.Inc()
+}
+
+func (m *Metrics) addTarballBytes(n int) {
+	if m == nil {
+		return
+	}
+	m.tarballBytes.Add(float64(n))
+}
+
+// tarballDone records the result of the download of a tarball.
+func (m *Metrics) tarballDone(err error) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.tarballs.Inc("failed")
+		return
+	}
+	m.tarballs.Inc("downloaded")
+}
+
+func (m *Metrics) integrityFailure(err error) {
+	if m == nil || !errors.Is(err, repositories.ErrIntegrityMismatch) {
+		return
+	}
+	m.integrityFailures.Inc()
+}
+
+// retry records a new attempt after the given failure.
+func (m *Metrics) retry(stage entities.Stage, err error) {
+	if m == nil {
+		return
+	}
+	m.retries.Inc(stage.String(), httpclient.StatusLabel(err))
+}
+
+// setWorkers records the number of workers started for each stage.
+func (m *Metrics) setWorkers(options DownloadPackagesOptions) {
+	if m == nil {
+		return
+	}
+	m.workers.Set(float64(options.MetadataWorkers), entities.MetadataStage.String())
+	m.workers.Set(float64(options.DownloadWorkers), entities.DownloadStage.String())
+}
+
+// updateStages records the depth of the queues and the busy workers of each stage.
+func (m *Metrics) updateStages(snapshot entities.ProgressSnapshot, metadataQueue, downloadQueue int) {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Set(float64(metadataQueue), entities.MetadataStage.String())
+	m.queueDepth.Set(float64(downloadQueue), entities.DownloadStage.String())
+	m.activeWorkers.Set(float64(snapshot.Metadata.Active), entities.MetadataStage.String())
+	m.activeWorkers.Set(float64(snapshot.Download.Active), entities.DownloadStage.String())
+}
@@ -19,11 +19,14 @@ type DownloadPackagesOptions struct {
 	MetadataWorkers       int
 	DownloadWorkers       int
 	UpdateLocalRepository bool
+	// Progress displays the progress of the run. The progress is logged when it is nil.
+	Progress ProgressReporter
 }
 
 // NpmDownloadService defines the interface of the download service.
 type NpmDownloadService interface {
 	DownloadPackages(ctx context.Context, packageList []string, options DownloadPackagesOptions)
+	Report() *entities.RunReport
 }
 
 type npmDownloadService struct {
@@ -34,10 +37,14 @@ type npmDownloadService struct {
 	startingDate       time.Time
 	metadataWorkerPool MetadataWorkerPool
 	tarballWorkerPool  TarballWorkerPool
+	report             *entities.RunReport
+	progress           *entities.Progress
+	metrics            *Metrics
+	dryRun             bool
 }
 
 // NewNpmDownloadService creates a new instance of the download service.
-func NewNpmDownloadService(npmRepo repositories.NpmRepository, fileRepo repositories.LocalNpmRepository, log logger.Logger) *npmDownloadService {
+func NewNpmDownloadService(npmRepo repositories.NpmRepository, fileRepo repositories.LocalNpmRepository, log logger.Logger, metadataOptions MetadataOptions) *npmDownloadService {
 	packages, lastSync, err := fileRepo.LoadDownloadedPackagesState()
 	if err != nil {
 		log.Error("Failed to load downloaded versions: %v", err)
@@ -45,15 +52,22 @@ func NewNpmDownloadService(npmRepo repositories.NpmRepository, fileRepo reposito
 	}
 
 	state := entities.NewLocalNpmState(packages, lastSync, log)
+	report := entities.NewRunReport()
+	startingDate := time.Now().UTC()
+	progress := entities.NewProgress(startingDate)
 
 	return &npmDownloadService{
 		npmRepo:            npmRepo,
 		localNpmRepo:       fileRepo,
 		logger:             log,
 		downloadState:      state,
-		startingDate:       time.Now().UTC(),
-		metadataWorkerPool: NewMetadataWorkerPool(log, fileRepo, npmRepo, state),
-		tarballWorkerPool:  NewTarballdWorkerPool(log, fileRepo, npmRepo, state),
+		startingDate:       startingDate,
+		metadataWorkerPool: NewMetadataWorkerPool(log, fileRepo, npmRepo, state, metadataOptions, report, progress),
+		tarballWorkerPool:  NewTarballdWorkerPool(log, fileRepo, npmRepo, state, report, progress, metadataOptions.Metrics, metadataOptions.DryRun),
+		report:             report,
+		progress:           progress,
+		metrics:            metadataOptions.Metrics,
+		dryRun:             metadataOptions.DryRun,
 	}
 }
 
@@ -66,24 +80,36 @@ func (s *npmDownloadService) DownloadPackages(ctx context.Context, packageList [
 	s.logger.Info("Starting download service")
 	defer s.logger.Info("Download service stopped")
 
-	// Start a ticker to log progress.
-	ticker := time.NewTicker(1 * time.Second)
+	// Start a ticker to display the progress.
+	reporter := options.Progress
+	if reporter == nil {
+		reporter = NewLogProgressReporter(s.logger)
+	}
+	s.metrics.setWorkers(options)
+	ticker := time.NewTicker(progressInterval)
+	stopProgress := make(chan struct{})
+	progressStopped := make(chan struct{})
 	go func() {
+		defer close(progressStopped)
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case <-stopProgress:
+				return
 			case <-ticker.C:
-				analysed := s.downloadState.GetAnalysedCount()
-				downloaded := s.downloadState.GetDownloadedCount()
-				s.logger.Info("Analysed: %d/%d, Downloaded: %d/%d", analysed, analysed+len(metadataChan), downloaded, downloaded+len(downloadChan))
+				snapshot := s.progress.Snapshot(time.Now().UTC())
+				s.metrics.updateStages(snapshot, len(metadataChan), len(downloadChan))
+				reporter.Update(snapshot)
 			}
 		}
 	}()
 
 	var retrievePackages []entities.RetrievePackage
 	for _, pkg := range packageList {
-		retrievePackages = append(retrievePackages, entities.NewRetrievePackage(pkg))
+		retrievePackage := entities.NewRetrievePackage(pkg)
+		retrievePackages = append(retrievePackages, retrievePackage)
+		s.report.AddRoot(retrievePackage.Name)
 	}
 
 	// Optionally update packageList with packages from the local state.
@@ -105,6 +131,7 @@ func (s *npmDownloadService) DownloadPackages(ctx context.Context, packageList [
 
 	// Enqueue the initial packages into the metadata channel.
 	for _, pkg := range retrievePackages {
+		s.progress.Enqueue(entities.MetadataStage)
 		metadataChan <- pkg
 	}
 
@@ -118,10 +145,58 @@ func (s *npmDownloadService) DownloadPackages(ctx context.Context, packageList [
 	s.logger.Info("Download workers finished")
 	close(downloadChan)
 
-	// Stop the ticker.
+	// Stop the ticker and display the final progress.
 	ticker.Stop()
+	close(stopProgress)
+	<-progressStopped
+	snapshot := s.progress.Snapshot(time.Now().UTC())
+	s.metrics.updateStages(snapshot, len(metadataChan), len(downloadChan))
+	reporter.Finish(snapshot)
+
+	// A dry run only reports the tarballs it would download.
+	if s.dryRun {
+		return
+	}
+
+	// Point the dist-tags of the stored package.json to the downloaded versions.
+	s.updateDistTags(s.metadataWorkerPool.SyncedPackages())
+
+	// Add the discovered dependencies to the graph of the previous runs.
+	s.saveDependencyGraph()
 
 	// Save the download state.
 	pkgs := s.downloadState.GetPackages()
 	s.localNpmRepo.SaveDownloadedPackagesState(pkgs, s.startingDate)
 }
+
+// updateDistTags rewrites the dist-tags of the given packages.
+func (s *npmDownloadService) updateDistTags(packages []string) {
+	for _, name := range packages {
+		tags, err := s.localNpmRepo.UpdateDistTags(name)
+		if err != nil {
+			s.logger.Warn("Failed to update the dist-tags of %s: %v", name, err)
+			continue
+		}
+		if s.logger.IsDebug() && tags != nil {
+			s.logger.Debug("Dist-tags of %s: %v", name, tags)
+		}
+	}
+}
+
+// saveDependencyGraph merges the dependencies discovered during the run into the stored graph.
+func (s *npmDownloadService) saveDependencyGraph() {
+	graph, err := s.localNpmRepo.LoadDependencyGraph()
+	if err != nil {
+		s.logger.Warn("Failed to load the dependency graph: %v", err)
+		return
+	}
+	graph.Merge(s.report.DependencyGraph())
+	if err := s.localNpmRepo.SaveDependencyGraph(graph); err != nil {
+		s.logger.Warn("Failed to save the dependency graph: %v", err)
+	}
+}
+
+// Report returns the report of the download run.
+func (s *npmDownloadService) Report() *entities.RunReport {
+	return s.report
+}
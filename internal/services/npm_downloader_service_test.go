@@ -20,6 +20,7 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 	mockLocalState := entities.NewMockLocalNpmState(t)
 	mockMetadataPool := NewMockMetadataWorkerPool(t)
 	mockTarballPool := NewMockTarballWorkerPool(t)
+	mockProgress := NewMockProgressReporter(t)
 
 	service := &npmDownloadService{
 		npmRepo:            mockNpmRepo,
@@ -29,16 +30,22 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 		metadataWorkerPool: mockMetadataPool,
 		tarballWorkerPool:  mockTarballPool,
 		startingDate:       time.Now().UTC(),
+		report:             entities.NewRunReport(),
+		progress:           entities.NewProgress(time.Now()),
 	}
 
 	t.Run("Download packages with cancelled context", func(t *testing.T) {
 		mockLocalState.On("GetPackages").Return([]entities.RetrievePackage{}).Once()
 		mockLocalNpmRepo.On("SaveDownloadedPackagesState", mock.Anything, mock.Anything).Return(nil).Once()
+		mockLocalNpmRepo.On("LoadDependencyGraph").Return(entities.DependencyGraph{}, nil).Once()
+		mockLocalNpmRepo.On("SaveDependencyGraph", mock.Anything).Return(nil).Once()
 
 		mockLogger.On("Info", mock.Anything).Return().Times(4)
 
 		mockMetadataPool.On("WaitAllWorkers").Return().Once()
+		mockProgress.On("Finish", mock.Anything).Return().Once()
 		mockTarballPool.On("WaitAllWorkers").Return().Once()
+		mockMetadataPool.On("SyncedPackages").Return([]string(nil)).Once()
 
 		// Create a context that is canceled immediately.
 		ctx, cancel := context.WithCancel(context.Background())
@@ -49,6 +56,7 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 			MetadataWorkers:       0,
 			DownloadWorkers:       0,
 			UpdateLocalRepository: false,
+			Progress:              mockProgress,
 		})
 
 		// Give a short time for the service to exit.
@@ -60,11 +68,17 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 	})
 
 	t.Run("Download packages with update local repository", func(t *testing.T) {
+		service.progress = entities.NewProgress(time.Now())
 
 		expectedStatePkgs := []entities.RetrievePackage{{Name: "statePkg1"}, {Name: "statePkg2"}}
 		mockLocalState.On("GetPackages").Return(expectedStatePkgs).Times(2)
 
 		mockLocalNpmRepo.On("SaveDownloadedPackagesState", mock.Anything, mock.Anything).Return(nil).Once()
+		mockLocalNpmRepo.On("LoadDependencyGraph").Return(entities.DependencyGraph{Roots: []string{"statePkg1"}}, nil).Once()
+		mockLocalNpmRepo.On("SaveDependencyGraph", entities.DependencyGraph{
+			Roots: []string{"initialPkg", "pkg1", "statePkg1"},
+			Edges: []entities.DependencyEdge{},
+		}).Return(nil).Once()
 
 		mockLogger.On("Info", mock.Anything).Return().Times(5)
 		var packageChannel chan entities.RetrievePackage
@@ -74,7 +88,14 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 			}).Return().Once()
 
 		mockMetadataPool.On("WaitAllWorkers").Return().Once()
+		// The initial packages and the packages of the state are queued for the metadata stage.
+		mockProgress.On("Finish", mock.MatchedBy(func(snapshot entities.ProgressSnapshot) bool {
+			return snapshot.Metadata.Queued == 3
+		})).Return().Once()
 		mockTarballPool.On("WaitAllWorkers").Return().Once()
+		mockMetadataPool.On("SyncedPackages").Return([]string{"statePkg1"}).Once()
+		mockLocalNpmRepo.On("UpdateDistTags", "statePkg1").Return(entities.DistTags{"latest": "1.0.0"}, nil).Once()
+		mockLogger.On("IsDebug").Return(false).Once()
 
 		// Use a context with timeout to allow the service to complete.
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
@@ -86,6 +107,7 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 			MetadataWorkers:       1,
 			DownloadWorkers:       0,
 			UpdateLocalRepository: true,
+			Progress:              mockProgress,
 		})
 
 		assert.Equal(t, 3, len(packageChannel))
@@ -98,6 +120,8 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 	t.Run("starts same worker number as option say", func(t *testing.T) {
 		mockLocalState.On("GetPackages").Return([]entities.RetrievePackage{}).Once()
 		mockLocalNpmRepo.On("SaveDownloadedPackagesState", mock.Anything, mock.Anything).Return(nil).Once()
+		mockLocalNpmRepo.On("LoadDependencyGraph").Return(entities.DependencyGraph{}, nil).Once()
+		mockLocalNpmRepo.On("SaveDependencyGraph", mock.Anything).Return(nil).Once()
 
 		mockLogger.On("Info", mock.Anything).Return().Times(4)
 
@@ -105,7 +129,9 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 		mockTarballPool.On("StartWorker", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Times(4)
 
 		mockMetadataPool.On("WaitAllWorkers").Return().Once()
+		mockProgress.On("Finish", mock.Anything).Return().Once()
 		mockTarballPool.On("WaitAllWorkers").Return().Once()
+		mockMetadataPool.On("SyncedPackages").Return([]string(nil)).Once()
 
 		// Use a context with timeout to allow the service to complete.
 		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
@@ -117,6 +143,7 @@ func TestNpmDownloadService_DownloadPackages(t *testing.T) {
 			MetadataWorkers:       6,
 			DownloadWorkers:       4,
 			UpdateLocalRepository: false,
+			Progress:              mockProgress,
 		})
 
 		// Assert that SaveDownloadedPackagesState was called.
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/logger"
+)
+
+// progressInterval is the interval between two updates of the progress.
+const progressInterval = time.Second
+
+// ProgressReporter displays the progress of a download run.
+type ProgressReporter interface {
+	// Update displays the progress while the run goes on.
+	Update(snapshot entities.ProgressSnapshot)
+	// Finish displays the progress at the end of the run.
+	Finish(snapshot entities.ProgressSnapshot)
+}
+
+// logProgressReporter logs the progress as key=value lines, one per stage.
+type logProgressReporter struct {
+	logger logger.Logger
+}
+
+// NewLogProgressReporter creates a reporter logging the progress, for non-interactive runs.
+func NewLogProgressReporter(logger logger.Logger) ProgressReporter {
+	return &logProgressReporter{logger: logger}
+}
+
+// Update logs the progress of each stage.
+func (r *logProgressReporter) Update(snapshot entities.ProgressSnapshot) {
+	r.log(snapshot)
+}
+
+// Finish logs the final progress of each stage.
+func (r *logProgressReporter) Finish(snapshot entities.ProgressSnapshot) {
+	r.log(snapshot)
+}
+
+func (r *logProgressReporter) log(snapshot entities.ProgressSnapshot) {
+	metadata, download := snapshot.Metadata, snapshot.Download
+	r.logger.Info("Progress stage=metadata done=%d total=%d active=%d retries=%d failures=%d eta=%s",
+		metadata.Done, metadata.Queued, metadata.Active, metadata.Retries, metadata.Failures, FormatETA(metadata.ETA(snapshot.Elapsed)))
+	r.logger.Info("Progress stage=download done=%d total=%d active=%d retries=%d failures=%d bytes=%d bytes_per_sec=%d eta=%s",
+		download.Done, download.Queued, download.Active, download.Retries, download.Failures, snapshot.Bytes, snapshot.BytesPerSecond(), FormatETA(download.ETA(snapshot.Elapsed)))
+}
+
+// FormatETA formats an estimated time left, rounded to the second. An unknown ETA is "unknown".
+func FormatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "unknown"
+	}
+	return eta.Round(time.Second).String()
+}
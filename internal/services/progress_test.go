@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogProgressReporter(t *testing.T) {
+	mockLogger := logger.NewMockLogger(t)
+	reporter := NewLogProgressReporter(mockLogger)
+	snapshot := entities.ProgressSnapshot{
+		Elapsed:  10 * time.Second,
+		Metadata: entities.StageProgress{Queued: 20, Done: 10, Active: 2, Retries: 1},
+		Download: entities.StageProgress{Queued: 30, Done: 15, Active: 4, Failures: 1},
+		Bytes:    5000,
+	}
+
+	mockLogger.On("Info", "Progress stage=metadata done=%d total=%d active=%d retries=%d failures=%d eta=%s",
+		int64(10), int64(20), int64(2), int64(1), int64(0), "10s").Once()
+	mockLogger.On("Info", "Progress stage=download done=%d total=%d active=%d retries=%d failures=%d bytes=%d bytes_per_sec=%d eta=%s",
+		int64(15), int64(30), int64(4), int64(0), int64(1), int64(5000), int64(500), "10s").Once()
+
+	reporter.Update(snapshot)
+}
+
+func TestFormatETA(t *testing.T) {
+	assert.Equal(t, "unknown", FormatETA(-1))
+	assert.Equal(t, "1m5s", FormatETA(65*time.Second+300*time.Millisecond))
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// PruneRepository removes from the local repository the versions selected by the retention
+// policy. The packages left without any version are dropped from the download state and from the
+// roots of the dependency graph, and the edges of the removed versions from the graph. With dryRun, nothing is written and
+// the report tells what would be removed and the bytes freed.
+func PruneRepository(repo repositories.LocalNpmRepository, policy entities.RetentionPolicy, now time.Time, dryRun bool) (entities.PruneReport, error) {
+	report := entities.PruneReport{DryRun: dryRun, Packages: []entities.PrunedPackage{}}
+	names, err := repo.ListPackages()
+	if err != nil {
+		return report, fmt.Errorf("failed to list the packages: %w", err)
+	}
+
+	deleted := map[string]bool{}
+	var removed []string
+	for _, name := range names {
+		versions, err := repo.LoadLocalVersions(name)
+		if err != nil {
+			return report, fmt.Errorf("failed to load the versions of %s: %w", name, err)
+		}
+		pruned := policy.Prune(versions, now)
+		if len(pruned) == 0 {
+			continue
+		}
+		selected := make([]string, 0, len(pruned))
+		for _, pkg := range pruned {
+			selected = append(selected, pkg.Version.String())
+			removed = append(removed, name+"@"+pkg.Version.String())
+		}
+		result, err := repo.PrunePackage(name, selected, dryRun)
+		if err != nil {
+			return report, fmt.Errorf("failed to prune %s: %w", name, err)
+		}
+		if result.Deleted {
+			deleted[name] = true
+		}
+		report.Packages = append(report.Packages, result)
+	}
+	if dryRun || len(removed) == 0 {
+		return report, nil
+	}
+
+	return report, forgetRemovedVersions(repo, removed, deleted)
+}
+
+// forgetRemovedVersions drops the deleted packages from the download state and from the roots of
+// the dependency graph, and the edges of the removed versions, written name@version, from the graph.
+func forgetRemovedVersions(repo repositories.LocalNpmRepository, removed []string, deleted map[string]bool) error {
+	if len(deleted) > 0 {
+		tracked, lastSync, err := repo.LoadDownloadedPackagesState()
+		if err != nil {
+			return fmt.Errorf("failed to load the download state: %w", err)
+		}
+		var kept []entities.RetrievePackage
+		for _, pkg := range tracked {
+			if !deleted[pkg.Name] {
+				kept = append(kept, pkg)
+			}
+		}
+		if len(kept) != len(tracked) {
+			if err := repo.SaveDownloadedPackagesState(kept, lastSync); err != nil {
+				return fmt.Errorf("failed to save the download state: %w", err)
+			}
+		}
+	}
+
+	if len(deleted) == 0 && len(removed) == 0 {
+		return nil
+	}
+	graph, err := repo.LoadDependencyGraph()
+	if err != nil {
+		return fmt.Errorf("failed to load the dependency graph: %w", err)
+	}
+	if len(graph.Roots) == 0 && len(graph.Edges) == 0 {
+		return nil
+	}
+	graph.RemoveVersions(removed)
+	for name := range deleted {
+		graph.RemoveRoot(name)
+	}
+	if err := repo.SaveDependencyGraph(graph); err != nil {
+		return fmt.Errorf("failed to save the dependency graph: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneRepository(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	policy := entities.RetentionPolicy{KeepLast: 1}
+	versions := func(name string, values ...entities.SemVer) []entities.NpmPackage {
+		var packages []entities.NpmPackage
+		for _, v := range values {
+			packages = append(packages, entities.NpmPackage{Name: name, Version: v})
+		}
+		return packages
+	}
+
+	t.Run("State and graph are updated", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a", "b"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(versions("a", entities.SemVer{Major: 1}, entities.SemVer{Major: 2}), nil).Once()
+		repo.On("LoadLocalVersions", "b").Return(versions("b", entities.SemVer{Major: 1}), nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0"}, false).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0"}, Kept: 1, Freed: 10}, nil).Once()
+		repo.On("LoadDependencyGraph").Return(entities.DependencyGraph{
+			Roots: []string{"a"},
+			Edges: []entities.DependencyEdge{{From: "a@1.0.0", To: "b"}, {From: "a@2.0.0", To: "b"}},
+		}, nil).Once()
+		repo.On("SaveDependencyGraph", entities.DependencyGraph{
+			Roots: []string{"a"},
+			Edges: []entities.DependencyEdge{{From: "a@2.0.0", To: "b"}},
+		}).Return(nil).Once()
+
+		report, err := PruneRepository(repo, policy, now, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Removed())
+		assert.Equal(t, int64(10), report.Freed())
+	})
+
+	t.Run("Deleted packages are dropped from the download state and the graph roots", func(t *testing.T) {
+		beta := versions("a", entities.SemVer{Major: 1, PreRelease: "beta"})
+		beta[0].ReleaseDate = now.Add(-48 * time.Hour)
+		lastSync := now.Add(-time.Hour)
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(beta, nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0-beta"}, false).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0-beta"}, Deleted: true}, nil).Once()
+		repo.On("LoadDownloadedPackagesState").
+			Return([]entities.RetrievePackage{{Name: "a"}, {Name: "b"}}, lastSync, nil).Once()
+		repo.On("SaveDownloadedPackagesState", []entities.RetrievePackage{{Name: "b"}}, lastSync).Return(nil).Once()
+		repo.On("LoadDependencyGraph").Return(entities.DependencyGraph{Roots: []string{"a", "b"}}, nil).Once()
+		repo.On("SaveDependencyGraph", entities.DependencyGraph{Roots: []string{"b"}}).Return(nil).Once()
+
+		_, err := PruneRepository(repo, entities.RetentionPolicy{PrereleaseMaxAge: 24 * time.Hour}, now, false)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("Dry run writes nothing", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(versions("a", entities.SemVer{Major: 1}, entities.SemVer{Major: 2}), nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0"}, true).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0"}, Kept: 1, Freed: 10}, nil).Once()
+
+		report, err := PruneRepository(repo, policy, now, true)
+
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, int64(10), report.Freed())
+	})
+
+	t.Run("Failure to prune a package", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(versions("a", entities.SemVer{Major: 1}, entities.SemVer{Major: 2}), nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0"}, false).Return(entities.PrunedPackage{}, errors.New("permission denied")).Once()
+
+		_, err := PruneRepository(repo, policy, now, false)
+
+		assert.EqualError(t, err, "failed to prune a: permission denied")
+	})
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// RemoveOptions contains the options of the removal of packages.
+type RemoveOptions struct {
+	// Tombstone records the removed packages and ranges, so that the next runs never download
+	// them again, even as a dependency.
+	Tombstone bool
+	// DryRun writes nothing: the report tells what would be removed and the bytes freed.
+	DryRun bool
+}
+
+// RemovePackages removes from the local repository the packages, or the versions of a range,
+// given as tombstones. The removed packages are dropped from the download state and from the
+// roots of the dependency graph, and the edges of the removed versions from the graph. A whole
+// package without package.json is removed from its tarballs. A target without any stored version
+// is an error, unless it is only recorded as a tombstone.
+func RemovePackages(repo repositories.LocalNpmRepository, targets []entities.Tombstone, options RemoveOptions) (entities.PruneReport, error) {
+	report := entities.PruneReport{DryRun: options.DryRun, Packages: []entities.PrunedPackage{}}
+	deleted := map[string]bool{}
+	var removed []string
+	for _, target := range targets {
+		versions, err := repo.LoadLocalVersions(target.Name)
+		if err != nil {
+			return report, fmt.Errorf("failed to load the versions of %s: %w", target.Name, err)
+		}
+		var selected []string
+		for _, version := range versions {
+			if target.Matches(version) {
+				selected = append(selected, version.Version.String())
+				removed = append(removed, target.Name+"@"+version.Version.String())
+			}
+		}
+		if target.IsWholePackage() {
+			deleted[target.Name] = true
+			if len(selected) == 0 {
+				// Without package.json, the stored versions are only known from their tarball.
+				tarballs, err := repo.LoadTarballVersions(target.Name)
+				if err != nil {
+					return report, fmt.Errorf("failed to load the versions of %s: %w", target.Name, err)
+				}
+				for _, version := range tarballs {
+					selected = append(selected, version.String())
+					removed = append(removed, target.Name+"@"+version.String())
+				}
+			}
+		}
+		if len(selected) == 0 {
+			if !options.Tombstone {
+				return report, fmt.Errorf("no version of %s in the local repository", target)
+			}
+			continue
+		}
+
+		result, err := repo.PrunePackage(target.Name, selected, options.DryRun)
+		if err != nil {
+			return report, fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+		if result.Deleted {
+			deleted[target.Name] = true
+		}
+		report.Packages = append(report.Packages, result)
+	}
+	if options.DryRun {
+		return report, nil
+	}
+
+	if err := forgetRemovedVersions(repo, removed, deleted); err != nil {
+		return report, err
+	}
+
+	if options.Tombstone {
+		tombstones, err := repo.LoadTombstones()
+		if err != nil {
+			return report, fmt.Errorf("failed to load the tombstones: %w", err)
+		}
+		for _, target := range targets {
+			tombstones = entities.AddTombstone(tombstones, target)
+		}
+		if err := repo.SaveTombstones(tombstones); err != nil {
+			return report, fmt.Errorf("failed to save the tombstones: %w", err)
+		}
+	}
+	return report, nil
+}
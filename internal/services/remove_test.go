@@ -0,0 +1,117 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemovePackages(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tombstone := func(name string, versions string) entities.Tombstone {
+		target, err := entities.NewTombstone(name, versions, "", createdAt)
+		require.NoError(t, err)
+		return target
+	}
+	localVersions := []entities.NpmPackage{
+		{Name: "a", Version: entities.SemVer{Major: 1}},
+		{Name: "a", Version: entities.SemVer{Major: 2}},
+	}
+
+	t.Run("Whole package is removed from the state and the graph", func(t *testing.T) {
+		lastSync := createdAt.Add(-time.Hour)
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(localVersions, nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0", "2.0.0"}, false).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0", "2.0.0"}, Deleted: true, Freed: 20}, nil).Once()
+		repo.On("LoadDownloadedPackagesState").Return([]entities.RetrievePackage{{Name: "a"}, {Name: "b"}}, lastSync, nil).Once()
+		repo.On("SaveDownloadedPackagesState", []entities.RetrievePackage{{Name: "b"}}, lastSync).Return(nil).Once()
+		repo.On("LoadDependencyGraph").Return(entities.DependencyGraph{
+			Roots: []string{"a", "b"},
+			Edges: []entities.DependencyEdge{{From: "a@1.0.0", To: "b"}, {From: "b@1.0.0", To: "a"}},
+		}, nil).Once()
+		repo.On("SaveDependencyGraph", entities.DependencyGraph{
+			Roots: []string{"b"},
+			Edges: []entities.DependencyEdge{{From: "b@1.0.0", To: "a"}},
+		}).Return(nil).Once()
+
+		report, err := RemovePackages(repo, []entities.Tombstone{tombstone("a", "")}, RemoveOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.Removed())
+		assert.Equal(t, int64(20), report.Freed())
+	})
+
+	t.Run("Tombstones are recorded for the targets", func(t *testing.T) {
+		target := tombstone("a", "^1.0.0")
+		missing := tombstone("gone", "")
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(localVersions, nil).Once()
+		repo.On("LoadLocalVersions", "gone").Return(nil, nil).Once()
+		repo.On("LoadTarballVersions", "gone").Return(nil, nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0"}, false).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0"}, Kept: 1}, nil).Once()
+		repo.On("LoadDownloadedPackagesState").Return([]entities.RetrievePackage{{Name: "a"}}, createdAt, nil).Once()
+		repo.On("LoadDependencyGraph").Return(entities.DependencyGraph{}, nil).Once()
+		repo.On("LoadTombstones").Return(nil, nil).Once()
+		repo.On("SaveTombstones", []entities.Tombstone{target, missing}).Return(nil).Once()
+
+		_, err := RemovePackages(repo, []entities.Tombstone{target, missing}, RemoveOptions{Tombstone: true})
+
+		require.NoError(t, err)
+	})
+
+	t.Run("Dry run writes nothing", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(localVersions, nil).Once()
+		repo.On("PrunePackage", "a", []string{"2.0.0"}, true).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"2.0.0"}, Kept: 1}, nil).Once()
+
+		report, err := RemovePackages(repo, []entities.Tombstone{tombstone("a", "2")}, RemoveOptions{Tombstone: true, DryRun: true})
+
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		repo.AssertNotCalled(t, "SaveTombstones", mock.Anything)
+	})
+
+	t.Run("Whole package without package.json is removed from its tarballs", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(nil, nil).Once()
+		repo.On("LoadTarballVersions", "a").Return([]entities.SemVer{{Major: 1}}, nil).Once()
+		repo.On("PrunePackage", "a", []string{"1.0.0"}, false).
+			Return(entities.PrunedPackage{Name: "a", Removed: []string{"1.0.0"}, Deleted: true, Freed: 10}, nil).Once()
+		repo.On("LoadDownloadedPackagesState").Return([]entities.RetrievePackage{{Name: "a"}}, createdAt, nil).Once()
+		repo.On("SaveDownloadedPackagesState", []entities.RetrievePackage(nil), createdAt).Return(nil).Once()
+		repo.On("LoadDependencyGraph").Return(entities.DependencyGraph{}, nil).Once()
+
+		report, err := RemovePackages(repo, []entities.Tombstone{tombstone("a", "")}, RemoveOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Removed())
+		assert.Equal(t, int64(10), report.Freed())
+	})
+
+	t.Run("Whole package not in the local repository", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(nil, nil).Once()
+		repo.On("LoadTarballVersions", "a").Return(nil, nil).Once()
+
+		_, err := RemovePackages(repo, []entities.Tombstone{tombstone("a", "")}, RemoveOptions{})
+
+		assert.EqualError(t, err, "no version of a in the local repository")
+	})
+
+	t.Run("Package not in the local repository", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("LoadLocalVersions", "a").Return(localVersions, nil).Once()
+
+		_, err := RemovePackages(repo, []entities.Tombstone{tombstone("a", "^3.0.0")}, RemoveOptions{})
+
+		assert.EqualError(t, err, "no version of a@^3.0.0 in the local repository")
+	})
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/npmoffline/internal/entities"
+)
+
+// defaultTag is the dist-tag installed for the packages requested directly.
+const defaultTag = "latest"
+
+// anyRange is used for the dist-tags which do not point to a known version.
+var anyRange, _ = entities.ParseSemVerRange("*")
+
+// requiredVersions tracks the version ranges required for each package, to find out which
+// versions npm would install. Some versions are only downloaded when a range resolves to them:
+// the deprecated versions with the if-required policy, and every version with the required-only
+// selection. Since dependents may be analysed after the package itself, these pending versions are
+// kept so that they can be downloaded when a new range needs them.
+type requiredVersions struct {
+	mutex  sync.Mutex
+	ranges map[string][]string
+	// analysed holds the versions of the packages already analysed. The entry of a package is nil
+	// once no version is pending anymore.
+	analysed map[string]*analysedVersions
+}
+
+type analysedVersions struct {
+	// all are the versions of the packument, reduced to what the resolution of a range needs.
+	all []resolvable
+	// pending are the versions not downloaded yet, waiting for a range to resolve to them.
+	pending []entities.NpmPackage
+}
+
+// resolvable is a version of a package, reduced to what the resolution of a range needs.
+type resolvable struct {
+	version    entities.SemVer
+	distTags   []string
+	deprecated bool
+}
+
+// toResolvables reduces the versions of a package to what the resolution of a range needs.
+func toResolvables(versions []entities.NpmPackage) []resolvable {
+	result := make([]resolvable, len(versions))
+	for i, version := range versions {
+		result[i] = resolvable{version: version.Version, distTags: version.DistTags, deprecated: version.Deprecated != ""}
+	}
+	return result
+}
+
+func newRequiredVersions() *requiredVersions {
+	return &requiredVersions{
+		ranges:   map[string][]string{},
+		analysed: map[string]*analysedVersions{},
+	}
+}
+
+// require records a range, or a dist-tag, required for a package. If the package was already
+// analysed, the pending versions the range resolves to are returned, to be downloaded.
+func (r *requiredVersions) require(pkg string, value string) []entities.NpmPackage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ranges[pkg] = append(r.ranges[pkg], value)
+
+	versions := r.analysed[pkg]
+	if versions == nil {
+		return nil
+	}
+	var required []entities.NpmPackage
+	required, versions.pending = takeResolved(versions.pending, versions.all, []string{value})
+	if len(versions.pending) == 0 {
+		// No range can release a version anymore: the versions of the package are not needed.
+		r.analysed[pkg] = nil
+	}
+	return required
+}
+
+// filter returns the pending versions required by the ranges known so far, and keeps the others
+// for the ranges to come. All the versions of the package are needed to resolve the ranges.
+// A package no one depends on was requested directly: its latest version is required.
+// When several specs of a package are analysed, their pending versions are merged.
+func (r *requiredVersions) filter(pkg string, all []entities.NpmPackage, pending []entities.NpmPackage) (required []entities.NpmPackage, notRequired []entities.NpmPackage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ranges := r.ranges[pkg]
+	if len(ranges) == 0 {
+		ranges = []string{defaultTag}
+	}
+	versions := &analysedVersions{all: toResolvables(all)}
+	if previous := r.analysed[pkg]; previous != nil {
+		// Another spec of the package was analysed before, its pending versions still wait for a range.
+		pending = mergePending(previous.pending, pending)
+	}
+	required, versions.pending = takeResolved(pending, versions.all, ranges)
+	r.analysed[pkg] = nil
+	if len(versions.pending) > 0 {
+		r.analysed[pkg] = versions
+	}
+	return required, versions.pending
+}
+
+// mergePending returns the pending versions of an earlier analysis, followed by the new ones it
+// does not hold yet.
+func mergePending(previous []entities.NpmPackage, pending []entities.NpmPackage) []entities.NpmPackage {
+	merged := slices.Clone(previous)
+	for _, version := range pending {
+		if !slices.ContainsFunc(previous, func(p entities.NpmPackage) bool { return p.Version == version.Version }) {
+			merged = append(merged, version)
+		}
+	}
+	return merged
+}
+
+// takeResolved splits the pending versions between those a range resolves to and the others.
+func takeResolved(pending []entities.NpmPackage, all []resolvable, ranges []string) (required []entities.NpmPackage, notRequired []entities.NpmPackage) {
+	resolved := map[entities.SemVer]bool{}
+	for _, value := range ranges {
+		if version, ok := resolveRange(all, value); ok {
+			resolved[version] = true
+		}
+	}
+	for _, version := range pending {
+		if resolved[version.Version] {
+			required = append(required, version)
+		} else {
+			notRequired = append(notRequired, version)
+		}
+	}
+	return required, notRequired
+}
+
+// resolveRange returns the version npm installs for a range or a dist-tag: the version of the
+// "latest" tag when it matches, else the highest matching version which is not deprecated,
+// else the highest matching deprecated version.
+func resolveRange(all []resolvable, value string) (entities.SemVer, bool) {
+	rng, err := entities.ParseSemVerRange(value)
+	if err != nil {
+		for _, version := range all {
+			if slices.Contains(version.distTags, value) {
+				return version.version, true
+			}
+		}
+		rng = anyRange
+	}
+
+	for _, version := range all {
+		if slices.Contains(version.distTags, defaultTag) && !version.deprecated && rng.Matches(version.version) {
+			return version.version, true
+		}
+	}
+
+	var best, bestDeprecated entities.SemVer
+	found, foundDeprecated := false, false
+	for _, version := range all {
+		if !rng.Matches(version.version) {
+			continue
+		}
+		if !version.deprecated {
+			if !found || version.version.Compare(best) > 0 {
+				best, found = version.version, true
+			}
+		} else if !foundDeprecated || version.version.Compare(bestDeprecated) > 0 {
+			bestDeprecated, foundDeprecated = version.version, true
+		}
+	}
+	if found {
+		return best, true
+	}
+	return bestDeprecated, foundDeprecated
+}
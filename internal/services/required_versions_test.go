@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredVersions(t *testing.T) {
+	version := func(v string, deprecated string, tags ...string) entities.NpmPackage {
+		semver, err := entities.NewSemVer(v)
+		require.NoError(t, err)
+		return entities.NpmPackage{Name: "pkg", Version: semver, Deprecated: deprecated, DistTags: tags}
+	}
+	v1 := version("1.0.0", "use 2.x")
+	v11 := version("1.1.0", "use 2.x")
+	v2 := version("2.0.0", "")
+	v3 := version("3.0.0", "broken")
+	all := []entities.NpmPackage{v1, v11, v2, v3}
+
+	t.Run("Packages requested directly only require their latest non deprecated version", func(t *testing.T) {
+		required := newRequiredVersions()
+
+		kept, pending := required.filter("pkg", all, all)
+
+		assert.Equal(t, []entities.NpmPackage{v2}, kept)
+		assert.Equal(t, []entities.NpmPackage{v1, v11, v3}, pending)
+	})
+
+	t.Run("The highest deprecated version matching a range is required when nothing else matches", func(t *testing.T) {
+		required := newRequiredVersions()
+		assert.Empty(t, required.require("pkg", "^1.0.0"))
+		assert.Empty(t, required.require("pkg", "^2.0.0"))
+
+		kept, pending := required.filter("pkg", all, all)
+
+		assert.Equal(t, []entities.NpmPackage{v11, v2}, kept)
+		assert.Equal(t, []entities.NpmPackage{v1, v3}, pending)
+	})
+
+	t.Run("Ranges discovered after the analysis release the pending versions", func(t *testing.T) {
+		required := newRequiredVersions()
+		assert.Empty(t, required.require("pkg", "^2.0.0"))
+		_, pending := required.filter("pkg", all, all)
+		require.Len(t, pending, 3)
+
+		assert.Equal(t, []entities.NpmPackage{v3}, required.require("pkg", ">=3"))
+		// Released versions are only returned once.
+		assert.Empty(t, required.require("pkg", "3.0.0"))
+		assert.Empty(t, required.require("pkg", "^2.0.0"))
+	})
+
+	t.Run("The versions of a package are dropped once none is pending", func(t *testing.T) {
+		required := newRequiredVersions()
+		_, pending := required.filter("pkg", all, []entities.NpmPackage{v11, v3})
+		require.Len(t, pending, 2)
+		require.NotNil(t, required.analysed["pkg"])
+
+		assert.Equal(t, []entities.NpmPackage{v11}, required.require("pkg", "^1.0.0"))
+		assert.NotNil(t, required.analysed["pkg"])
+		assert.Equal(t, []entities.NpmPackage{v3}, required.require("pkg", "^3.0.0"))
+		assert.Nil(t, required.analysed["pkg"])
+		assert.Empty(t, required.require("pkg", "^3.0.0"))
+
+		kept, pending := required.filter("other", all, []entities.NpmPackage{v2})
+		assert.Equal(t, []entities.NpmPackage{v2}, kept)
+		assert.Empty(t, pending)
+		assert.Nil(t, required.analysed["other"])
+	})
+
+	t.Run("Only pending versions are returned, but all versions are available", func(t *testing.T) {
+		required := newRequiredVersions()
+		required.require("pkg", "^1.0.0 || ^2.0.0")
+
+		kept, pending := required.filter("pkg", all, []entities.NpmPackage{v11, v3})
+
+		assert.Empty(t, kept)
+		assert.Equal(t, []entities.NpmPackage{v11, v3}, pending)
+	})
+
+	t.Run("Dist-tags resolve to their version and the latest tag is preferred", func(t *testing.T) {
+		tagged := []entities.NpmPackage{version("1.0.0", "", "latest"), version("1.5.0", ""), version("2.0.0-beta.1", "", "next")}
+		required := newRequiredVersions()
+		required.require("pkg", "next")
+		required.require("pkg", "^1.0.0")
+
+		kept, pending := required.filter("pkg", tagged, tagged)
+
+		assert.Equal(t, []entities.NpmPackage{tagged[0], tagged[2]}, kept)
+		assert.Equal(t, []entities.NpmPackage{tagged[1]}, pending)
+	})
+	t.Run("The pending versions of every spec of a package are kept", func(t *testing.T) {
+		tagged := []entities.NpmPackage{version("1.0.0", ""), version("1.5.0", "", "latest"), version("2.0.0-beta.1", "", "next")}
+		required := newRequiredVersions()
+		// react@next, then react, are analysed.
+		required.require("pkg", "next")
+		_, pending := required.filter("pkg", tagged, []entities.NpmPackage{tagged[0], tagged[1]})
+		require.Len(t, pending, 2)
+		kept, pending := required.filter("pkg", tagged, []entities.NpmPackage{tagged[0]})
+		assert.Empty(t, kept)
+		assert.Equal(t, []entities.NpmPackage{tagged[0], tagged[1]}, pending)
+
+		assert.Equal(t, []entities.NpmPackage{tagged[0]}, required.require("pkg", "1.0.0"))
+		assert.Equal(t, []entities.NpmPackage{tagged[1]}, required.require("pkg", "^1.0.0"))
+		assert.Nil(t, required.analysed["pkg"])
+	})
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/npmoffline/internal/pkg/httpclient"
+)
+
+const (
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = time.Minute
+)
+
+// waitBeforeRetry waits before the next attempt using the backoff policy.
+// It returns false when no new attempt must be made: the error is not transient,
+// the attempts are exhausted or the context is done.
+func waitBeforeRetry(ctx context.Context, backoff httpclient.Backoff, attempt, maxAttempts int, err error) bool {
+	if attempt >= maxAttempts || !httpclient.IsRetryable(err) {
+		return false
+	}
+	return backoff.Wait(ctx, attempt, err) == nil
+}
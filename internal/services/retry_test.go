@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitBeforeRetry(t *testing.T) {
+	backoff := httpclient.NewBackoff(time.Millisecond, 10*time.Millisecond)
+
+	t.Run("Retries transient errors", func(t *testing.T) {
+		assert.True(t, waitBeforeRetry(context.Background(), backoff, 1, 3, fmt.Errorf("connection reset")))
+		assert.True(t, waitBeforeRetry(context.Background(), backoff, 1, 3, &httpclient.StatusError{StatusCode: http.StatusBadGateway}))
+	})
+
+	t.Run("Stops on the last attempt", func(t *testing.T) {
+		assert.False(t, waitBeforeRetry(context.Background(), backoff, 3, 3, fmt.Errorf("connection reset")))
+	})
+
+	t.Run("Stops on permanent status errors", func(t *testing.T) {
+		assert.False(t, waitBeforeRetry(context.Background(), backoff, 1, 3, &httpclient.StatusError{StatusCode: http.StatusNotFound}))
+	})
+
+	t.Run("Honours Retry-After", func(t *testing.T) {
+		err := &httpclient.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Millisecond}
+		start := time.Now()
+		assert.True(t, waitBeforeRetry(context.Background(), httpclient.NewBackoff(time.Millisecond, time.Second), 1, 3, err))
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("Stops when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.False(t, waitBeforeRetry(ctx, backoff, 1, 3, fmt.Errorf("connection reset")))
+	})
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// BuildSBOM lists the versions stored in the local repository with their dependencies. Each
+// dependency of a version, optional and peer dependencies included, is resolved to the highest
+// stored version satisfying its range, as npm would install it from the repository; the
+// dependencies no stored version satisfies are left out.
+func BuildSBOM(repo repositories.LocalNpmRepository) (entities.SBOM, error) {
+	var sbom entities.SBOM
+	names, err := repo.ListPackages()
+	if err != nil {
+		return sbom, fmt.Errorf("failed to list the packages: %w", err)
+	}
+	stored := map[string][]entities.SemVer{}
+	var versions []entities.NpmPackage
+	for _, name := range names {
+		local, err := repo.LoadLocalVersions(name)
+		if err != nil {
+			return sbom, fmt.Errorf("failed to load the versions of %s: %w", name, err)
+		}
+		for _, version := range local {
+			stored[name] = append(stored[name], version.Version)
+		}
+		versions = append(versions, local...)
+	}
+
+	for _, version := range versions {
+		component := entities.NewSBOMComponent(version)
+		deps := append(append(append(append([]string{}, version.Dependencies...), version.OptionalDeps...),
+			version.PeerDeps...), version.OptionalPeerDeps...)
+		seen := map[string]bool{}
+		for _, dep := range deps {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			spec := version.DependencyRanges[dep]
+			if spec == "" {
+				spec = "*"
+			}
+			depRange, err := entities.ParseSemVerRange(spec)
+			if err != nil {
+				continue
+			}
+			if resolved, ok := depRange.MaxMatching(stored[dep]); ok {
+				component.DependsOn = append(component.DependsOn, entities.PackageURL(dep, resolved.String()))
+			}
+		}
+		sbom.Components = append(sbom.Components, component)
+	}
+	sbom.Sort()
+	return sbom, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSBOM(t *testing.T) {
+	t.Run("Dependencies are resolved to the highest stored version", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"app", "dep", "peer"}, nil).Once()
+		repo.On("LoadLocalVersions", "app").Return([]entities.NpmPackage{{
+			Name:             "app",
+			Version:          entities.SemVer{Major: 1},
+			Dependencies:     []string{"dep", "missing"},
+			PeerDeps:         []string{"peer"},
+			DependencyRanges: map[string]string{"dep": "^1.0.0", "missing": "^1.0.0", "peer": "^3.0.0"},
+		}}, nil).Once()
+		repo.On("LoadLocalVersions", "dep").Return([]entities.NpmPackage{
+			{Name: "dep", Version: entities.SemVer{Major: 1, Minor: 1}},
+			{Name: "dep", Version: entities.SemVer{Major: 1, Minor: 2}},
+			{Name: "dep", Version: entities.SemVer{Major: 2}},
+		}, nil).Once()
+		repo.On("LoadLocalVersions", "peer").Return([]entities.NpmPackage{
+			{Name: "peer", Version: entities.SemVer{Major: 2}},
+		}, nil).Once()
+
+		sbom, err := BuildSBOM(repo)
+
+		require.NoError(t, err)
+		require.Len(t, sbom.Components, 5)
+		assert.Equal(t, "pkg:npm/app@1.0.0", sbom.Components[0].PURL)
+		assert.Equal(t, []string{"pkg:npm/dep@1.2.0"}, sbom.Components[0].DependsOn)
+		assert.Equal(t, "pkg:npm/dep@2.0.0", sbom.Components[3].PURL)
+	})
+
+	t.Run("Failure to load a package", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(nil, errors.New("invalid package.json")).Once()
+
+		_, err := BuildSBOM(repo)
+
+		assert.EqualError(t, err, "failed to load the versions of a: invalid package.json")
+	})
+}
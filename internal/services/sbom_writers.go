@@ -0,0 +1,239 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+)
+
+// SBOMDocument identifies a generated SBOM document.
+type SBOMDocument struct {
+	// ID is the random UUID of the document, used as serial number or namespace.
+	ID string
+	// Created is the time the document was generated.
+	Created time.Time
+}
+
+// NewSBOMDocument creates the identity of a document generated at the given time.
+func NewSBOMDocument(created time.Time) SBOMDocument {
+	return SBOMDocument{ID: newUUID(), Created: created}
+}
+
+// SBOMWriter writes an SBOM in a given format.
+type SBOMWriter func(w io.Writer, sbom entities.SBOM, document SBOMDocument) error
+
+// SBOMWriters write the SBOM in each format.
+var SBOMWriters = map[string]SBOMWriter{
+	"cyclonedx-json": WriteCycloneDX,
+	"spdx-json":      WriteSPDX,
+}
+
+// sbomTool is the tool named as the author of the documents.
+const sbomTool = "npm-pkg"
+
+type cycloneDXComponent struct {
+	Type     string             `json:"type"`
+	BOMRef   string             `json:"bom-ref"`
+	Name     string             `json:"name"`
+	Group    string             `json:"group,omitempty"`
+	Version  string             `json:"version"`
+	PURL     string             `json:"purl"`
+	Hashes   []cycloneDXHash    `json:"hashes,omitempty"`
+	Licenses []cycloneDXLicense `json:"licenses,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+// cycloneDXLicense is a license identifier, a free-form license name or an SPDX expression.
+type cycloneDXLicense struct {
+	License    *cycloneDXLicenseName `json:"license,omitempty"`
+	Expression string                `json:"expression,omitempty"`
+}
+
+type cycloneDXLicenseName struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes the SBOM as a CycloneDX 1.5 JSON document.
+func WriteCycloneDX(w io.Writer, sbom entities.SBOM, document SBOMDocument) error {
+	components := []cycloneDXComponent{}
+	dependencies := []cycloneDXDependency{}
+	for _, component := range sbom.Components {
+		converted := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  component.PURL,
+			Name:    component.Package,
+			Version: component.Version,
+			PURL:    component.PURL,
+		}
+		if scope, name, scoped := strings.Cut(component.Package, "/"); scoped {
+			converted.Group, converted.Name = scope, name
+		}
+		for _, hash := range component.Hashes {
+			converted.Hashes = append(converted.Hashes, cycloneDXHash{Algorithm: hash.Algorithm, Content: hash.Value})
+		}
+		if component.License != entities.NoAssertion {
+			converted.Licenses = []cycloneDXLicense{cycloneDXLicenseOf(component)}
+		}
+		components = append(components, converted)
+		dependsOn := component.DependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		dependencies = append(dependencies, cycloneDXDependency{Ref: component.PURL, DependsOn: dependsOn})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + document.ID,
+		"version":      1,
+		"metadata": map[string]any{
+			"timestamp": document.Created.UTC().Format(time.RFC3339),
+			"tools":     map[string]any{"components": []map[string]string{{"type": "application", "name": sbomTool}}},
+		},
+		"components":   components,
+		"dependencies": dependencies,
+	})
+}
+
+// cycloneDXLicenseOf returns a known single license as an identifier, a known compound
+// expression as an expression and an unknown license as a name.
+func cycloneDXLicenseOf(component entities.SBOMComponent) cycloneDXLicense {
+	var license cycloneDXLicense
+	if component.KnownLicense && strings.Contains(component.License, " ") {
+		license.Expression = component.License
+		return license
+	}
+	license.License = &cycloneDXLicenseName{}
+	if component.KnownLicense {
+		license.License.ID = component.License
+	} else {
+		license.License.Name = component.License
+	}
+	return license
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// spdxInvalidChars are the characters not allowed in SPDX identifiers.
+var spdxInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// WriteSPDX writes the SBOM as an SPDX 2.3 JSON document.
+func WriteSPDX(w io.Writer, sbom entities.SBOM, document SBOMDocument) error {
+	ids := map[string]string{}
+	used := map[string]bool{}
+	for i, component := range sbom.Components {
+		id := "SPDXRef-Package-" + strings.Trim(spdxInvalidChars.ReplaceAllString(component.Package, "-"), "-") + "-" + spdxInvalidChars.ReplaceAllString(component.Version, "-")
+		if used[id] {
+			id = fmt.Sprintf("%s-%d", id, i)
+		}
+		used[id] = true
+		ids[component.PURL] = id
+	}
+
+	packages := []spdxPackage{}
+	relationships := []spdxRelationship{}
+	for _, component := range sbom.Components {
+		id := ids[component.PURL]
+		declared := entities.NoAssertion
+		if component.KnownLicense {
+			declared = component.License
+		}
+		download := component.Tarball
+		if download == "" {
+			download = entities.NoAssertion
+		}
+		converted := spdxPackage{
+			SPDXID:           id,
+			Name:             component.Package,
+			VersionInfo:      component.Version,
+			DownloadLocation: download,
+			LicenseConcluded: entities.NoAssertion,
+			LicenseDeclared:  declared,
+			CopyrightText:    entities.NoAssertion,
+			ExternalRefs:     []spdxExternalRef{{Category: "PACKAGE-MANAGER", Type: "purl", Locator: component.PURL}},
+		}
+		for _, hash := range component.Hashes {
+			converted.Checksums = append(converted.Checksums, spdxChecksum{
+				Algorithm: strings.ReplaceAll(hash.Algorithm, "-", ""),
+				Value:     hash.Value,
+			})
+		}
+		packages = append(packages, converted)
+		relationships = append(relationships, spdxRelationship{Element: "SPDXRef-DOCUMENT", Type: "DESCRIBES", Related: id})
+		for _, dep := range component.DependsOn {
+			relationships = append(relationships, spdxRelationship{Element: id, Type: "DEPENDS_ON", Related: ids[dep]})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              "npm-pkg local repository",
+		"documentNamespace": "https://spdx.org/spdxdocs/npm-pkg-" + document.ID,
+		"creationInfo": map[string]any{
+			"created":  document.Created.UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: " + sbomTool},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	})
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSBOMWriters(t *testing.T) {
+	sbom := entities.SBOM{Components: []entities.SBOMComponent{
+		{
+			Package:      "@scope/lib",
+			Version:      "1.0.0",
+			PURL:         entities.PackageURL("@scope/lib", "1.0.0"),
+			License:      "MIT",
+			KnownLicense: true,
+			Tarball:      "https://registry.npmjs.org/@scope/lib/-/lib-1.0.0.tgz",
+			Hashes:       []entities.Hash{{Algorithm: "SHA-512", Value: "ab12"}},
+		},
+		{
+			Package:      "app",
+			Version:      "2.0.0",
+			PURL:         entities.PackageURL("app", "2.0.0"),
+			License:      "Apache-2.0 OR MIT",
+			KnownLicense: true,
+			DependsOn:    []string{entities.PackageURL("@scope/lib", "1.0.0")},
+		},
+		{
+			Package: "legacy",
+			Version: "0.1.0",
+			PURL:    entities.PackageURL("legacy", "0.1.0"),
+			License: "Custom License",
+		},
+	}}
+	document := SBOMDocument{ID: "6f1c2d3e-4b5a-4c6d-8e7f-0a1b2c3d4e5f", Created: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)}
+
+	for format, golden := range map[string]string{
+		"cyclonedx-json": "sbom.cyclonedx.json",
+		"spdx-json":      "sbom.spdx.json",
+	} {
+		t.Run(format, func(t *testing.T) {
+			expected, err := os.ReadFile(filepath.Join("testdata", golden))
+			require.NoError(t, err)
+			var out strings.Builder
+
+			require.NoError(t, SBOMWriters[format](&out, sbom, document))
+
+			assert.Equal(t, string(expected), out.String())
+		})
+	}
+}
+
+func TestNewSBOMDocument(t *testing.T) {
+	created := time.Now()
+
+	first, second := NewSBOMDocument(created), NewSBOMDocument(created)
+
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, created, first.Created)
+}
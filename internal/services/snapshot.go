@@ -0,0 +1,26 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+)
+
+// SnapshotRepository creates the snapshot of the versions stored in the local repository.
+func SnapshotRepository(repo repositories.LocalNpmRepository, now time.Time) (entities.Snapshot, error) {
+	names, err := repo.ListPackages()
+	if err != nil {
+		return entities.Snapshot{}, fmt.Errorf("failed to list the packages: %w", err)
+	}
+	var versions []entities.NpmPackage
+	for _, name := range names {
+		local, err := repo.LoadLocalVersions(name)
+		if err != nil {
+			return entities.Snapshot{}, fmt.Errorf("failed to load the versions of %s: %w", name, err)
+		}
+		versions = append(versions, local...)
+	}
+	return entities.NewSnapshot(versions, now), nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/npmoffline/internal/entities"
+	"github.com/npmoffline/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRepository(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Stored versions of all the packages", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a", "b"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return([]entities.NpmPackage{{Name: "a", Version: entities.SemVer{Major: 1}, License: "MIT"}}, nil).Once()
+		repo.On("LoadLocalVersions", "b").Return(nil, nil).Once()
+
+		snapshot, err := SnapshotRepository(repo, now)
+
+		require.NoError(t, err)
+		assert.Equal(t, now, snapshot.CreatedAt)
+		require.Len(t, snapshot.Packages, 1)
+		assert.Equal(t, "a", snapshot.Packages[0].Name)
+		assert.Equal(t, "MIT", snapshot.Packages[0].Versions[0].License)
+	})
+
+	t.Run("Failure to load a package", func(t *testing.T) {
+		repo := repositories.NewMockLocalNpmRepository(t)
+		repo.On("ListPackages").Return([]string{"a"}, nil).Once()
+		repo.On("LoadLocalVersions", "a").Return(nil, errors.New("invalid package.json")).Once()
+
+		_, err := SnapshotRepository(repo, now)
+
+		assert.EqualError(t, err, "failed to load the versions of a: invalid package.json")
+	})
+}